@@ -1,12 +1,43 @@
 package gotgbot
 
-import "unicode/utf16"
+import (
+	"strings"
+	"unicode/utf16"
+)
 
 type ParsedMessageEntity struct {
 	MessageEntity
 	Text string `json:"text"`
 }
 
+// MessageCommand is a bot command contained in a message, as returned by Message.Commands.
+type MessageCommand struct {
+	ParsedMessageEntity
+	// Name is the command name, without the leading "/" or the bot username; eg, "start".
+	Name string
+	// BotUsername is the username of the bot the command is addressed to, if any; eg, for "/start@jobs_bot", this is
+	// "jobs_bot".
+	BotUsername string
+	// Args contains the text following the command, up to the next command, with surrounding whitespace removed.
+	Args string
+}
+
+// IsFor checks whether the command is addressed to the bot with the given username; commands which do not specify a
+// bot are addressed to all bots.
+func (c MessageCommand) IsFor(username string) bool {
+	return c.BotUsername == "" || strings.EqualFold(c.BotUsername, username)
+}
+
+// MessageMention is a user mentioned in a message, as returned by Message.Mentions.
+// Users with a username are mentioned by their username; users without one are mentioned by their User object.
+type MessageMention struct {
+	ParsedMessageEntity
+	// Username is the mentioned username, without the leading "@". Set for "mention" entities.
+	Username string
+	// User is the mentioned user. Set for "text_mention" entities.
+	User *User
+}
+
 // ParseEntities calls Message.ParseEntity on all message text entities.
 func (m Message) ParseEntities() (out []ParsedMessageEntity) {
 	return m.ParseEntityTypes(nil)
@@ -49,6 +80,89 @@ func (m Message) ParseCaptionEntity(entity MessageEntity) ParsedMessageEntity {
 	return parseEntity(entity, utf16.Encode([]rune(m.Caption)))
 }
 
+// Commands returns all the bot commands contained in the message text (or caption, for media messages).
+func (m Message) Commands() (out []MessageCommand) {
+	utf16Text := utf16.Encode([]rune(m.GetText()))
+	ents := m.GetEntities()
+	for i, ent := range ents {
+		if ent.Type != "bot_command" {
+			continue
+		}
+
+		// Args run until the next command, or the end of the text.
+		argsEnd := int64(len(utf16Text))
+		for _, next := range ents[i+1:] {
+			if next.Type == "bot_command" {
+				argsEnd = next.Offset
+				break
+			}
+		}
+
+		parsed := parseEntity(ent, utf16Text)
+		name, botUsername, _ := strings.Cut(strings.TrimPrefix(parsed.Text, "/"), "@")
+		out = append(out, MessageCommand{
+			ParsedMessageEntity: parsed,
+			Name:                name,
+			BotUsername:         botUsername,
+			Args:                strings.TrimSpace(string(utf16.Decode(utf16Text[ent.Offset+ent.Length : argsEnd]))),
+		})
+	}
+	return out
+}
+
+// Mentions returns all the users mentioned in the message text (or caption, for media messages), both by username and
+// by text mention.
+func (m Message) Mentions() (out []MessageMention) {
+	for _, ent := range m.parseTextEntityTypes("mention", "text_mention") {
+		mention := MessageMention{ParsedMessageEntity: ent}
+		if ent.Type == "mention" {
+			mention.Username = strings.TrimPrefix(ent.Text, "@")
+		} else {
+			mention.User = ent.MessageEntity.User
+		}
+		out = append(out, mention)
+	}
+	return out
+}
+
+// URLs returns all the URLs contained in the message text (or caption, for media messages), both as plain URLs and as
+// text links. The URL is available in the Url field of each entity.
+func (m Message) URLs() []ParsedMessageEntity {
+	return m.parseTextEntityTypes("url", "text_link")
+}
+
+// Hashtags returns all the hashtags contained in the message text (or caption, for media messages), without the
+// leading "#".
+func (m Message) Hashtags() (out []string) {
+	for _, ent := range m.parseTextEntityTypes("hashtag") {
+		out = append(out, strings.TrimPrefix(ent.Text, "#"))
+	}
+	return out
+}
+
+// Cashtags returns all the cashtags contained in the message text (or caption, for media messages), without the
+// leading "$".
+func (m Message) Cashtags() (out []string) {
+	for _, ent := range m.parseTextEntityTypes("cashtag") {
+		out = append(out, strings.TrimPrefix(ent.Text, "$"))
+	}
+	return out
+}
+
+// parseTextEntityTypes parses the entities of the given types in the message text, or caption for media messages.
+func (m Message) parseTextEntityTypes(types ...string) (out []ParsedMessageEntity) {
+	utf16Text := utf16.Encode([]rune(m.GetText()))
+	for _, ent := range m.GetEntities() {
+		for _, t := range types {
+			if ent.Type == t {
+				out = append(out, parseEntity(ent, utf16Text))
+				break
+			}
+		}
+	}
+	return out
+}
+
 func parseEntity(entity MessageEntity, utf16Text []uint16) ParsedMessageEntity {
 	text := string(utf16.Decode(utf16Text[entity.Offset : entity.Offset+entity.Length]))
 
@@ -0,0 +1,61 @@
+package gotgbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMessageEntityAccessors(t *testing.T) {
+	user := &User{Id: 123, FirstName: "John"}
+	m := Message{
+		Text: "👋 /start@my_bot a b /help @john John #go $USD https://example.com link",
+		Entities: []MessageEntity{
+			{Type: "bot_command", Offset: 3, Length: 13},
+			{Type: "bot_command", Offset: 21, Length: 5},
+			{Type: "mention", Offset: 27, Length: 5},
+			{Type: "text_mention", Offset: 33, Length: 4, User: user},
+			{Type: "hashtag", Offset: 38, Length: 3},
+			{Type: "cashtag", Offset: 42, Length: 4},
+			{Type: "url", Offset: 47, Length: 19},
+			{Type: "text_link", Offset: 67, Length: 4, Url: "https://example.org"},
+		},
+	}
+
+	commands := m.Commands()
+	if len(commands) != 2 {
+		t.Fatalf("expected 2 commands, got %d", len(commands))
+	}
+	if commands[0].Name != "start" || commands[0].BotUsername != "my_bot" || commands[0].Args != "a b" {
+		t.Errorf("unexpected first command: %+v", commands[0])
+	}
+	if !commands[0].IsFor("My_Bot") || commands[0].IsFor("other_bot") {
+		t.Errorf("unexpected target bot check for %q", commands[0].BotUsername)
+	}
+	if commands[1].Name != "help" || commands[1].BotUsername != "" || commands[1].Args != "@john John #go $USD https://example.com link" {
+		t.Errorf("unexpected second command: %+v", commands[1])
+	}
+	if !commands[1].IsFor("other_bot") {
+		t.Errorf("expected command without bot username to be for all bots")
+	}
+
+	mentions := m.Mentions()
+	if len(mentions) != 2 || mentions[0].Username != "john" || mentions[0].User != nil ||
+		mentions[1].Username != "" || mentions[1].User != user {
+		t.Errorf("unexpected mentions: %+v", mentions)
+	}
+
+	var urls []string
+	for _, u := range m.URLs() {
+		urls = append(urls, u.Url)
+	}
+	if expected := []string{"https://example.com", "https://example.org"}; !reflect.DeepEqual(urls, expected) {
+		t.Errorf("unexpected URLs: %v", urls)
+	}
+
+	if hashtags := m.Hashtags(); !reflect.DeepEqual(hashtags, []string{"go"}) {
+		t.Errorf("unexpected hashtags: %v", hashtags)
+	}
+	if cashtags := m.Cashtags(); !reflect.DeepEqual(cashtags, []string{"USD"}) {
+		t.Errorf("unexpected cashtags: %v", cashtags)
+	}
+}
@@ -0,0 +1,60 @@
+package ext
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffPolicy decides how long to wait before retrying after consecutive failures.
+type BackoffPolicy interface {
+	// Delay returns how long to wait after the given number of consecutive failures.
+	// The failures value is always at least 1.
+	Delay(failures int) time.Duration
+}
+
+var _ BackoffPolicy = ExponentialBackoff{}
+
+// ExponentialBackoff is a BackoffPolicy which doubles (or multiplies by Multiplier) the delay after each consecutive
+// failure, up to a maximum value.
+type ExponentialBackoff struct {
+	// Initial is the delay after the first failure.
+	Initial time.Duration
+	// Max is the maximum delay between two attempts.
+	// If 0, the delay is not capped.
+	Max time.Duration
+	// Multiplier is the factor by which the delay increases after each failure.
+	// If <= 1, the default value of 2 is used.
+	Multiplier float64
+	// Jitter is the fraction of the delay which is randomised, to avoid multiple instances retrying in lockstep.
+	// For example, a Jitter of 0.2 returns a delay anywhere between 80% and 100% of the computed value.
+	// Must be between 0 and 1.
+	Jitter float64
+}
+
+// Delay returns the delay to use after the given number of consecutive failures.
+func (e ExponentialBackoff) Delay(failures int) time.Duration {
+	if failures < 1 {
+		failures = 1
+	}
+
+	multiplier := e.Multiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+
+	delay := float64(e.Initial) * math.Pow(multiplier, float64(failures-1))
+	if e.Max > 0 && delay > float64(e.Max) {
+		delay = float64(e.Max)
+	}
+	if delay >= math.MaxInt64 {
+		// Avoid overflowing the duration when no maximum is set.
+		delay = math.Nextafter(math.MaxInt64, 0)
+	}
+
+	if e.Jitter > 0 {
+		jitter := math.Min(e.Jitter, 1)
+		delay -= delay * jitter * rand.Float64() //nolint:gosec // We don't need secure randomness for jitter.
+	}
+	return time.Duration(delay)
+}
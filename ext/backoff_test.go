@@ -0,0 +1,44 @@
+package ext
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff_Delay(t *testing.T) {
+	b := ExponentialBackoff{
+		Initial: time.Second,
+		Max:     5 * time.Second,
+	}
+
+	for failures, expected := range map[int]time.Duration{
+		0: time.Second, // invalid values are treated as the first failure
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+		// Large values should not overflow.
+		1000: 5 * time.Second,
+	} {
+		if d := b.Delay(failures); d != expected {
+			t.Errorf("expected delay of %s after %d failures, got %s", expected, failures, d)
+		}
+	}
+
+	if d := (ExponentialBackoff{Initial: time.Second}).Delay(1000); d <= 0 {
+		t.Errorf("expected uncapped delay to remain positive, got %s", d)
+	}
+}
+
+func TestExponentialBackoff_Jitter(t *testing.T) {
+	b := ExponentialBackoff{
+		Initial: time.Second,
+		Jitter:  0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		if d := b.Delay(1); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("expected jittered delay between 0.5s and 1s, got %s", d)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package ext
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+)
+
+// BotCommandTarget identifies a list of bot commands, by the scope and language it is shown for.
+type BotCommandTarget struct {
+	// Scope is the scope of users the commands are shown to. If nil, the default scope is used.
+	Scope gotgbot.BotCommandScope
+	// LanguageCode is a two-letter ISO 639-1 language code. If empty, the commands are shown to all users from the
+	// given scope for whose language there are no dedicated commands.
+	LanguageCode string
+}
+
+// BotCommandInfo describes a bot command, and the scope and language it should be registered for.
+type BotCommandInfo struct {
+	gotgbot.BotCommand
+	BotCommandTarget
+}
+
+// BotCommandHandler is implemented by handlers which describe bot commands, such as handlers.Command.
+// These commands are collected by Dispatcher.BotCommands, to be registered with Dispatcher.SyncBotCommands.
+type BotCommandHandler interface {
+	Handler
+	// BotCommands returns the list of bot commands handled by this handler.
+	BotCommands() []BotCommandInfo
+}
+
+// BotCommandLocalizer translates bot commands, so that they can be registered for each language by
+// Dispatcher.SyncBotCommands. The ext/i18n package provides an implementation based on message catalogs.
+type BotCommandLocalizer interface {
+	// LocalizeBotCommands returns the commands to register, given the commands described by the dispatcher's handlers.
+	// The returned commands should include the translated commands, with their LanguageCode set.
+	LocalizeBotCommands(cmds []BotCommandInfo) []BotCommandInfo
+}
+
+// SyncBotCommandsOpts is the set of fields used to configure Dispatcher.SyncBotCommands.
+type SyncBotCommandsOpts struct {
+	// Targets is a list of additional scopes and languages to keep in sync. Any commands registered for these targets
+	// are deleted if none of the dispatcher's handlers describe commands for them; this allows for cleaning up
+	// scopes and languages which are no longer in use.
+	// The default scope, with no language code, is always synced.
+	Targets []BotCommandTarget
+	// Localizer, if set, translates the commands before they are registered, to register them for each language.
+	Localizer BotCommandLocalizer
+	// RequestOpts are the request options used for all the API calls.
+	RequestOpts *gotgbot.RequestOpts
+}
+
+// botCommandList is the list of commands to register for a single target.
+type botCommandList struct {
+	target   BotCommandTarget
+	commands []gotgbot.BotCommand
+}
+
+// BotCommands returns the list of bot commands described by all the handlers in the dispatcher, in the order in which
+// they would be processed.
+func (d *Dispatcher) BotCommands() []BotCommandInfo {
+	var cmds []BotCommandInfo
+	for _, groups := range d.handlers.getGroups() {
+		for _, h := range groups {
+			if bch, ok := h.(BotCommandHandler); ok {
+				cmds = append(cmds, bch.BotCommands()...)
+			}
+		}
+	}
+	return cmds
+}
+
+// SyncBotCommands registers the bot commands described by the dispatcher's handlers with telegram, for every scope and
+// language they are described for.
+// The currently registered commands are fetched with GetMyCommands first, and SetMyCommands or DeleteMyCommands are
+// only called if they differ; this makes it cheap to call on every startup.
+// If multiple handlers describe the same command for the same scope and language, the first one is used.
+func (d *Dispatcher) SyncBotCommands(b *gotgbot.Bot, opts *SyncBotCommandsOpts) error {
+	var reqOpts *gotgbot.RequestOpts
+	var extraTargets []BotCommandTarget
+	cmds := d.BotCommands()
+	if opts != nil {
+		reqOpts = opts.RequestOpts
+		extraTargets = opts.Targets
+		if opts.Localizer != nil {
+			cmds = opts.Localizer.LocalizeBotCommands(cmds)
+		}
+	}
+
+	lists, err := getBotCommandLists(cmds, extraTargets)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range lists {
+		err := syncBotCommandList(b, l, reqOpts)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getBotCommandLists groups commands by target. The default target and any extra targets are always included, even
+// if they have no commands.
+func getBotCommandLists(cmds []BotCommandInfo, extraTargets []BotCommandTarget) ([]*botCommandList, error) {
+	var lists []*botCommandList
+	byKey := map[string]*botCommandList{}
+	getList := func(target BotCommandTarget) (*botCommandList, error) {
+		key, err := getBotCommandTargetKey(target)
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := byKey[key]; ok {
+			return l, nil
+		}
+		l := &botCommandList{target: target}
+		byKey[key] = l
+		lists = append(lists, l)
+		return l, nil
+	}
+
+	for _, target := range append([]BotCommandTarget{{}}, extraTargets...) {
+		if _, err := getList(target); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, cmd := range cmds {
+		l, err := getList(cmd.BotCommandTarget)
+		if err != nil {
+			return nil, err
+		}
+		if !containsBotCommand(l.commands, cmd.Command) {
+			l.commands = append(l.commands, cmd.BotCommand)
+		}
+	}
+	return lists, nil
+}
+
+// getBotCommandTargetKey returns a unique key for each target.
+func getBotCommandTargetKey(target BotCommandTarget) (string, error) {
+	scope := target.Scope
+	if scope == nil {
+		scope = gotgbot.BotCommandScopeDefault{}
+	}
+	bs, err := json.Marshal(scope)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal bot command scope: %w", err)
+	}
+	return string(bs) + ":" + target.LanguageCode, nil
+}
+
+func containsBotCommand(cmds []gotgbot.BotCommand, command string) bool {
+	for _, c := range cmds {
+		if c.Command == command {
+			return true
+		}
+	}
+	return false
+}
+
+// syncBotCommandList makes sure the commands registered on telegram match the expected list.
+func syncBotCommandList(b *gotgbot.Bot, l *botCommandList, reqOpts *gotgbot.RequestOpts) error {
+	current, err := b.GetMyCommands(&gotgbot.GetMyCommandsOpts{
+		Scope:        l.target.Scope,
+		LanguageCode: l.target.LanguageCode,
+		RequestOpts:  reqOpts,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get bot commands for scope %s: %w", describeBotCommandTarget(l.target), err)
+	}
+
+	if equalBotCommands(current, l.commands) {
+		return nil
+	}
+
+	if len(l.commands) == 0 {
+		_, err = b.DeleteMyCommands(&gotgbot.DeleteMyCommandsOpts{
+			Scope:        l.target.Scope,
+			LanguageCode: l.target.LanguageCode,
+			RequestOpts:  reqOpts,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete bot commands for scope %s: %w", describeBotCommandTarget(l.target), err)
+		}
+		return nil
+	}
+
+	_, err = b.SetMyCommands(l.commands, &gotgbot.SetMyCommandsOpts{
+		Scope:        l.target.Scope,
+		LanguageCode: l.target.LanguageCode,
+		RequestOpts:  reqOpts,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set bot commands for scope %s: %w", describeBotCommandTarget(l.target), err)
+	}
+	return nil
+}
+
+func equalBotCommands(a []gotgbot.BotCommand, b []gotgbot.BotCommand) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// describeBotCommandTarget returns a human-readable description of the target, for error messages.
+func describeBotCommandTarget(target BotCommandTarget) string {
+	scope := "default"
+	if target.Scope != nil {
+		scope = target.Scope.GetType()
+	}
+	if target.LanguageCode != "" {
+		scope += " (" + target.LanguageCode + ")"
+	}
+	return scope
+}
@@ -0,0 +1,124 @@
+package ext_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+)
+
+func TestSyncBotCommands(t *testing.T) {
+	var mux sync.Mutex
+	var calls []string
+	// registered maps scope and language to the registered commands.
+	registered := map[string]string{
+		`{"type":"all_group_chats"}:`: `[{"command":"old","description":"Old command"}]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("failed to decode params: %v", err)
+			return
+		}
+
+		scope := params["scope"]
+		if scope == "" || scope == "null" {
+			scope = `{"type":"default"}`
+		}
+		key := scope + ":" + params["language_code"]
+
+		mux.Lock()
+		defer mux.Unlock()
+		method := path.Base(r.URL.Path)
+		calls = append(calls, method+" "+key)
+		switch method {
+		case "getMyCommands":
+			cmds := registered[key]
+			if cmds == "" {
+				cmds = "[]"
+			}
+			fmt.Fprintf(w, `{"ok": true, "result": %s}`, cmds)
+		case "setMyCommands":
+			registered[key] = params["commands"]
+			fmt.Fprint(w, `{"ok": true, "result": true}`)
+		case "deleteMyCommands":
+			delete(registered, key)
+			fmt.Fprint(w, `{"ok": true, "result": true}`)
+		default:
+			t.Errorf("unexpected method %s", method)
+		}
+	}))
+	defer server.Close()
+
+	b := &gotgbot.Bot{
+		Token: "123:SOME_TOKEN",
+		BotClient: &gotgbot.BaseBotClient{
+			DefaultRequestOpts: &gotgbot.RequestOpts{APIURL: server.URL},
+		},
+	}
+
+	noop := func(b *gotgbot.Bot, ctx *ext.Context) error { return nil }
+	d := ext.NewDispatcher(nil)
+	d.AddHandler(handlers.NewCommand("start", noop).SetDescription("Start the bot"))
+	d.AddHandler(handlers.NewCommand("help", noop).SetDescription("Get help"))
+	d.AddHandler(handlers.NewCommand("hidden", noop))
+	d.AddHandler(handlers.NewCommand("help", noop).SetDescription("Duplicate help"))
+	d.AddHandler(handlers.NewCommand("hilfe", noop).SetDescription("Hilfe erhalten").SetLanguageCode("de"))
+	d.AddHandlerToGroup(handlers.NewConversation(
+		[]ext.Handler{handlers.NewCommand("ban", noop).SetDescription("Ban a user").SetScope(gotgbot.BotCommandScopeAllChatAdministrators{})},
+		nil, nil,
+	), 1)
+
+	opts := &ext.SyncBotCommandsOpts{
+		Targets: []ext.BotCommandTarget{{Scope: gotgbot.BotCommandScopeAllGroupChats{}}},
+	}
+	if err := d.SyncBotCommands(b, opts); err != nil {
+		t.Fatalf("failed to sync commands: %v", err)
+	}
+
+	expectedCalls := []string{
+		`getMyCommands {"type":"default"}:`,
+		`setMyCommands {"type":"default"}:`,
+		`getMyCommands {"type":"all_group_chats"}:`,
+		`deleteMyCommands {"type":"all_group_chats"}:`,
+		`getMyCommands {"type":"default"}:de`,
+		`setMyCommands {"type":"default"}:de`,
+		`getMyCommands {"type":"all_chat_administrators"}:`,
+		`setMyCommands {"type":"all_chat_administrators"}:`,
+	}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("unexpected calls:\n%v\nexpected:\n%v", calls, expectedCalls)
+	}
+
+	expectedRegistered := map[string]string{
+		`{"type":"default"}:`:                 `[{"command":"start","description":"Start the bot"},{"command":"help","description":"Get help"}]`,
+		`{"type":"default"}:de`:               `[{"command":"hilfe","description":"Hilfe erhalten"}]`,
+		`{"type":"all_chat_administrators"}:`: `[{"command":"ban","description":"Ban a user"}]`,
+	}
+	if !reflect.DeepEqual(registered, expectedRegistered) {
+		t.Errorf("unexpected registered commands:\n%v\nexpected:\n%v", registered, expectedRegistered)
+	}
+
+	// Syncing again should only fetch the commands, since nothing has changed.
+	calls = nil
+	if err := d.SyncBotCommands(b, opts); err != nil {
+		t.Fatalf("failed to sync commands: %v", err)
+	}
+	for _, call := range calls {
+		if !strings.HasPrefix(call, "getMyCommands ") {
+			t.Errorf("unexpected call when commands are already in sync: %s", call)
+		}
+	}
+	if len(calls) != 4 {
+		t.Errorf("expected 4 calls, got %d", len(calls))
+	}
+}
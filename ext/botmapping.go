@@ -1,13 +1,16 @@
 package ext
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"log"
 	"net/http"
+	"net/netip"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 )
@@ -30,6 +33,15 @@ type botData struct {
 	urlPath string
 	// webhookSecret stores the webhook secret for this bot.
 	webhookSecret string
+	// allowedSubnets restricts the source IPs which can send webhook updates for this bot, if set.
+	allowedSubnets []netip.Prefix
+	// trustedProxies defines the proxies which are trusted to forward the original source IP.
+	trustedProxies []netip.Prefix
+	// maxBodySize limits the size of incoming webhook updates, if set.
+	maxBodySize int64
+
+	// pollingState tracks the health of the polling loop. This is nil for webhook bots.
+	pollingState *pollingState
 }
 
 // botMapping Ensures that all botData is stored in a thread-safe manner.
@@ -51,8 +63,8 @@ var ErrBotAlreadyExists = errors.New("bot already exists in bot mapping")
 var ErrBotUrlPathAlreadyExists = errors.New("url path already exists in bot mapping")
 
 // addBot Adds a new bot to the botMapping structure.
-// Pass an empty urlPath/webhookSecret if using polling instead of webhooks.
-func (m *botMapping) addBot(b *gotgbot.Bot, urlPath string, webhookSecret string) (*botData, error) {
+// Pass an empty urlPath and nil opts if using polling instead of webhooks.
+func (m *botMapping) addBot(b *gotgbot.Bot, urlPath string, opts *AddWebhookOpts) (*botData, error) {
 	// Clean up the URLPath such that it remains consistent.
 	urlPath = strings.TrimPrefix(urlPath, "/")
 
@@ -76,11 +88,24 @@ func (m *botMapping) addBot(b *gotgbot.Bot, urlPath string, webhookSecret string
 
 	bData := botData{
 		bot:                 b,
-		updateChan:          make(chan json.RawMessage),
 		stopUpdates:         make(chan struct{}),
 		updateWriterControl: &sync.WaitGroup{},
 		urlPath:             urlPath,
-		webhookSecret:       webhookSecret,
+	}
+
+	queueSize := 0
+	if opts != nil {
+		bData.webhookSecret = opts.SecretToken
+		bData.allowedSubnets = opts.AllowedSubnets
+		bData.trustedProxies = opts.TrustedProxies
+		bData.maxBodySize = opts.MaxBodySize
+		queueSize = opts.QueueSize
+	}
+	// A buffered channel allows webhook updates to be acknowledged before the dispatcher has picked them up.
+	bData.updateChan = make(chan json.RawMessage, queueSize)
+	if urlPath == "" {
+		// No URL path means this bot is polling.
+		bData.pollingState = &pollingState{}
 	}
 
 	m.mapping[bData.bot.Token] = bData
@@ -172,6 +197,12 @@ func (m *botMapping) getHandlerFunc(prefix string) func(writer http.ResponseWrit
 			return
 		}
 
+		if len(b.allowedSubnets) != 0 && !containsIP(b.allowedSubnets, getClientIP(r, b.trustedProxies)) {
+			// Drop any updates which don't come from an allowed source.
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
 		headerSecret := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
 		if b.webhookSecret != "" && b.webhookSecret != headerSecret {
 			// Drop any updates from invalid secret tokens.
@@ -179,8 +210,19 @@ func (m *botMapping) getHandlerFunc(prefix string) func(writer http.ResponseWrit
 			return
 		}
 
-		bytes, err := io.ReadAll(r.Body)
+		body := r.Body
+		if b.maxBodySize > 0 {
+			body = http.MaxBytesReader(w, r.Body, b.maxBodySize)
+		}
+
+		bytes, err := io.ReadAll(body)
 		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+
 			if m.errFunc != nil {
 				m.errFunc(err)
 			} else {
@@ -190,10 +232,78 @@ func (m *botMapping) getHandlerFunc(prefix string) func(writer http.ResponseWrit
 			return
 		}
 
-		b.updateChan <- bytes
+		if cap(b.updateChan) == 0 {
+			// No queue; wait for the dispatcher to pick up the update.
+			b.updateChan <- bytes
+			return
+		}
+
+		select {
+		case b.updateChan <- bytes:
+			// Queued; the update is acknowledged straight away.
+		default:
+			// The queue is full. Let telegram know to back off, and send the update again later.
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
 	}
 }
 
+// getClientIP determines the IP address of the client which sent the request.
+// If the request comes from a trusted proxy, the X-Forwarded-For and X-Real-IP headers are used to determine the
+// original client IP.
+func getClientIP(r *http.Request, trustedProxies []netip.Prefix) netip.Addr {
+	remoteAddr, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	ip := remoteAddr.Addr().Unmap()
+	if !containsIP(trustedProxies, ip) {
+		return ip
+	}
+
+	if forwardedFor := r.Header.Values("X-Forwarded-For"); len(forwardedFor) != 0 {
+		// Iterate from the right, skipping trusted proxies, as anything further left may have been set by the client.
+		hops := strings.Split(strings.Join(forwardedFor, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				return netip.Addr{}
+			}
+			hop = hop.Unmap()
+			if !containsIP(trustedProxies, hop) {
+				return hop
+			}
+		}
+		return ip
+	}
+
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		hop, err := netip.ParseAddr(strings.TrimSpace(realIP))
+		if err != nil {
+			return netip.Addr{}
+		}
+		return hop.Unmap()
+	}
+
+	return ip
+}
+
+// containsIP checks whether the IP is contained in any of the subnets.
+func containsIP(subnets []netip.Prefix, ip netip.Addr) bool {
+	if !ip.IsValid() {
+		return false
+	}
+
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *botMapping) logf(format string, args ...interface{}) {
 	if m.errorLog != nil {
 		m.errorLog.Printf(format, args...)
@@ -215,6 +325,51 @@ func (b *botData) stop() {
 	close(b.updateChan)
 }
 
+// stopContext returns a context which is cancelled once the bot is stopped.
+// The returned cancel func must be called to release the associated resources.
+func (b *botData) stopContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-b.stopUpdates:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// wait sleeps for the given duration, unless the bot is stopped in the meantime.
+// It returns false if the bot was stopped.
+func (b *botData) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-b.stopUpdates:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// waitFor waits for the WaitGroup to complete, unless the bot is stopped in the meantime.
+// It returns false if the bot was stopped.
+func (b *botData) waitFor(wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-b.stopUpdates:
+		return false
+	case <-done:
+		return true
+	}
+}
+
 func (b *botData) shouldStopUpdates() bool {
 	select {
 	case <-b.stopUpdates:
@@ -0,0 +1,526 @@
+package ext
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+)
+
+var (
+	ErrUnterminatedQuote = errors.New("unterminated quote")
+	ErrMissingArgument   = errors.New("missing argument")
+)
+
+// commandArgsDataKey is the Context.Data key used to cache the parsed command arguments.
+const commandArgsDataKey = "gotgbot_command_args"
+
+// ArgType defines how a command argument should be parsed.
+type ArgType int
+
+const (
+	// ArgString is a single string argument, which may be quoted to include whitespace.
+	ArgString ArgType = iota
+	// ArgInt is an integer argument.
+	ArgInt
+	// ArgDuration is a duration argument, such as "1h30m", or "2d".
+	ArgDuration
+	// ArgUser is a user argument: either a text mention, or a numeric user ID.
+	// If the command message is a reply, the user being replied to is used instead, and no argument is consumed.
+	ArgUser
+	// ArgText consumes all the remaining text of the message, as-is. It must be the last argument, and any flags must
+	// be placed before it.
+	ArgText
+)
+
+// ArgSpec describes a single command argument, or key=value flag.
+type ArgSpec struct {
+	// Name of the argument, as shown in the usage message, and used to access the parsed value.
+	Name string
+	// Type of the argument.
+	Type ArgType
+	// Optional arguments may be omitted; arguments are required by default.
+	Optional bool
+}
+
+// CommandSpec describes the arguments expected by a command, to validate them and generate usage messages.
+type CommandSpec struct {
+	// Args is the list of positional arguments.
+	Args []ArgSpec
+	// Flags is the list of accepted key=value flags. If empty, no flags are accepted.
+	Flags []ArgSpec
+}
+
+// UsageError is returned when command arguments do not match the expected CommandSpec.
+type UsageError struct {
+	// Usage is the usage string for the command; eg, "/ban <user> [duration] [reason...]".
+	Usage string
+	// Reason describes what was wrong with the arguments.
+	Reason string
+}
+
+func (e *UsageError) Error() string {
+	return e.Reason + "\nUsage: " + e.Usage
+}
+
+// Usage generates a usage string for the given command; eg, "/ban <user> [duration] [reason...] [silent=<value>]".
+func (s CommandSpec) Usage(command string) string {
+	bd := strings.Builder{}
+	bd.WriteString(command)
+	for _, a := range s.Args {
+		name := a.Name
+		if a.Type == ArgText {
+			name += "..."
+		}
+		bd.WriteString(" " + wrapUsage(name, a.Optional))
+	}
+	for _, f := range s.Flags {
+		bd.WriteString(" " + wrapUsage(f.Name+"=<value>", f.Optional))
+	}
+	return bd.String()
+}
+
+func wrapUsage(name string, optional bool) string {
+	if optional {
+		return "[" + name + "]"
+	}
+	return "<" + name + ">"
+}
+
+// Parse validates the command arguments against the spec, and stores the parsed values, to be accessed by name with
+// the CommandArgs getters. A *UsageError is returned if the arguments do not match.
+func (s CommandSpec) Parse(args *CommandArgs) error {
+	usage := func(format string, a ...interface{}) error {
+		return &UsageError{Usage: s.Usage(args.Command), Reason: fmt.Sprintf(format, a...)}
+	}
+
+	s.splitFlags(args)
+	args.values = map[string]interface{}{}
+	idx := 0
+	for _, a := range s.Args {
+		if a.Type == ArgUser {
+			if u := args.ReplyUser(); u != nil {
+				args.values[a.Name] = u
+				continue
+			}
+		}
+
+		if idx >= args.Len() {
+			if !a.Optional {
+				return usage("Missing argument <%s>.", a.Name)
+			}
+			continue
+		}
+
+		if a.Type == ArgText {
+			args.values[a.Name] = args.Rest(idx)
+			idx = args.Len()
+			continue
+		}
+
+		v, err := args.parseArg(args.args[idx], a.Type)
+		if err != nil {
+			return usage("Invalid argument <%s>: %s.", a.Name, err.Error())
+		}
+		args.values[a.Name] = v
+		idx++
+	}
+	if idx < args.Len() {
+		return usage("Too many arguments.")
+	}
+
+	for _, f := range s.Flags {
+		raw, ok := args.flags[f.Name]
+		if !ok {
+			if !f.Optional {
+				return usage("Missing flag %s.", f.Name)
+			}
+			continue
+		}
+		v, err := args.parseArg(commandArg{value: raw}, f.Type)
+		if err != nil {
+			return usage("Invalid flag %s: %s.", f.Name, err.Error())
+		}
+		args.values[f.Name] = v
+	}
+	return nil
+}
+
+// splitFlags splits the tokens of the command into positional arguments and flags. Only the key=value tokens
+// matching a flag of the spec are flags; flag extraction stops once the ArgText argument starts, so its text is kept
+// as-is.
+func (s CommandSpec) splitFlags(args *CommandArgs) {
+	textIdx := -1
+	consumed := 0
+	for _, a := range s.Args {
+		if a.Type == ArgText {
+			textIdx = consumed
+			break
+		}
+		if a.Type != ArgUser || args.ReplyUser() == nil {
+			consumed++
+		}
+	}
+
+	args.args = nil
+	args.flags = map[string]string{}
+	for _, tok := range args.tokens {
+		if tok.key != "" && (textIdx < 0 || len(args.args) <= textIdx) && s.hasFlag(tok.key) {
+			args.flags[tok.key] = tok.flag
+			continue
+		}
+		args.args = append(args.args, tok)
+	}
+}
+
+func (s CommandSpec) hasFlag(key string) bool {
+	for _, f := range s.Flags {
+		if f.Name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// commandArg is a single argument token.
+type commandArg struct {
+	value string
+	// offset is the byte offset of the argument in the message text.
+	offset int
+	// user is the mentioned user, if the argument is a text mention.
+	user *gotgbot.User
+	// key and flag are the key and value of key=value tokens, which may be parsed as flags.
+	key  string
+	flag string
+}
+
+// CommandArgs contains the arguments of a command message. Arguments are separated by whitespace; single or double
+// quotes can be used to include whitespace in an argument, and backslashes escape the following character.
+// Arguments of the form key=value are parsed as flags; once validated with a CommandSpec, only the flags it declares
+// are kept, and other key=value arguments are positional.
+type CommandArgs struct {
+	// Command is the command the arguments were passed to, without the bot username; eg, "/start".
+	Command string
+
+	msg    *gotgbot.Message
+	text   string
+	tokens []commandArg
+	args   []commandArg
+	flags  map[string]string
+	// values contains the named values parsed with a CommandSpec.
+	values map[string]interface{}
+}
+
+// CommandArgs returns the parsed arguments of the command contained in the effective message.
+// The result is cached for the rest of the update; if a handlers.Command with a CommandSpec matched the update, the
+// arguments will already have been validated.
+func (c *Context) CommandArgs() (*CommandArgs, error) {
+	if args, ok := c.Data[commandArgsDataKey].(*CommandArgs); ok {
+		return args, nil
+	}
+	if c.EffectiveMessage == nil {
+		return nil, errors.New("no message to get command arguments from")
+	}
+
+	args, err := ParseCommandArgs(c.EffectiveMessage)
+	if err != nil {
+		return nil, err
+	}
+	if c.Data == nil {
+		c.Data = map[string]interface{}{}
+	}
+	c.Data[commandArgsDataKey] = args
+	return args, nil
+}
+
+// ParseCommandArgs parses the arguments following the command at the start of the message text (or caption).
+func ParseCommandArgs(msg *gotgbot.Message) (*CommandArgs, error) {
+	text := msg.GetText()
+	cmdEnd := strings.IndexFunc(text, unicode.IsSpace)
+	if cmdEnd < 0 {
+		cmdEnd = len(text)
+	}
+
+	args := &CommandArgs{
+		Command: strings.SplitN(text[:cmdEnd], "@", 2)[0],
+		msg:     msg,
+		text:    text,
+		flags:   map[string]string{},
+	}
+
+	mentions := map[int]gotgbot.ParsedMessageEntity{}
+	for _, ent := range textMentions(msg) {
+		mentions[int(ent.Offset)] = ent
+	}
+
+	for i := cmdEnd; ; {
+		for i < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			i += size
+		}
+		if i >= len(text) {
+			break
+		}
+
+		// Text mentions may contain whitespace, so the whole mention is a single argument.
+		if ent, ok := mentions[i]; ok && ent.User != nil {
+			end := i + int(ent.Length)
+			args.tokens = append(args.tokens, commandArg{value: text[i:end], offset: i, user: ent.User})
+			i = end
+			continue
+		}
+
+		value, end, err := readArg(text, i)
+		if err != nil {
+			return nil, err
+		}
+		arg := commandArg{value: value, offset: i}
+
+		// key=value flags must have an unquoted key.
+		keyEnd := i
+		for keyEnd < len(text) && isFlagKeyChar(text[keyEnd], keyEnd == i) {
+			keyEnd++
+		}
+		if keyEnd > i && keyEnd < len(text) && text[keyEnd] == '=' {
+			arg.key = text[i:keyEnd]
+			arg.flag, _, err = readArg(text, keyEnd+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		args.tokens = append(args.tokens, arg)
+		i = end
+	}
+
+	// Without a CommandSpec, all key=value arguments are flags.
+	for _, tok := range args.tokens {
+		if tok.key != "" {
+			args.flags[tok.key] = tok.flag
+			continue
+		}
+		args.args = append(args.args, tok)
+	}
+	return args, nil
+}
+
+// textMentions returns the text mentions in the message text, or caption for media messages.
+func textMentions(msg *gotgbot.Message) []gotgbot.ParsedMessageEntity {
+	if msg.Caption != "" {
+		return msg.ParseCaptionEntityTypes(map[string]struct{}{"text_mention": {}})
+	}
+	return msg.ParseEntityTypes(map[string]struct{}{"text_mention": {}})
+}
+
+func isFlagKeyChar(c byte, first bool) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || !first && (c >= '0' && c <= '9' || c == '-')
+}
+
+// readArg reads a single argument starting at the given offset, handling quotes and escapes. It returns the unquoted
+// value, and the offset of the end of the argument.
+func readArg(text string, i int) (string, int, error) {
+	bd := strings.Builder{}
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case unicode.IsSpace(r):
+			return bd.String(), i, nil
+
+		case r == '\\' && i+1 < len(text):
+			next, nextSize := utf8.DecodeRuneInString(text[i+1:])
+			bd.WriteRune(next)
+			i += 1 + nextSize
+
+		case r == '"' || r == '\'':
+			start := i
+			for i++; ; {
+				if i >= len(text) {
+					return "", 0, fmt.Errorf("%w at byte offset %d", ErrUnterminatedQuote, start)
+				}
+				c, cSize := utf8.DecodeRuneInString(text[i:])
+				if c == r {
+					i++
+					break
+				}
+				if c == '\\' && i+1 < len(text) {
+					c, cSize = utf8.DecodeRuneInString(text[i+1:])
+					i++
+				}
+				bd.WriteRune(c)
+				i += cSize
+			}
+
+		default:
+			bd.WriteRune(r)
+			i += size
+		}
+	}
+	return bd.String(), i, nil
+}
+
+// Len returns the number of positional arguments.
+func (a *CommandArgs) Len() int {
+	return len(a.args)
+}
+
+// Args returns all the positional arguments.
+func (a *CommandArgs) Args() []string {
+	out := make([]string, 0, len(a.args))
+	for _, arg := range a.args {
+		out = append(out, arg.value)
+	}
+	return out
+}
+
+// Arg returns the positional argument at the given index, or an empty string if there is none.
+func (a *CommandArgs) Arg(i int) string {
+	if i < 0 || i >= len(a.args) {
+		return ""
+	}
+	return a.args[i].value
+}
+
+// Rest returns the raw message text, starting at the positional argument at the given index. Quotes and escapes are
+// kept as-is.
+func (a *CommandArgs) Rest(i int) string {
+	if i < 0 || i >= len(a.args) {
+		return ""
+	}
+	return strings.TrimSpace(a.text[a.args[i].offset:])
+}
+
+// Flag returns the value of the given key=value flag.
+func (a *CommandArgs) Flag(key string) (string, bool) {
+	v, ok := a.flags[key]
+	return v, ok
+}
+
+// Int parses the positional argument at the given index as an integer.
+func (a *CommandArgs) Int(i int) (int64, error) {
+	arg, err := a.getArg(i)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(arg.value, 10, 64)
+}
+
+// Duration parses the positional argument at the given index as a duration. On top of the formats supported by
+// time.ParseDuration, days ("d") and weeks ("w") are supported; eg, "2d", or "1w".
+func (a *CommandArgs) Duration(i int) (time.Duration, error) {
+	arg, err := a.getArg(i)
+	if err != nil {
+		return 0, err
+	}
+	return parseDuration(arg.value)
+}
+
+// User parses the positional argument at the given index as a user: either a text mention, or a numeric user ID.
+// Note that usernames cannot be resolved to users by the Bot API, so are not supported.
+func (a *CommandArgs) User(i int) (*gotgbot.User, error) {
+	arg, err := a.getArg(i)
+	if err != nil {
+		return nil, err
+	}
+	return a.parseUser(arg)
+}
+
+// ReplyUser returns the sender of the message being replied to by the command, if any.
+func (a *CommandArgs) ReplyUser() *gotgbot.User {
+	if a.msg.ReplyToMessage == nil || a.msg.ReplyToMessage.ForumTopicCreated != nil {
+		// Messages sent in forum topics are replies to the topic creation message.
+		return nil
+	}
+	return a.msg.ReplyToMessage.From
+}
+
+// Get returns the value of a string argument or flag parsed with a CommandSpec.
+func (a *CommandArgs) Get(name string) string {
+	v, _ := a.values[name].(string)
+	return v
+}
+
+// GetInt returns the value of an integer argument or flag parsed with a CommandSpec.
+func (a *CommandArgs) GetInt(name string) int64 {
+	v, _ := a.values[name].(int64)
+	return v
+}
+
+// GetDuration returns the value of a duration argument or flag parsed with a CommandSpec.
+func (a *CommandArgs) GetDuration(name string) time.Duration {
+	v, _ := a.values[name].(time.Duration)
+	return v
+}
+
+// GetUser returns the value of a user argument or flag parsed with a CommandSpec.
+func (a *CommandArgs) GetUser(name string) *gotgbot.User {
+	v, _ := a.values[name].(*gotgbot.User)
+	return v
+}
+
+// Has checks whether an optional argument or flag was set, when parsed with a CommandSpec.
+func (a *CommandArgs) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+func (a *CommandArgs) getArg(i int) (commandArg, error) {
+	if i < 0 || i >= len(a.args) {
+		return commandArg{}, fmt.Errorf("%w at index %d", ErrMissingArgument, i)
+	}
+	return a.args[i], nil
+}
+
+// parseArg parses an argument value to the given type.
+func (a *CommandArgs) parseArg(arg commandArg, t ArgType) (interface{}, error) {
+	switch t {
+	case ArgInt:
+		v, err := strconv.ParseInt(arg.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", arg.value)
+		}
+		return v, nil
+	case ArgDuration:
+		v, err := parseDuration(arg.value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a duration", arg.value)
+		}
+		return v, nil
+	case ArgUser:
+		return a.parseUser(arg)
+	default:
+		return arg.value, nil
+	}
+}
+
+func (a *CommandArgs) parseUser(arg commandArg) (*gotgbot.User, error) {
+	if arg.user != nil {
+		return arg.user, nil
+	}
+
+	id, err := strconv.ParseInt(arg.value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a user mention or user ID", arg.value)
+	}
+	return &gotgbot.User{Id: id}, nil
+}
+
+// parseDuration parses a duration, adding support for days and weeks to time.ParseDuration.
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(s, suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,179 @@
+package ext_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+func TestParseCommandArgs(t *testing.T) {
+	mentioned := &gotgbot.User{Id: 42, FirstName: "Jöhn"}
+	msg := &gotgbot.Message{
+		Text: `/ban@my_bot Jöhn 12 "a quoted \"arg\"" 'single' esc\ aped 2d silent=yes note="with space"`,
+		Entities: []gotgbot.MessageEntity{
+			{Type: "bot_command", Offset: 0, Length: 11},
+			{Type: "text_mention", Offset: 12, Length: 4, User: mentioned},
+		},
+	}
+
+	args, err := ext.ParseCommandArgs(msg)
+	if err != nil {
+		t.Fatalf("failed to parse args: %v", err)
+	}
+
+	if args.Command != "/ban" {
+		t.Errorf("unexpected command: %q", args.Command)
+	}
+	expectedArgs := []string{"Jöhn", "12", `a quoted "arg"`, "single", "esc aped", "2d"}
+	if !reflect.DeepEqual(args.Args(), expectedArgs) {
+		t.Errorf("unexpected args: %q", args.Args())
+	}
+	if v, _ := args.Flag("silent"); v != "yes" {
+		t.Errorf("unexpected silent flag: %q", v)
+	}
+	if v, _ := args.Flag("note"); v != "with space" {
+		t.Errorf("unexpected note flag: %q", v)
+	}
+
+	if u, err := args.User(0); err != nil || u != mentioned {
+		t.Errorf("expected text mention user, got %v (%v)", u, err)
+	}
+	if i, err := args.Int(1); err != nil || i != 12 {
+		t.Errorf("expected int 12, got %d (%v)", i, err)
+	}
+	if u, err := args.User(1); err != nil || u.Id != 12 {
+		t.Errorf("expected user ID 12, got %v (%v)", u, err)
+	}
+	if d, err := args.Duration(5); err != nil || d != 48*time.Hour {
+		t.Errorf("expected 2 day duration, got %s (%v)", d, err)
+	}
+	if _, err := args.Int(10); !errors.Is(err, ext.ErrMissingArgument) {
+		t.Errorf("expected missing argument error, got %v", err)
+	}
+
+	if _, err := ext.ParseCommandArgs(&gotgbot.Message{Text: `/cmd "unterminated`}); !errors.Is(err, ext.ErrUnterminatedQuote) {
+		t.Errorf("expected unterminated quote error, got %v", err)
+	}
+}
+
+func TestCommandSpec(t *testing.T) {
+	spec := ext.CommandSpec{
+		Args: []ext.ArgSpec{
+			{Name: "user", Type: ext.ArgUser},
+			{Name: "duration", Type: ext.ArgDuration, Optional: true},
+			{Name: "reason", Type: ext.ArgText, Optional: true},
+		},
+		Flags: []ext.ArgSpec{
+			{Name: "silent", Optional: true},
+		},
+	}
+
+	if usage := spec.Usage("/ban"); usage != "/ban <user> [duration] [reason...] [silent=<value>]" {
+		t.Errorf("unexpected usage: %q", usage)
+	}
+
+	args, err := ext.ParseCommandArgs(&gotgbot.Message{Text: "/ban 123 1h silent=1 being  rude"})
+	if err != nil {
+		t.Fatalf("failed to parse args: %v", err)
+	}
+	if err := spec.Parse(args); err != nil {
+		t.Fatalf("failed to validate args: %v", err)
+	}
+	if args.GetUser("user").Id != 123 || args.GetDuration("duration") != time.Hour || args.Get("reason") != "being  rude" || args.Get("silent") != "1" {
+		t.Errorf("unexpected values: %v %s %q %q", args.GetUser("user"), args.GetDuration("duration"), args.Get("reason"), args.Get("silent"))
+	}
+
+	// Replying to a user fills the user argument.
+	replied := &gotgbot.User{Id: 456}
+	args, err = ext.ParseCommandArgs(&gotgbot.Message{Text: "/ban 2d", ReplyToMessage: &gotgbot.Message{From: replied}})
+	if err != nil {
+		t.Fatalf("failed to parse args: %v", err)
+	}
+	if err := spec.Parse(args); err != nil {
+		t.Fatalf("failed to validate args: %v", err)
+	}
+	if args.GetUser("user") != replied || args.GetDuration("duration") != 48*time.Hour || args.Has("reason") {
+		t.Errorf("unexpected values: %v %s %v", args.GetUser("user"), args.GetDuration("duration"), args.Has("reason"))
+	}
+
+	for name, text := range map[string]string{
+		"missing user":     "/ban",
+		"invalid duration": "/ban 123 soon",
+		"undeclared flag":  "/ban 123 loud=1",
+	} {
+		t.Run(name, func(t *testing.T) {
+			args, err := ext.ParseCommandArgs(&gotgbot.Message{Text: text})
+			if err != nil {
+				t.Fatalf("failed to parse args: %v", err)
+			}
+			var usageErr *ext.UsageError
+			if err := spec.Parse(args); !errors.As(err, &usageErr) {
+				t.Fatalf("expected usage error, got %v", err)
+			}
+			if usageErr.Usage != spec.Usage("/ban") {
+				t.Errorf("unexpected usage: %q", usageErr.Usage)
+			}
+		})
+	}
+}
+
+func TestCommandSpecMultiWordMention(t *testing.T) {
+	spec := ext.CommandSpec{
+		Args: []ext.ArgSpec{
+			{Name: "user", Type: ext.ArgUser},
+			{Name: "duration", Type: ext.ArgDuration, Optional: true},
+		},
+	}
+
+	mentioned := &gotgbot.User{Id: 42, FirstName: "John", LastName: "Smith"}
+	args, err := ext.ParseCommandArgs(&gotgbot.Message{
+		Text:     "/ban John Smith 1h",
+		Entities: []gotgbot.MessageEntity{{Type: "text_mention", Offset: 5, Length: 10, User: mentioned}},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse args: %v", err)
+	}
+	if !reflect.DeepEqual(args.Args(), []string{"John Smith", "1h"}) {
+		t.Errorf("unexpected args: %q", args.Args())
+	}
+	if err := spec.Parse(args); err != nil {
+		t.Fatalf("failed to validate args: %v", err)
+	}
+	if args.GetUser("user") != mentioned || args.GetDuration("duration") != time.Hour {
+		t.Errorf("unexpected values: %v %s", args.GetUser("user"), args.GetDuration("duration"))
+	}
+}
+
+func TestCommandSpecTextFlags(t *testing.T) {
+	spec := ext.CommandSpec{
+		Args:  []ext.ArgSpec{{Name: "text", Type: ext.ArgText}},
+		Flags: []ext.ArgSpec{{Name: "silent", Optional: true}},
+	}
+
+	for text, expected := range map[string]struct {
+		text   string
+		silent bool
+	}{
+		// Undeclared keys are not flags.
+		"/note remember x=5 tomorrow": {text: "remember x=5 tomorrow"},
+		"/note x=5 tomorrow":          {text: "x=5 tomorrow"},
+		// Declared flags are only extracted before the text starts.
+		"/note silent=1 remember":          {text: "remember", silent: true},
+		"/note remember silent=1 tomorrow": {text: "remember silent=1 tomorrow"},
+	} {
+		args, err := ext.ParseCommandArgs(&gotgbot.Message{Text: text})
+		if err != nil {
+			t.Fatalf("failed to parse args: %v", err)
+		}
+		if err := spec.Parse(args); err != nil {
+			t.Fatalf("%s: failed to validate args: %v", text, err)
+		}
+		if args.Get("text") != expected.text || args.Has("silent") != expected.silent {
+			t.Errorf("%s: unexpected values: %q %v", text, args.Get("text"), args.Has("silent"))
+		}
+	}
+}
@@ -53,6 +53,16 @@ type UpdateDispatcher interface {
 	Stop()
 }
 
+// The TrackingUpdateDispatcher interface is implemented by UpdateDispatchers which can report when each update has
+// finished processing. When polling with an OffsetStore, the Updater uses it to only save offsets once the updates
+// have been handled.
+type TrackingUpdateDispatcher interface {
+	UpdateDispatcher
+	// StartTracking is the same as Start, but calls done once each update has finished processing, whether it
+	// succeeded or not.
+	StartTracking(b *gotgbot.Bot, updates <-chan json.RawMessage, done func(update json.RawMessage))
+}
+
 // The Dispatcher struct is the default UpdateDispatcher implementation.
 // It supports grouping of update handlers, allowing for powerful update handling flows.
 // Customise the handling of updates by wrapping the Processor struct.
@@ -91,7 +101,7 @@ type Dispatcher struct {
 }
 
 // Ensure compile-time type safety.
-var _ UpdateDispatcher = &Dispatcher{}
+var _ TrackingUpdateDispatcher = &Dispatcher{}
 
 // DispatcherOpts can be used to configure or override default Dispatcher behaviours.
 type DispatcherOpts struct {
@@ -189,6 +199,12 @@ func (d *Dispatcher) MaxUsage() int {
 // Start to handle incoming updates.
 // This is a blocking method; it should be called as a goroutine, such that it can receive incoming updates.
 func (d *Dispatcher) Start(b *gotgbot.Bot, updates <-chan json.RawMessage) {
+	d.StartTracking(b, updates, nil)
+}
+
+// StartTracking is the same as Start, but calls done once each update has finished processing.
+// The done func may be nil.
+func (d *Dispatcher) StartTracking(b *gotgbot.Bot, updates <-chan json.RawMessage, done func(update json.RawMessage)) {
 	// Listen to updates as they come in from the updater.
 	for upd := range updates {
 		d.waitGroup.Add(1)
@@ -203,6 +219,9 @@ func (d *Dispatcher) Start(b *gotgbot.Bot, updates <-chan json.RawMessage) {
 		go func(upd json.RawMessage) {
 			// We defer here so that whatever happens, we can clean up the dispatcher.
 			defer func() {
+				if done != nil {
+					done(upd)
+				}
 				if d.limiter != nil {
 					// Pop an item from the limiter, allowing another update to process.
 					<-d.limiter
@@ -0,0 +1,323 @@
+package callbackdata
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters"
+)
+
+// MaxLength is the maximum length of callback data allowed by telegram, in bytes.
+const MaxLength = 64
+
+var (
+	ErrInvalidData     = errors.New("invalid callback data")
+	ErrVersionMismatch = errors.New("callback data version mismatch")
+	ErrTooLong         = errors.New("callback data too long")
+)
+
+const (
+	// separator separates the prefix, version and fields of the encoded data.
+	separator = ':'
+	// storedMarker marks encoded data which was spilled into the Store; it is followed by the store key.
+	storedMarker = '*'
+	// escape is used to escape the special characters within string fields.
+	escape = '%'
+)
+
+// CodecOpts is the set of fields used to configure a Codec.
+type CodecOpts struct {
+	// Version is encoded alongside the data. It should be incremented whenever the struct fields change in an
+	// incompatible way, so that buttons sent with an older version fail to decode with ErrVersionMismatch, rather
+	// than being decoded into the wrong fields.
+	Version int
+	// Store is used to hold data which doesn't fit within MaxLength bytes. The callback data then only contains a
+	// short key into the store. If nil, encoding data which is too long fails with ErrTooLong.
+	Store Store
+}
+
+// Codec encodes a struct type into compact callback data, under a short prefix which identifies it.
+//
+// The exported fields of the struct are encoded in order, separated by colons: "<prefix>:<version>:<field>:<field>".
+// Supported field types are strings, booleans, integers and floats; integers are encoded in base 36 to save space.
+// If the encoded data is longer than MaxLength, it is moved to the Store.
+type Codec[T any] struct {
+	prefix  string
+	version string
+	store   Store
+	// fields contains the indexes of the struct fields to encode.
+	fields []int
+}
+
+// NewCodec registers the struct type T under the given prefix, which should be short and unique to this type.
+// The prefix may not contain any colons, asterisks or percent signs.
+func NewCodec[T any](prefix string, opts *CodecOpts) (*Codec[T], error) {
+	if prefix == "" || strings.ContainsAny(prefix, string([]rune{separator, storedMarker, escape})) {
+		return nil, fmt.Errorf("invalid callback data prefix %q", prefix)
+	}
+
+	var version int
+	var store Store
+	if opts != nil {
+		version = opts.Version
+		store = opts.Store
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("callback data type must be a struct, got %s", t)
+	}
+
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if !isSupportedKind(f.Type.Kind()) {
+			return nil, fmt.Errorf("unsupported type %s for callback data field %s", f.Type, f.Name)
+		}
+		fields = append(fields, i)
+	}
+
+	return &Codec[T]{
+		prefix:  prefix,
+		version: strconv.FormatInt(int64(version), 36),
+		store:   store,
+		fields:  fields,
+	}, nil
+}
+
+func isSupportedKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// Prefix returns the prefix this codec was registered with.
+func (c *Codec[T]) Prefix() string {
+	return c.prefix
+}
+
+// Match returns true if the callback data was encoded by this codec; the data may still fail to decode.
+func (c *Codec[T]) Match(data string) bool {
+	return strings.HasPrefix(data, c.prefix+string(separator))
+}
+
+// Filter returns a callback query filter, matching the callback queries with data encoded by this codec.
+func (c *Codec[T]) Filter() filters.CallbackQuery {
+	return func(cq *gotgbot.CallbackQuery) bool {
+		return c.Match(cq.Data)
+	}
+}
+
+// Encode encodes the value into callback data.
+func (c *Codec[T]) Encode(v T) (string, error) {
+	rv := reflect.ValueOf(v)
+	fields := make([]string, 0, len(c.fields))
+	for _, i := range c.fields {
+		fields = append(fields, encodeField(rv.Field(i)))
+	}
+
+	header := c.prefix + string(separator) + c.version + string(separator)
+	payload := strings.Join(fields, string(separator))
+	if len(header)+len(payload) <= MaxLength {
+		return header + payload, nil
+	}
+
+	if c.store == nil {
+		return "", fmt.Errorf("%w: %d bytes", ErrTooLong, len(header)+len(payload))
+	}
+
+	key, err := newStoreKey()
+	if err != nil {
+		return "", err
+	}
+	data := header + string(storedMarker) + key
+	if len(data) > MaxLength {
+		return "", fmt.Errorf("%w: prefix is too long to store data", ErrTooLong)
+	}
+
+	err = c.store.Set(key, payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to store callback data: %w", err)
+	}
+	return data, nil
+}
+
+// Button is a helper to create an inline keyboard button with the encoded value as its callback data.
+func (c *Codec[T]) Button(text string, v T) (gotgbot.InlineKeyboardButton, error) {
+	data, err := c.Encode(v)
+	if err != nil {
+		return gotgbot.InlineKeyboardButton{}, err
+	}
+	return gotgbot.InlineKeyboardButton{Text: text, CallbackData: data}, nil
+}
+
+// Decode decodes callback data into a new value.
+// Data spilled into a store which no longer holds it returns ErrExpired.
+func (c *Codec[T]) Decode(data string) (T, error) {
+	var v T
+	if !c.Match(data) {
+		return v, fmt.Errorf("%w: missing prefix %q", ErrInvalidData, c.prefix)
+	}
+
+	version, payload, ok := strings.Cut(strings.TrimPrefix(data, c.prefix+string(separator)), string(separator))
+	if !ok {
+		return v, fmt.Errorf("%w: missing version", ErrInvalidData)
+	}
+	if version != c.version {
+		return v, fmt.Errorf("%w: got version %s, expected %s", ErrVersionMismatch, version, c.version)
+	}
+
+	if strings.HasPrefix(payload, string(storedMarker)) {
+		if c.store == nil {
+			return v, fmt.Errorf("%w: no store to load data from", ErrInvalidData)
+		}
+
+		var err error
+		payload, err = c.store.Get(strings.TrimPrefix(payload, string(storedMarker)))
+		if err != nil {
+			return v, fmt.Errorf("failed to load callback data: %w", err)
+		}
+	}
+
+	fields := strings.Split(payload, string(separator))
+	if len(c.fields) == 0 && payload == "" {
+		fields = nil
+	}
+	if len(fields) != len(c.fields) {
+		return v, fmt.Errorf("%w: got %d fields, expected %d", ErrInvalidData, len(fields), len(c.fields))
+	}
+
+	rv := reflect.ValueOf(&v).Elem()
+	for n, i := range c.fields {
+		err := decodeField(rv.Field(i), fields[n])
+		if err != nil {
+			return v, fmt.Errorf("%w: field %s: %s", ErrInvalidData, rv.Type().Field(i).Name, err.Error())
+		}
+	}
+	return v, nil
+}
+
+func encodeField(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return escapeString(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			return "1"
+		}
+		return "0"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 36)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 36)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	}
+	// Unreachable, since field types are checked by NewCodec.
+	return ""
+}
+
+func decodeField(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		str, err := unescapeString(s)
+		if err != nil {
+			return err
+		}
+		v.SetString(str)
+	case reflect.Bool:
+		switch s {
+		case "1":
+			v.SetBool(true)
+		case "0":
+			v.SetBool(false)
+		default:
+			return fmt.Errorf("invalid bool %q", s)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 36, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 36, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	}
+	return nil
+}
+
+// escapeString percent-encodes the characters with a special meaning in the encoded data.
+func escapeString(s string) string {
+	if !strings.ContainsAny(s, string([]rune{separator, storedMarker, escape})) {
+		return s
+	}
+
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case separator, storedMarker, escape:
+			sb.WriteString(fmt.Sprintf("%c%02X", escape, r))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func unescapeString(s string) (string, error) {
+	if !strings.ContainsRune(s, escape) {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != escape {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", errors.New("truncated escape sequence")
+		}
+		b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape sequence %q", s[i:i+3])
+		}
+		sb.WriteByte(byte(b))
+		i += 2
+	}
+	return sb.String(), nil
+}
+
+// newStoreKey generates a random key for the store; 8 random bytes are encoded to 11 characters.
+func newStoreKey() (string, error) {
+	bs := make([]byte, 8)
+	_, err := rand.Read(bs)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate store key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bs), nil
+}
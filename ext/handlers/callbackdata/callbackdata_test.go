@@ -0,0 +1,130 @@
+package callbackdata_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/callbackdata"
+)
+
+type action struct {
+	Action  string
+	UserId  int64
+	Confirm bool
+	Amount  float64
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec, err := callbackdata.NewCodec[action]("act", &callbackdata.CodecOpts{Version: 1})
+	if err != nil {
+		t.Fatalf("failed to create codec: %v", err)
+	}
+
+	for name, v := range map[string]action{
+		"simple":          {Action: "ban", UserId: 123456789, Confirm: true, Amount: 1.5},
+		"empty":           {},
+		"negative":        {UserId: -1001234567890},
+		"special strings": {Action: "a:b*c%d"},
+		"unicode":         {Action: "👍ü"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := codec.Encode(v)
+			if err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+			if !codec.Match(data) || len(data) > callbackdata.MaxLength {
+				t.Fatalf("unexpected encoded data %q", data)
+			}
+
+			got, err := codec.Decode(data)
+			if err != nil {
+				t.Fatalf("failed to decode %q: %v", data, err)
+			}
+			if got != v {
+				t.Errorf("expected %+v, got %+v", v, got)
+			}
+		})
+	}
+}
+
+func TestCodecFormat(t *testing.T) {
+	codec, err := callbackdata.NewCodec[action]("act", &callbackdata.CodecOpts{Version: 1})
+	if err != nil {
+		t.Fatalf("failed to create codec: %v", err)
+	}
+
+	data, err := codec.Encode(action{Action: "ban:1", UserId: 35, Confirm: true, Amount: 0.5})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	if expected := "act:1:ban%3A1:z:1:0.5"; data != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	if _, err := callbackdata.NewCodec[action]("a:b", nil); err == nil {
+		t.Errorf("expected invalid prefix to fail")
+	}
+	if _, err := callbackdata.NewCodec[struct{ Ids []int64 }]("ids", nil); err == nil {
+		t.Errorf("expected unsupported field type to fail")
+	}
+
+	v1, _ := callbackdata.NewCodec[action]("act", &callbackdata.CodecOpts{Version: 1})
+	v2, _ := callbackdata.NewCodec[action]("act", &callbackdata.CodecOpts{Version: 2})
+	data, err := v1.Encode(action{Action: "ban"})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	if _, err := v2.Decode(data); !errors.Is(err, callbackdata.ErrVersionMismatch) {
+		t.Errorf("expected version mismatch, got %v", err)
+	}
+
+	for _, data := range []string{"act", "act:1", "act:1:ban", "act:1:ban:z:2:0.5", "act:1:ban:z:1:x", "act:1:ban%3:z:1:0"} {
+		if _, err := v1.Decode(data); !errors.Is(err, callbackdata.ErrInvalidData) {
+			t.Errorf("expected invalid data for %q, got %v", data, err)
+		}
+	}
+
+	if _, err := v1.Encode(action{Action: strings.Repeat("a", callbackdata.MaxLength)}); !errors.Is(err, callbackdata.ErrTooLong) {
+		t.Errorf("expected data to be too long without a store, got %v", err)
+	}
+}
+
+func TestCodecStore(t *testing.T) {
+	store := callbackdata.NewLRUStore(2)
+	codec, err := callbackdata.NewCodec[action]("act", &callbackdata.CodecOpts{Store: store})
+	if err != nil {
+		t.Fatalf("failed to create codec: %v", err)
+	}
+
+	long := action{Action: strings.Repeat("a", callbackdata.MaxLength)}
+	var datas []string
+	for i := 0; i < 3; i++ {
+		data, err := codec.Encode(long)
+		if err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		if len(data) > callbackdata.MaxLength {
+			t.Fatalf("stored data is too long: %q", data)
+		}
+		datas = append(datas, data)
+	}
+
+	if store.Len() != 2 {
+		t.Errorf("expected store to hold 2 entries, got %d", store.Len())
+	}
+	if _, err := codec.Decode(datas[0]); !errors.Is(err, callbackdata.ErrExpired) {
+		t.Errorf("expected evicted entry to be expired, got %v", err)
+	}
+	for _, data := range datas[1:] {
+		got, err := codec.Decode(data)
+		if err != nil {
+			t.Fatalf("failed to decode stored data: %v", err)
+		}
+		if got != long {
+			t.Errorf("unexpected stored value: %+v", got)
+		}
+	}
+}
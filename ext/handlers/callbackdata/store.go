@@ -0,0 +1,92 @@
+package callbackdata
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+)
+
+// ErrExpired is returned when callback data can't be found in the store; eg, because it was evicted.
+var ErrExpired = errors.New("callback data expired")
+
+// Store holds the encoded data which doesn't fit within the callback data limits.
+type Store interface {
+	// Get returns the data stored under the key. If the key is unknown, ErrExpired should be returned.
+	Get(key string) (string, error)
+	// Set stores the data under the given key.
+	Set(key string, data string) error
+}
+
+// DefaultLRUStoreSize is the default number of entries kept by the LRUStore.
+const DefaultLRUStoreSize = 10_000
+
+// LRUStore is a thread-safe in-memory implementation of the Store interface. Once full, the least recently used
+// entries are evicted; buttons using evicted entries fail to decode with ErrExpired.
+type LRUStore struct {
+	// size is the maximum number of entries to keep.
+	size int
+	// entries keeps track of the order in which entries were used; most recent first.
+	entries *list.List
+	// keys maps each key to its entry.
+	keys map[string]*list.Element
+	// lock allows us to ensure synchronous data access.
+	lock sync.Mutex
+}
+
+type lruEntry struct {
+	key  string
+	data string
+}
+
+// NewLRUStore creates a new LRUStore, holding up to size entries. If size is 0 or less, DefaultLRUStoreSize is used.
+func NewLRUStore(size int) *LRUStore {
+	if size <= 0 {
+		size = DefaultLRUStoreSize
+	}
+	return &LRUStore{
+		size:    size,
+		entries: list.New(),
+		keys:    map[string]*list.Element{},
+	}
+}
+
+var _ Store = &LRUStore{}
+
+func (s *LRUStore) Get(key string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, ok := s.keys[key]
+	if !ok {
+		return "", ErrExpired
+	}
+	s.entries.MoveToFront(e)
+	return e.Value.(*lruEntry).data, nil
+}
+
+func (s *LRUStore) Set(key string, data string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if e, ok := s.keys[key]; ok {
+		e.Value.(*lruEntry).data = data
+		s.entries.MoveToFront(e)
+		return nil
+	}
+
+	s.keys[key] = s.entries.PushFront(&lruEntry{key: key, data: data})
+	for s.entries.Len() > s.size {
+		oldest := s.entries.Back()
+		s.entries.Remove(oldest)
+		delete(s.keys, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries currently held by the store.
+func (s *LRUStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.entries.Len()
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"unicode/utf8"
 
@@ -18,8 +20,25 @@ type Command struct {
 	AllowChannel bool
 	Command      string // set to a lowercase value for case-insensitivity
 	Response     Response
+
+	// Spec optionally describes the arguments expected by the command. If set, the arguments are validated before
+	// calling the Response, and the parsed values can be obtained with ext.Context.CommandArgs.
+	Spec *ext.CommandSpec
+	// OnUsageError is called when the command arguments do not match the Spec.
+	// If nil, the usage error is sent as a reply to the command message.
+	OnUsageError func(b *gotgbot.Bot, ctx *ext.Context, err *ext.UsageError) error
+
+	// Description is shown to users in the telegram command list. Commands without a description are not registered
+	// by ext.Dispatcher.SyncBotCommands.
+	Description string
+	// Scope is the scope of users the command is registered for. If nil, the default scope is used.
+	Scope gotgbot.BotCommandScope
+	// LanguageCode is the language the command is registered for. If empty, it is registered for all languages.
+	LanguageCode string
 }
 
+var _ ext.BotCommandHandler = Command{}
+
 // NewCommand creates a new case-insensitive command.
 // By default, commands do not work on edited messages, or channel posts. These can be enabled by setting the
 // AllowEdited and AllowChannel fields respectively.
@@ -51,6 +70,63 @@ func (c Command) SetTriggers(triggers []rune) Command {
 	return c
 }
 
+// SetSpec sets the arguments expected by this command.
+func (c Command) SetSpec(spec ext.CommandSpec) Command {
+	c.Spec = &spec
+	return c
+}
+
+// SetOnUsageError sets the function called when the command arguments do not match the Spec.
+func (c Command) SetOnUsageError(onUsageError func(b *gotgbot.Bot, ctx *ext.Context, err *ext.UsageError) error) Command {
+	c.OnUsageError = onUsageError
+	return c
+}
+
+// SetDescription sets the description of this command, to be registered with ext.Dispatcher.SyncBotCommands.
+func (c Command) SetDescription(description string) Command {
+	c.Description = description
+	return c
+}
+
+// SetScope sets the scope of users this command is registered for.
+func (c Command) SetScope(scope gotgbot.BotCommandScope) Command {
+	c.Scope = scope
+	return c
+}
+
+// SetLanguageCode sets the language this command is registered for.
+func (c Command) SetLanguageCode(languageCode string) Command {
+	c.LanguageCode = languageCode
+	return c
+}
+
+// BotCommands returns the telegram command described by this handler, if it has a description.
+// Commands which can't be triggered with a forward-slash aren't telegram-native, so are never returned.
+func (c Command) BotCommands() []ext.BotCommandInfo {
+	if c.Description == "" || !containsRune(c.Triggers, '/') {
+		return nil
+	}
+	return []ext.BotCommandInfo{{
+		BotCommand: gotgbot.BotCommand{
+			Command:     c.Command,
+			Description: c.Description,
+		},
+		BotCommandTarget: ext.BotCommandTarget{
+			Scope:        c.Scope,
+			LanguageCode: c.LanguageCode,
+		},
+	}}
+}
+
+func containsRune(rs []rune, r rune) bool {
+	for _, x := range rs {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
+
 func (c Command) CheckUpdate(b *gotgbot.Bot, ctx *ext.Context) bool {
 	if ctx.Message != nil {
 		if ctx.Message.GetText() == "" {
@@ -85,9 +161,52 @@ func (c Command) CheckUpdate(b *gotgbot.Bot, ctx *ext.Context) bool {
 }
 
 func (c Command) HandleUpdate(b *gotgbot.Bot, ctx *ext.Context) error {
+	if c.Spec != nil {
+		if err := c.parseArgs(ctx); err != nil {
+			return c.handleUsageError(b, ctx, err)
+		}
+	}
 	return c.Response(b, ctx)
 }
 
+// parseArgs parses the command arguments, and validates them against the Spec.
+func (c Command) parseArgs(ctx *ext.Context) *ext.UsageError {
+	args, err := ctx.CommandArgs()
+	if err == nil {
+		err = c.Spec.Parse(args)
+	}
+	if err == nil {
+		return nil
+	}
+
+	var usageErr *ext.UsageError
+	if errors.As(err, &usageErr) {
+		return usageErr
+	}
+
+	// Arguments which can't be tokenized, such as unterminated quotes.
+	trigger := "/"
+	if len(c.Triggers) > 0 {
+		trigger = string(c.Triggers[0])
+	}
+	return &ext.UsageError{
+		Usage:  c.Spec.Usage(trigger + c.Command),
+		Reason: fmt.Sprintf("Invalid arguments: %s.", err.Error()),
+	}
+}
+
+func (c Command) handleUsageError(b *gotgbot.Bot, ctx *ext.Context, usageErr *ext.UsageError) error {
+	if c.OnUsageError != nil {
+		return c.OnUsageError(b, ctx, usageErr)
+	}
+
+	_, err := ctx.EffectiveMessage.Reply(b, usageErr.Error(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to send usage error: %w", err)
+	}
+	return nil
+}
+
 func (c Command) Name() string {
 	return "command_" + c.Command
 }
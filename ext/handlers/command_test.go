@@ -0,0 +1,55 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+)
+
+func TestCommandSpec(t *testing.T) {
+	b := NewTestBot()
+
+	var gotCount int64
+	var gotUsageErr *ext.UsageError
+	cmd := handlers.NewCommand("repeat", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		args, err := ctx.CommandArgs()
+		if err != nil {
+			return err
+		}
+		gotCount = args.GetInt("count")
+		return nil
+	}).SetSpec(ext.CommandSpec{
+		Args: []ext.ArgSpec{
+			{Name: "count", Type: ext.ArgInt},
+			{Name: "text", Type: ext.ArgText},
+		},
+	}).SetOnUsageError(func(b *gotgbot.Bot, ctx *ext.Context, err *ext.UsageError) error {
+		gotUsageErr = err
+		return nil
+	})
+
+	ctx := NewCommandMessage(1, 1, "repeat", []string{"3", "hello"})
+	if !cmd.CheckUpdate(b, ctx) {
+		t.Fatal("command should match")
+	}
+	if err := cmd.HandleUpdate(b, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotCount != 3 || gotUsageErr != nil {
+		t.Errorf("expected count 3 and no usage error, got %d and %v", gotCount, gotUsageErr)
+	}
+
+	gotCount = 0
+	ctx = NewCommandMessage(1, 1, "repeat", []string{"three", "hello"})
+	if err := cmd.HandleUpdate(b, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotCount != 0 {
+		t.Errorf("response should not be called on usage errors")
+	}
+	if gotUsageErr == nil || gotUsageErr.Usage != "/repeat <count> <text...>" {
+		t.Errorf("unexpected usage error: %v", gotUsageErr)
+	}
+}
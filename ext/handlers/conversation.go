@@ -3,6 +3,7 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
@@ -166,6 +167,32 @@ func (c Conversation) Name() string {
 	return fmt.Sprintf("conversation_%p", c.States)
 }
 
+// BotCommands returns the bot commands described by the conversation's handlers; entry points first, followed by
+// the exits, states (sorted by key) and fallbacks.
+func (c Conversation) BotCommands() []ext.BotCommandInfo {
+	stateKeys := make([]string, 0, len(c.States))
+	for k := range c.States {
+		stateKeys = append(stateKeys, k)
+	}
+	sort.Strings(stateKeys)
+
+	handlerLists := [][]ext.Handler{c.EntryPoints, c.Exits}
+	for _, k := range stateKeys {
+		handlerLists = append(handlerLists, c.States[k])
+	}
+	handlerLists = append(handlerLists, c.Fallbacks)
+
+	var cmds []ext.BotCommandInfo
+	for _, hs := range handlerLists {
+		for _, h := range hs {
+			if bch, ok := h.(ext.BotCommandHandler); ok {
+				cmds = append(cmds, bch.BotCommands()...)
+			}
+		}
+	}
+	return cmds
+}
+
 // getNextHandler goes through all the handlers in the conversation, until it finds a handler that matches.
 // If no matching handler is found, returns nil.
 func (c Conversation) getNextHandler(b *gotgbot.Bot, ctx *ext.Context) (ext.Handler, error) {
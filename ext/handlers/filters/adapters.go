@@ -0,0 +1,127 @@
+package filters
+
+import "github.com/PaulSonOfLars/gotgbot/v2"
+
+type (
+	// Sender is a filter on the sender of an update. It can be lifted into the update-specific filter types with
+	// the *Sender adapters, such as MessageSender or CallbackQuerySender.
+	Sender func(s *gotgbot.Sender) bool
+	// Chat is a filter on the chat an update happened in. It can be lifted into the update-specific filter types
+	// with the *Chat adapters, such as MessageChat or CallbackQueryChat.
+	Chat func(c *gotgbot.Chat) bool
+)
+
+// MessageSender lifts a Sender filter into a Message filter.
+func MessageSender(f Sender) Message {
+	return func(msg *gotgbot.Message) bool {
+		return f(msg.GetSender())
+	}
+}
+
+// MessageChat lifts a Chat filter into a Message filter.
+func MessageChat(f Chat) Message {
+	return func(msg *gotgbot.Message) bool {
+		return f(&msg.Chat)
+	}
+}
+
+// CallbackQuerySender lifts a Sender filter into a CallbackQuery filter. The sender is the user who pressed the
+// button; not the sender of the message the button is attached to.
+func CallbackQuerySender(f Sender) CallbackQuery {
+	return func(cq *gotgbot.CallbackQuery) bool {
+		s := &gotgbot.Sender{User: &cq.From}
+		if cq.Message != nil {
+			s.ChatId = cq.Message.GetChat().Id
+		}
+		return f(s)
+	}
+}
+
+// CallbackQueryChat lifts a Chat filter into a CallbackQuery filter.
+// Callback queries from inline messages have no chat, so never match.
+func CallbackQueryChat(f Chat) CallbackQuery {
+	return func(cq *gotgbot.CallbackQuery) bool {
+		if cq.Message == nil {
+			return false
+		}
+		c := cq.Message.GetChat()
+		return f(&c)
+	}
+}
+
+// ChatJoinRequestSender lifts a Sender filter into a ChatJoinRequest filter.
+func ChatJoinRequestSender(f Sender) ChatJoinRequest {
+	return func(cjr *gotgbot.ChatJoinRequest) bool {
+		return f(&gotgbot.Sender{User: &cjr.From, ChatId: cjr.Chat.Id})
+	}
+}
+
+// ChatJoinRequestChat lifts a Chat filter into a ChatJoinRequest filter.
+func ChatJoinRequestChat(f Chat) ChatJoinRequest {
+	return func(cjr *gotgbot.ChatJoinRequest) bool {
+		return f(&cjr.Chat)
+	}
+}
+
+// ChatMemberSender lifts a Sender filter into a ChatMember filter. The sender is the user who performed the change.
+func ChatMemberSender(f Sender) ChatMember {
+	return func(u *gotgbot.ChatMemberUpdated) bool {
+		return f(&gotgbot.Sender{User: &u.From, ChatId: u.Chat.Id})
+	}
+}
+
+// ChatMemberChat lifts a Chat filter into a ChatMember filter.
+func ChatMemberChat(f Chat) ChatMember {
+	return func(u *gotgbot.ChatMemberUpdated) bool {
+		return f(&u.Chat)
+	}
+}
+
+// ChosenInlineResultSender lifts a Sender filter into a ChosenInlineResult filter.
+func ChosenInlineResultSender(f Sender) ChosenInlineResult {
+	return func(cir *gotgbot.ChosenInlineResult) bool {
+		return f(&gotgbot.Sender{User: &cir.From})
+	}
+}
+
+// InlineQuerySender lifts a Sender filter into an InlineQuery filter.
+func InlineQuerySender(f Sender) InlineQuery {
+	return func(iq *gotgbot.InlineQuery) bool {
+		return f(&gotgbot.Sender{User: &iq.From})
+	}
+}
+
+// PollAnswerSender lifts a Sender filter into a PollAnswer filter.
+func PollAnswerSender(f Sender) PollAnswer {
+	return func(pa *gotgbot.PollAnswer) bool {
+		return f(pa.GetSender())
+	}
+}
+
+// PreCheckoutQuerySender lifts a Sender filter into a PreCheckoutQuery filter.
+func PreCheckoutQuerySender(f Sender) PreCheckoutQuery {
+	return func(pcq *gotgbot.PreCheckoutQuery) bool {
+		return f(&gotgbot.Sender{User: &pcq.From})
+	}
+}
+
+// ShippingQuerySender lifts a Sender filter into a ShippingQuery filter.
+func ShippingQuerySender(f Sender) ShippingQuery {
+	return func(sq *gotgbot.ShippingQuery) bool {
+		return f(&gotgbot.Sender{User: &sq.From})
+	}
+}
+
+// ReactionSender lifts a Sender filter into a Reaction filter.
+func ReactionSender(f Sender) Reaction {
+	return func(mru *gotgbot.MessageReactionUpdated) bool {
+		return f(mru.GetSender())
+	}
+}
+
+// ReactionChat lifts a Chat filter into a Reaction filter.
+func ReactionChat(f Chat) Reaction {
+	return func(mru *gotgbot.MessageReactionUpdated) bool {
+		return f(&mru.Chat)
+	}
+}
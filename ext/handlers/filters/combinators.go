@@ -0,0 +1,56 @@
+package filters
+
+// Filter is the constraint satisfied by all the filter types in this package, as well as by plain predicate funcs
+// such as message.Text. It allows for combining filters of any type with And, Or, Not, All and Any.
+type Filter[T any] interface {
+	~func(T) bool
+}
+
+// And returns a filter which matches if both filters match. The second filter is only checked if the first one
+// matched.
+func And[F Filter[T], T any](f1 F, f2 F) F {
+	return func(t T) bool {
+		return f1(t) && f2(t)
+	}
+}
+
+// Or returns a filter which matches if either filter matches. The second filter is only checked if the first one
+// didn't match.
+func Or[F Filter[T], T any](f1 F, f2 F) F {
+	return func(t T) bool {
+		return f1(t) || f2(t)
+	}
+}
+
+// Not returns a filter which matches if the given filter doesn't.
+func Not[F Filter[T], T any](f F) F {
+	return func(t T) bool {
+		return !f(t)
+	}
+}
+
+// All returns a filter which matches if all the given filters match, checking them in order.
+// An empty list of filters always matches.
+func All[F Filter[T], T any](fs []F) F {
+	return func(t T) bool {
+		for _, f := range fs {
+			if !f(t) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any returns a filter which matches if any of the given filters match, checking them in order.
+// An empty list of filters never matches.
+func Any[F Filter[T], T any](fs []F) F {
+	return func(t T) bool {
+		for _, f := range fs {
+			if f(t) {
+				return true
+			}
+		}
+		return false
+	}
+}
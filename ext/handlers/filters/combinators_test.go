@@ -0,0 +1,102 @@
+package filters_test
+
+import (
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
+)
+
+func TestCombinators(t *testing.T) {
+	noCommands := filters.And(message.Text, filters.Not(message.Command))
+	fromUser := filters.And(message.FromUserID(1), message.Private)
+	anyMedia := filters.Any([]filters.Message{message.Photo, message.Video})
+	allFromUser := filters.All([]filters.Message{message.FromUserID(1), message.Text})
+
+	for name, testParams := range map[string]struct {
+		filter   filters.Message
+		msg      *gotgbot.Message
+		expected bool
+	}{
+		"text": {
+			filter:   noCommands,
+			msg:      &gotgbot.Message{Text: "hello"},
+			expected: true,
+		},
+		"command": {
+			filter: noCommands,
+			msg: &gotgbot.Message{Text: "/start", Entities: []gotgbot.MessageEntity{
+				{Type: "bot_command", Offset: 0, Length: 6},
+			}},
+			expected: false,
+		},
+		"mixed filter types": {
+			filter:   fromUser,
+			msg:      &gotgbot.Message{From: &gotgbot.User{Id: 1}, Chat: gotgbot.Chat{Type: "private"}},
+			expected: true,
+		},
+		"or": {
+			filter:   filters.Or(message.Photo, message.Video),
+			msg:      &gotgbot.Message{Video: &gotgbot.Video{}},
+			expected: true,
+		},
+		"any": {
+			filter:   anyMedia,
+			msg:      &gotgbot.Message{Text: "hello"},
+			expected: false,
+		},
+		"all": {
+			filter:   allFromUser,
+			msg:      &gotgbot.Message{From: &gotgbot.User{Id: 1}, Text: "hello"},
+			expected: true,
+		},
+		"empty all": {
+			filter:   filters.All[filters.Message](nil),
+			msg:      &gotgbot.Message{},
+			expected: true,
+		},
+		"empty any": {
+			filter:   filters.Any[filters.Message](nil),
+			msg:      &gotgbot.Message{},
+			expected: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := testParams.filter(testParams.msg); got != testParams.expected {
+				t.Errorf("expected %v, got %v", testParams.expected, got)
+			}
+		})
+	}
+}
+
+func TestSenderAdapters(t *testing.T) {
+	admins := map[int64]bool{1: true}
+	isAdmin := filters.Sender(func(s *gotgbot.Sender) bool {
+		return admins[s.Id()]
+	})
+
+	adminMessage := filters.MessageSender(isAdmin)
+	if !adminMessage(&gotgbot.Message{From: &gotgbot.User{Id: 1}}) || adminMessage(&gotgbot.Message{From: &gotgbot.User{Id: 2}}) {
+		t.Errorf("unexpected message sender match")
+	}
+
+	adminCallback := filters.And(callbackquery.Prefix("ban:"), filters.CallbackQuerySender(isAdmin))
+	if !adminCallback(&gotgbot.CallbackQuery{From: gotgbot.User{Id: 1}, Data: "ban:2"}) {
+		t.Errorf("expected admin callback query to match")
+	}
+	if adminCallback(&gotgbot.CallbackQuery{From: gotgbot.User{Id: 2}, Data: "ban:1"}) {
+		t.Errorf("expected non-admin callback query not to match")
+	}
+
+	adminReaction := filters.ReactionSender(isAdmin)
+	if !adminReaction(&gotgbot.MessageReactionUpdated{User: &gotgbot.User{Id: 1}}) {
+		t.Errorf("expected admin reaction to match")
+	}
+
+	privateChat := filters.Chat(func(c *gotgbot.Chat) bool { return c.Type == "private" })
+	if filters.CallbackQueryChat(privateChat)(&gotgbot.CallbackQuery{InlineMessageId: "abc"}) {
+		t.Errorf("expected inline callback query not to match a chat filter")
+	}
+}
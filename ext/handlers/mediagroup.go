@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters"
+)
+
+// DefaultMediaGroupQuietPeriod is the default time to wait for more messages of a media group.
+const DefaultMediaGroupQuietPeriod = time.Second
+
+// ErrMediaGroupNotInitialised is returned by MediaGroup handlers which weren't created with NewMediaGroup.
+var ErrMediaGroupNotInitialised = errors.New("media group handler must be created with NewMediaGroup")
+
+// MediaGroupResponse is the function called with all the messages of a media group, in order.
+// The context is the context of the first message received.
+type MediaGroupResponse func(b *gotgbot.Bot, ctx *ext.Context, msgs []*gotgbot.Message) error
+
+// MediaGroup handles media groups (albums) as a single unit. Telegram sends each message of a media group as a
+// separate update; this handler buffers the messages sharing a MediaGroupId until no new message has been received
+// for the QuietPeriod, and then calls the response once with all the messages, ordered by message ID.
+//
+// The update of the first message of the group is kept processing while the group is buffered, and calls the
+// response; the updates of the other messages return immediately. Messages which are not part of a media group are
+// ignored.
+// MediaGroup handlers must be created with NewMediaGroup.
+type MediaGroup struct {
+	AllowChannel bool
+	Filter       filters.Message
+	Response     MediaGroupResponse
+	// QuietPeriod is the time to wait after the latest message of a group, before calling the response.
+	QuietPeriod time.Duration
+
+	// groups keeps track of the media groups currently being buffered.
+	groups *mediaGroupBuffer
+}
+
+// mediaGroupBuffer holds the messages of the media groups currently being buffered, by chat and media group ID.
+type mediaGroupBuffer struct {
+	groups map[string]*bufferedMediaGroup
+	// lock allows us to ensure synchronous data access.
+	lock sync.Mutex
+}
+
+type bufferedMediaGroup struct {
+	msgs []*gotgbot.Message
+	// lastReceived is when the latest message of the group was received.
+	lastReceived time.Time
+}
+
+// NewMediaGroup creates a new MediaGroup handler. The filter, if any, is checked against each message of the group;
+// only matching messages are buffered.
+func NewMediaGroup(f filters.Message, r MediaGroupResponse) MediaGroup {
+	return MediaGroup{
+		Filter:      f,
+		Response:    r,
+		QuietPeriod: DefaultMediaGroupQuietPeriod,
+		groups: &mediaGroupBuffer{
+			groups: map[string]*bufferedMediaGroup{},
+		},
+	}
+}
+
+// SetAllowChannel Enables channel messages for this handler.
+func (m MediaGroup) SetAllowChannel(allow bool) MediaGroup {
+	m.AllowChannel = allow
+	return m
+}
+
+// SetQuietPeriod sets the time to wait after the latest message of a group, before calling the response.
+func (m MediaGroup) SetQuietPeriod(quietPeriod time.Duration) MediaGroup {
+	m.QuietPeriod = quietPeriod
+	return m
+}
+
+func (m MediaGroup) CheckUpdate(b *gotgbot.Bot, ctx *ext.Context) bool {
+	msg := m.getMessage(ctx)
+	if msg == nil || msg.MediaGroupId == "" {
+		return false
+	}
+	return m.Filter == nil || m.Filter(msg)
+}
+
+func (m MediaGroup) HandleUpdate(b *gotgbot.Bot, ctx *ext.Context) error {
+	if m.groups == nil {
+		// Struct literals have no buffer to share between updates.
+		return ErrMediaGroupNotInitialised
+	}
+
+	msg := m.getMessage(ctx)
+	key := strconv.FormatInt(msg.Chat.Id, 10) + "/" + msg.MediaGroupId
+
+	m.groups.lock.Lock()
+	if group, ok := m.groups.groups[key]; ok {
+		// The group is already being buffered by another update.
+		group.msgs = append(group.msgs, msg)
+		group.lastReceived = time.Now()
+		m.groups.lock.Unlock()
+		return nil
+	}
+
+	group := &bufferedMediaGroup{
+		msgs:         []*gotgbot.Message{msg},
+		lastReceived: time.Now(),
+	}
+	m.groups.groups[key] = group
+	m.groups.lock.Unlock()
+
+	msgs := m.waitForGroup(key, group)
+	return m.Response(b, ctx, msgs)
+}
+
+// waitForGroup waits until no new message has been added to the group for the quiet period, and then returns the
+// group's messages in order.
+func (m MediaGroup) waitForGroup(key string, group *bufferedMediaGroup) []*gotgbot.Message {
+	quietPeriod := m.QuietPeriod
+	if quietPeriod <= 0 {
+		quietPeriod = DefaultMediaGroupQuietPeriod
+	}
+
+	wait := quietPeriod
+	for {
+		time.Sleep(wait)
+
+		m.groups.lock.Lock()
+		wait = time.Until(group.lastReceived.Add(quietPeriod))
+		if wait > 0 {
+			// New messages were received; keep waiting.
+			m.groups.lock.Unlock()
+			continue
+		}
+
+		delete(m.groups.groups, key)
+		m.groups.lock.Unlock()
+		break
+	}
+
+	sort.SliceStable(group.msgs, func(i, j int) bool {
+		return group.msgs[i].MessageId < group.msgs[j].MessageId
+	})
+	return group.msgs
+}
+
+func (m MediaGroup) getMessage(ctx *ext.Context) *gotgbot.Message {
+	if ctx.Message != nil {
+		return ctx.Message
+	}
+	if m.AllowChannel && ctx.ChannelPost != nil {
+		return ctx.ChannelPost
+	}
+	return nil
+}
+
+func (m MediaGroup) Name() string {
+	return fmt.Sprintf("media_group_%p", m.Response)
+}
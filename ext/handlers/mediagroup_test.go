@@ -0,0 +1,89 @@
+package handlers_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+)
+
+func TestMediaGroup(t *testing.T) {
+	b := NewTestBot()
+
+	var mux sync.Mutex
+	var groups [][]int64
+	h := handlers.NewMediaGroup(nil, func(b *gotgbot.Bot, ctx *ext.Context, msgs []*gotgbot.Message) error {
+		var ids []int64
+		for _, msg := range msgs {
+			ids = append(ids, msg.MessageId)
+		}
+		mux.Lock()
+		groups = append(groups, ids)
+		mux.Unlock()
+		return nil
+	}).SetQuietPeriod(50 * time.Millisecond)
+
+	d := ext.NewDispatcher(nil)
+	d.AddHandler(h)
+
+	if h.CheckUpdate(b, NewMessage(1, 1, "not an album")) {
+		t.Errorf("messages outside of media groups should not match")
+	}
+
+	var wg sync.WaitGroup
+	for i, msgId := range []int64{3, 1, 2} {
+		if i > 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		wg.Add(1)
+		go func(msgId int64) {
+			defer wg.Done()
+			err := d.ProcessUpdate(b, &gotgbot.Update{
+				Message: &gotgbot.Message{
+					MessageId:    msgId,
+					MediaGroupId: "album",
+					Chat:         gotgbot.Chat{Id: 1, Type: "private"},
+					Photo:        []gotgbot.PhotoSize{{FileId: "photo"}},
+				},
+			}, nil)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(msgId)
+	}
+	wg.Wait()
+
+	if len(groups) != 1 {
+		t.Fatalf("expected the response to be called once, got %d calls: %v", len(groups), groups)
+	}
+	if ids := groups[0]; len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Errorf("expected ordered album messages, got %v", ids)
+	}
+}
+
+func TestMediaGroupLiteral(t *testing.T) {
+	b := NewTestBot()
+	h := handlers.MediaGroup{Response: func(b *gotgbot.Bot, ctx *ext.Context, msgs []*gotgbot.Message) error {
+		t.Error("response should not be called")
+		return nil
+	}}
+
+	ctx := ext.NewContext(&gotgbot.Update{
+		Message: &gotgbot.Message{
+			MessageId:    1,
+			MediaGroupId: "album",
+			Chat:         gotgbot.Chat{Id: 1, Type: "private"},
+		},
+	}, nil)
+	if !h.CheckUpdate(b, ctx) {
+		t.Fatal("expected media group message to match")
+	}
+	if err := h.HandleUpdate(b, ctx); !errors.Is(err, handlers.ErrMediaGroupNotInitialised) {
+		t.Errorf("expected uninitialised handler error, got %v", err)
+	}
+}
@@ -15,6 +15,14 @@ func (n Named) Name() string {
 	return n.CustomName
 }
 
+// BotCommands returns the bot commands described by the parent handler, if any.
+func (n Named) BotCommands() []ext.BotCommandInfo {
+	if bch, ok := n.Handler.(ext.BotCommandHandler); ok {
+		return bch.BotCommands()
+	}
+	return nil
+}
+
 func NewNamedhandler(name string, handler ext.Handler) Named {
 	return Named{
 		CustomName: name,
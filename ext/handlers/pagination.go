@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+// DefaultPaginationPerPage is the default number of items shown on each page of a Pagination.
+const DefaultPaginationPerPage = 10
+
+const (
+	// maxCallbackDataLength is the maximum length of callback data allowed by telegram, in bytes.
+	maxCallbackDataLength = 64
+	// maxPaginationPage is the largest page number NewPagination checks the callback data length for.
+	maxPaginationPage = 999999999
+)
+
+// PageSource loads the items shown on a page, numbered from 0, along with the total number of items available.
+type PageSource[T any] func(b *gotgbot.Bot, ctx *ext.Context, page int, perPage int) (items []T, total int, err error)
+
+// PageRenderer renders the message text for the items on a page.
+type PageRenderer[T any] func(b *gotgbot.Bot, ctx *ext.Context, items []T, page int, pages int) (string, error)
+
+// Pagination is a paginated list, shown in a single message with buttons to move between pages.
+// Use Send to send the first page; the Pagination must also be added to the dispatcher, to handle the page buttons.
+// When a page button is pressed, the callback query is answered, and the message is edited in place to show the new
+// page.
+//
+// The page buttons use "pg:<name>:<page>" as their callback data. Pages which no longer exist, such as when items
+// were removed since the message was sent, show the last page instead.
+type Pagination[T any] struct {
+	// PaginationName identifies the pagination in the callback data; it should be short, and unique to this
+	// pagination.
+	PaginationName string
+	// PerPage is the number of items shown on each page. If 0 or less, DefaultPaginationPerPage is used.
+	PerPage  int
+	Source   PageSource[T]
+	Renderer PageRenderer[T]
+
+	// The following are all optional fields:
+	// ItemButton returns a button to show for each item on the page, above the page buttons.
+	ItemButton func(item T) gotgbot.InlineKeyboardButton
+	// ParseMode is the parse mode of the rendered text.
+	ParseMode string
+}
+
+// NewPagination creates a new Pagination. If perPage is 0 or less, DefaultPaginationPerPage is used.
+// The name must not be empty, nor contain ":", and must be short enough for the page buttons' callback data to fit in
+// telegram's 64 byte limit.
+func NewPagination[T any](name string, perPage int, source PageSource[T], renderer PageRenderer[T]) (Pagination[T], error) {
+	if name == "" || strings.Contains(name, ":") {
+		return Pagination[T]{}, fmt.Errorf("invalid pagination name %q", name)
+	}
+	p := Pagination[T]{
+		PaginationName: name,
+		PerPage:        perPage,
+		Source:         source,
+		Renderer:       renderer,
+	}
+	if l := len(p.pageData(maxPaginationPage)); l > maxCallbackDataLength {
+		return Pagination[T]{}, fmt.Errorf("pagination name %q is too long: page callback data would be %d bytes, over the %d byte limit", name, l, maxCallbackDataLength)
+	}
+	if perPage <= 0 {
+		p.PerPage = DefaultPaginationPerPage
+	}
+	return p, nil
+}
+
+// SetItemButton sets the function used to create a button for each item on the page.
+func (p Pagination[T]) SetItemButton(itemButton func(item T) gotgbot.InlineKeyboardButton) Pagination[T] {
+	p.ItemButton = itemButton
+	return p
+}
+
+// SetParseMode sets the parse mode of the rendered text.
+func (p Pagination[T]) SetParseMode(parseMode string) Pagination[T] {
+	p.ParseMode = parseMode
+	return p
+}
+
+// Send sends the given page to the current chat.
+func (p Pagination[T]) Send(b *gotgbot.Bot, ctx *ext.Context, page int) (*gotgbot.Message, error) {
+	text, markup, err := p.renderPage(b, ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := b.SendMessage(ctx.EffectiveChat.Id, text, &gotgbot.SendMessageOpts{
+		ParseMode:   p.ParseMode,
+		ReplyMarkup: markup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send page: %w", err)
+	}
+	return msg, nil
+}
+
+func (p Pagination[T]) CheckUpdate(b *gotgbot.Bot, ctx *ext.Context) bool {
+	return ctx.CallbackQuery != nil && strings.HasPrefix(ctx.CallbackQuery.Data, p.dataPrefix())
+}
+
+func (p Pagination[T]) HandleUpdate(b *gotgbot.Bot, ctx *ext.Context) error {
+	cq := ctx.CallbackQuery
+	// Invalid pages default to the first page.
+	page, _ := strconv.Atoi(strings.TrimPrefix(cq.Data, p.dataPrefix()))
+
+	// The callback query is answered even if the page can't be shown, so that the client stops loading.
+	err := p.editPage(b, ctx, cq, page)
+
+	_, aErr := cq.Answer(b, nil)
+	if err != nil {
+		return err
+	}
+	if aErr != nil {
+		return fmt.Errorf("failed to answer page callback query: %w", aErr)
+	}
+	return nil
+}
+
+// editPage edits the callback query's message to show the given page.
+func (p Pagination[T]) editPage(b *gotgbot.Bot, ctx *ext.Context, cq *gotgbot.CallbackQuery, page int) error {
+	text, markup, err := p.renderPage(b, ctx, page)
+	if err != nil {
+		return err
+	}
+
+	editOpts := &gotgbot.EditMessageTextOpts{
+		InlineMessageId: cq.InlineMessageId,
+		ParseMode:       p.ParseMode,
+		ReplyMarkup:     markup,
+	}
+	if cq.Message != nil {
+		editOpts.ChatId = cq.Message.GetChat().Id
+		editOpts.MessageId = cq.Message.GetMessageId()
+	}
+
+	_, _, err = b.EditMessageText(text, editOpts)
+	if err != nil && !isMessageNotModified(err) {
+		return fmt.Errorf("failed to edit page: %w", err)
+	}
+	return nil
+}
+
+func (p Pagination[T]) Name() string {
+	return "pagination_" + p.PaginationName
+}
+
+func (p Pagination[T]) dataPrefix() string {
+	return "pg:" + p.PaginationName + ":"
+}
+
+func (p Pagination[T]) pageData(page int) string {
+	return p.dataPrefix() + strconv.Itoa(page)
+}
+
+// renderPage loads and renders a page, clamping it to the last page if it no longer exists.
+func (p Pagination[T]) renderPage(b *gotgbot.Bot, ctx *ext.Context, page int) (string, gotgbot.InlineKeyboardMarkup, error) {
+	if page < 0 {
+		page = 0
+	}
+
+	perPage := p.PerPage
+	if perPage <= 0 {
+		perPage = DefaultPaginationPerPage
+	}
+
+	items, total, err := p.Source(b, ctx, page, perPage)
+	if err != nil {
+		return "", gotgbot.InlineKeyboardMarkup{}, fmt.Errorf("failed to load page %d: %w", page, err)
+	}
+
+	pages := (total + perPage - 1) / perPage
+	if pages == 0 {
+		pages = 1
+	}
+	if l := len(p.pageData(pages - 1)); l > maxCallbackDataLength {
+		return "", gotgbot.InlineKeyboardMarkup{}, fmt.Errorf("page callback data is %d bytes, over the %d byte limit", l, maxCallbackDataLength)
+	}
+	if page >= pages {
+		// Stale page; show the last one instead.
+		page = pages - 1
+		items, _, err = p.Source(b, ctx, page, perPage)
+		if err != nil {
+			return "", gotgbot.InlineKeyboardMarkup{}, fmt.Errorf("failed to load page %d: %w", page, err)
+		}
+	}
+
+	text, err := p.Renderer(b, ctx, items, page, pages)
+	if err != nil {
+		return "", gotgbot.InlineKeyboardMarkup{}, fmt.Errorf("failed to render page %d: %w", page, err)
+	}
+
+	kb := gotgbot.NewInlineKeyboard().Columns(1)
+	if p.ItemButton != nil {
+		for _, item := range items {
+			kb.Button(p.ItemButton(item))
+		}
+	}
+	return text, kb.PageNavigation(page, pages, p.pageData).Build(), nil
+}
+
+// isMessageNotModified checks whether an edit failed because the new content is identical to the old content; eg,
+// when the same page button is pressed twice.
+func isMessageNotModified(err error) bool {
+	var tgErr *gotgbot.TelegramError
+	return errors.As(err, &tgErr) && strings.Contains(tgErr.Description, "message is not modified")
+}
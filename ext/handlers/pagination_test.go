@@ -0,0 +1,183 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+)
+
+func TestPagination(t *testing.T) {
+	var mux sync.Mutex
+	var calls []string
+	var lastText string
+	var lastMarkup string
+	var failEdits bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("failed to decode params: %v", err)
+			return
+		}
+
+		mux.Lock()
+		defer mux.Unlock()
+		method := path.Base(r.URL.Path)
+		calls = append(calls, method)
+		switch method {
+		case "editMessageText":
+			if failEdits {
+				fmt.Fprint(w, `{"ok": false, "error_code": 400, "description": "Bad Request: message to edit not found"}`)
+				return
+			}
+			if params["text"] == lastText {
+				fmt.Fprint(w, `{"ok": false, "error_code": 400, "description": "Bad Request: message is not modified"}`)
+				return
+			}
+			lastText = params["text"]
+			lastMarkup = params["reply_markup"]
+			fmt.Fprint(w, `{"ok": true, "result": true}`)
+		case "answerCallbackQuery":
+			fmt.Fprint(w, `{"ok": true, "result": true}`)
+		default:
+			t.Errorf("unexpected method %s", method)
+		}
+	}))
+	defer server.Close()
+
+	b := &gotgbot.Bot{
+		Token: "123:SOME_TOKEN",
+		BotClient: &gotgbot.BaseBotClient{
+			DefaultRequestOpts: &gotgbot.RequestOpts{APIURL: server.URL},
+		},
+	}
+
+	items := []string{"a", "b", "c", "d", "e"}
+	p, err := handlers.NewPagination("items", 2, listSource(items), renderList)
+	if err != nil {
+		t.Fatalf("failed to create pagination: %v", err)
+	}
+
+	for _, testParams := range []struct {
+		data         string
+		expectedText string
+	}{
+		{data: "pg:items:1", expectedText: "Page 2/3: c, d"},
+		// Pressing the same button twice doesn't modify the message.
+		{data: "pg:items:1", expectedText: "Page 2/3: c, d"},
+		// Stale pages show the last page.
+		{data: "pg:items:10", expectedText: "Page 3/3: e"},
+	} {
+		ctx := newMessageCallbackQuery(testParams.data)
+		if !p.CheckUpdate(b, ctx) {
+			t.Fatalf("pagination should match %q", testParams.data)
+		}
+		if err := p.HandleUpdate(b, ctx); err != nil {
+			t.Fatalf("unexpected error for %q: %v", testParams.data, err)
+		}
+		if lastText != testParams.expectedText {
+			t.Errorf("expected text %q, got %q", testParams.expectedText, lastText)
+		}
+	}
+
+	if !strings.Contains(lastMarkup, `"callback_data":"pg:items:1"`) || strings.Contains(lastMarkup, `"pg:items:3"`) {
+		t.Errorf("unexpected navigation buttons on last page: %s", lastMarkup)
+	}
+
+	// Edit errors are returned, but the callback query is still answered.
+	mux.Lock()
+	failEdits = true
+	mux.Unlock()
+	if err := p.HandleUpdate(b, newMessageCallbackQuery("pg:items:0")); err == nil {
+		t.Errorf("expected edit error to be returned")
+	}
+	if answers := strings.Count(strings.Join(calls, " "), "answerCallbackQuery"); answers != 4 {
+		t.Errorf("expected every callback query to be answered, got %d answers", answers)
+	}
+
+	if p.CheckUpdate(b, newMessageCallbackQuery("pg:other:1")) {
+		t.Errorf("pagination should not match other paginations")
+	}
+}
+
+func TestPaginationLiteral(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ok": true, "result": true}`)
+	}))
+	defer server.Close()
+
+	b := &gotgbot.Bot{
+		Token: "123:SOME_TOKEN",
+		BotClient: &gotgbot.BaseBotClient{
+			DefaultRequestOpts: &gotgbot.RequestOpts{APIURL: server.URL},
+		},
+	}
+
+	var perPages []int
+	p := handlers.Pagination[string]{
+		PaginationName: "items",
+		Source: func(b *gotgbot.Bot, ctx *ext.Context, page int, perPage int) ([]string, int, error) {
+			perPages = append(perPages, perPage)
+			return listSource([]string{"a", "b"})(b, ctx, page, perPage)
+		},
+		Renderer: renderList,
+	}
+	if err := p.HandleUpdate(b, newMessageCallbackQuery("pg:items:0")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(perPages) != 1 || perPages[0] != handlers.DefaultPaginationPerPage {
+		t.Errorf("expected the default page size to be used, got %v", perPages)
+	}
+}
+
+func TestNewPaginationInvalidName(t *testing.T) {
+	for name, paginationName := range map[string]string{
+		"empty":     "",
+		"separator": "items:2",
+		"too long":  strings.Repeat("a", 60),
+	} {
+		if _, err := handlers.NewPagination(paginationName, 0, listSource(nil), renderList); err == nil {
+			t.Errorf("%s: expected invalid pagination name to be rejected", name)
+		}
+	}
+}
+
+func listSource(items []string) handlers.PageSource[string] {
+	return func(b *gotgbot.Bot, ctx *ext.Context, page int, perPage int) ([]string, int, error) {
+		start := page * perPage
+		end := start + perPage
+		if end > len(items) {
+			end = len(items)
+		}
+		if start > end {
+			return nil, len(items), nil
+		}
+		return items[start:end], len(items), nil
+	}
+}
+
+func renderList(b *gotgbot.Bot, ctx *ext.Context, items []string, page int, pages int) (string, error) {
+	return fmt.Sprintf("Page %d/%d: %s", page+1, pages, strings.Join(items, ", ")), nil
+}
+
+func newMessageCallbackQuery(data string) *ext.Context {
+	return ext.NewContext(&gotgbot.Update{
+		CallbackQuery: &gotgbot.CallbackQuery{
+			Id:   "1",
+			From: gotgbot.User{Id: 1, FirstName: "bob"},
+			Message: gotgbot.Message{
+				MessageId: 1,
+				Chat:      gotgbot.Chat{Id: 1, Type: "private"},
+			},
+			Data: data,
+		},
+	}, nil)
+}
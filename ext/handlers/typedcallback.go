@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/callbackdata"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters"
+)
+
+// TypedResponse is the function called with the decoded callback data of a TypedCallback.
+type TypedResponse[T any] func(b *gotgbot.Bot, ctx *ext.Context, data T) error
+
+// TypedCallback handles callback queries with data encoded by a callbackdata.Codec. The data is decoded before
+// calling the response.
+type TypedCallback[T any] struct {
+	AllowChannel bool
+	Codec        *callbackdata.Codec[T]
+	// Filter optionally restricts the callback queries handled, on top of the codec's prefix.
+	Filter   filters.CallbackQuery
+	Response TypedResponse[T]
+
+	// OnDecodeError is called when the callback data can't be decoded; eg, because the button was sent with an older
+	// codec version, or the data has expired from the store.
+	// If nil, the callback query is answered with a short alert to let the user know the button is outdated.
+	OnDecodeError func(b *gotgbot.Bot, ctx *ext.Context, err error) error
+}
+
+// NewTypedCallback creates a handler for the callback queries encoded by the given codec.
+func NewTypedCallback[T any](codec *callbackdata.Codec[T], r TypedResponse[T]) TypedCallback[T] {
+	return TypedCallback[T]{
+		Codec:    codec,
+		Response: r,
+	}
+}
+
+// SetAllowChannel Enables channel messages for this handler.
+func (cb TypedCallback[T]) SetAllowChannel(allow bool) TypedCallback[T] {
+	cb.AllowChannel = allow
+	return cb
+}
+
+// SetFilter sets an additional filter for the callback queries handled.
+func (cb TypedCallback[T]) SetFilter(filter filters.CallbackQuery) TypedCallback[T] {
+	cb.Filter = filter
+	return cb
+}
+
+// SetOnDecodeError sets the function called when the callback data can't be decoded.
+func (cb TypedCallback[T]) SetOnDecodeError(onDecodeError func(b *gotgbot.Bot, ctx *ext.Context, err error) error) TypedCallback[T] {
+	cb.OnDecodeError = onDecodeError
+	return cb
+}
+
+func (cb TypedCallback[T]) CheckUpdate(b *gotgbot.Bot, ctx *ext.Context) bool {
+	if ctx.CallbackQuery == nil {
+		return false
+	}
+
+	if !cb.AllowChannel && ctx.CallbackQuery.Message != nil && ctx.CallbackQuery.Message.GetChat().Type == "channel" {
+		return false
+	}
+
+	if !cb.Codec.Match(ctx.CallbackQuery.Data) {
+		return false
+	}
+
+	return cb.Filter == nil || cb.Filter(ctx.CallbackQuery)
+}
+
+func (cb TypedCallback[T]) HandleUpdate(b *gotgbot.Bot, ctx *ext.Context) error {
+	data, err := cb.Codec.Decode(ctx.CallbackQuery.Data)
+	if err != nil {
+		return cb.handleDecodeError(b, ctx, err)
+	}
+	return cb.Response(b, ctx, data)
+}
+
+func (cb TypedCallback[T]) handleDecodeError(b *gotgbot.Bot, ctx *ext.Context, decodeErr error) error {
+	if cb.OnDecodeError != nil {
+		return cb.OnDecodeError(b, ctx, decodeErr)
+	}
+
+	text := "This button is no longer valid."
+	if errors.Is(decodeErr, callbackdata.ErrExpired) {
+		text = "This button has expired."
+	}
+	_, err := ctx.CallbackQuery.Answer(b, &gotgbot.AnswerCallbackQueryOpts{
+		Text:      text,
+		ShowAlert: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to answer callback query with decode error: %w", err)
+	}
+	return nil
+}
+
+func (cb TypedCallback[T]) Name() string {
+	return "typed_callback_" + cb.Codec.Prefix()
+}
@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/callbackdata"
+)
+
+type vote struct {
+	PollId int64
+	Option string
+}
+
+func TestTypedCallback(t *testing.T) {
+	b := NewTestBot()
+
+	codec, err := callbackdata.NewCodec[vote]("vote", &callbackdata.CodecOpts{Version: 2})
+	if err != nil {
+		t.Fatalf("failed to create codec: %v", err)
+	}
+
+	var got vote
+	var gotErr error
+	h := handlers.NewTypedCallback(codec, func(b *gotgbot.Bot, ctx *ext.Context, data vote) error {
+		got = data
+		return nil
+	}).SetOnDecodeError(func(b *gotgbot.Bot, ctx *ext.Context, err error) error {
+		gotErr = err
+		return nil
+	})
+
+	data, err := codec.Encode(vote{PollId: 10, Option: "yes"})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	ctx := newCallbackQuery(data)
+	if !h.CheckUpdate(b, ctx) {
+		t.Fatal("handler should match encoded data")
+	}
+	if err := h.HandleUpdate(b, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.PollId != 10 || got.Option != "yes" || gotErr != nil {
+		t.Errorf("unexpected decoded data %+v, error %v", got, gotErr)
+	}
+
+	if h.CheckUpdate(b, newCallbackQuery("other:1:a")) {
+		t.Error("handler should not match data with another prefix")
+	}
+
+	ctx = newCallbackQuery("vote:1:a:yes")
+	if !h.CheckUpdate(b, ctx) {
+		t.Fatal("handler should match outdated data")
+	}
+	if err := h.HandleUpdate(b, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.Is(gotErr, callbackdata.ErrVersionMismatch) {
+		t.Errorf("expected version mismatch decode error, got %v", gotErr)
+	}
+}
+
+func newCallbackQuery(data string) *ext.Context {
+	return ext.NewContext(&gotgbot.Update{
+		CallbackQuery: &gotgbot.CallbackQuery{
+			Id:   "1",
+			From: gotgbot.User{Id: 1, FirstName: "bob"},
+			Data: data,
+		},
+	}, nil)
+}
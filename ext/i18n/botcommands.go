@@ -0,0 +1,71 @@
+package i18n
+
+import (
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+// BotCommandKeyPrefix is the prefix of the catalog keys holding bot command descriptions; eg, the description of the
+// /start command is the "commands.start" message.
+const BotCommandKeyPrefix = "commands."
+
+var _ ext.BotCommandLocalizer = &Bundle{}
+
+// LocalizeBotCommands implements ext.BotCommandLocalizer, so that the bundle can be used to register translated
+// commands with Dispatcher.SyncBotCommands:
+//
+//	err := dispatcher.SyncBotCommands(b, &ext.SyncBotCommandsOpts{Localizer: bundle})
+//
+// The descriptions of the commands are replaced by the default locale's BotCommandKeyPrefix messages, if any. The
+// commands are also added for the language code of each locale which has a description for at least one of them;
+// commands without a description in that locale keep their default description. Since telegram only accepts
+// two-letter language codes, regional locales such as "pt-br" are not registered.
+func (b *Bundle) LocalizeBotCommands(cmds []ext.BotCommandInfo) []ext.BotCommandInfo {
+	defaultLocale := normalizeLocale(b.DefaultLocale)
+
+	localized := make([]ext.BotCommandInfo, 0, len(cmds))
+	for _, cmd := range cmds {
+		if cmd.LanguageCode == "" {
+			if desc, ok := b.botCommandDescription(defaultLocale, cmd.Command); ok {
+				cmd.Description = desc
+			}
+		}
+		localized = append(localized, cmd)
+	}
+
+	for _, locale := range b.Locales() {
+		if locale == defaultLocale || len(locale) != 2 {
+			continue
+		}
+
+		var translated []ext.BotCommandInfo
+		var found bool
+		for _, cmd := range localized[:len(cmds)] {
+			if cmd.LanguageCode != "" {
+				// Commands which are already specific to a language are kept as they are.
+				continue
+			}
+			if desc, ok := b.botCommandDescription(locale, cmd.Command); ok {
+				cmd.Description = desc
+				found = true
+			}
+			cmd.LanguageCode = locale
+			translated = append(translated, cmd)
+		}
+		if found {
+			localized = append(localized, translated...)
+		}
+	}
+	return localized
+}
+
+// botCommandDescription returns the description of a command in the given locale, without any fallback.
+func (b *Bundle) botCommandDescription(locale string, command string) (string, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	msg, ok := b.messages[locale][BotCommandKeyPrefix+command]
+	if !ok || msg.plural != nil {
+		return "", false
+	}
+	return msg.text, true
+}
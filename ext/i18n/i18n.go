@@ -0,0 +1,407 @@
+// Package i18n provides message catalogs and per-update localisation for bots.
+//
+// Messages are loaded into a Bundle from JSON or TOML catalogs, one per locale. Catalogs map keys to messages; nested
+// objects or tables are flattened into dotted keys, and objects whose keys are all plural categories ("zero", "one",
+// "two", "few", "many" and "other") are plural messages, picked with the locale's plural rules. For example:
+//
+//	{
+//		"welcome": "Welcome, {name}!",
+//		"cart": {
+//			"items": {"one": "You have {count} item.", "other": "You have {count} items."}
+//		}
+//	}
+//
+// The i18n Processor picks a locale for each update, such as from the user's LanguageCode, and makes it available to
+// handlers through T and N:
+//
+//	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{
+//		Processor: i18n.NewProcessor(bundle, nil),
+//	})
+//	...
+//	ctx.EffectiveMessage.Reply(b, i18n.T(ctx, "welcome", i18n.Vars{"name": ctx.EffectiveUser.FirstName}), nil)
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Vars are the values of the placeholders in a message. A "{name}" placeholder is replaced with the value of the
+// "name" var; placeholders without a value are left as is.
+type Vars map[string]interface{}
+
+// message is a single message of a catalog; either a plain text, or a set of plural forms.
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+// Bundle holds the message catalogs of all locales. Locales are case-insensitive, and use "-" to separate the region;
+// eg, "en" or "pt-br". Underscores are also accepted, and converted.
+type Bundle struct {
+	// DefaultLocale is used when no other locale matches, and for messages missing from other locales.
+	DefaultLocale string
+
+	// lock allows us to ensure synchronous data access.
+	lock sync.RWMutex
+	// messages maps locales to their messages, by key.
+	messages map[string]map[string]message
+	// pluralRules are the plural rules which replace the default plural rules, by locale.
+	pluralRules map[string]PluralRule
+}
+
+// NewBundle creates a new, empty, Bundle.
+func NewBundle(defaultLocale string) *Bundle {
+	return &Bundle{
+		DefaultLocale: normalizeLocale(defaultLocale),
+		messages:      map[string]map[string]message{},
+		pluralRules:   map[string]PluralRule{},
+	}
+}
+
+// AddMessages adds plain text messages to the catalog of the given locale, replacing any existing messages with the
+// same keys.
+func (b *Bundle) AddMessages(locale string, messages map[string]string) {
+	catalog := map[string]message{}
+	for k, v := range messages {
+		catalog[k] = message{text: v}
+	}
+	b.addCatalog(locale, catalog)
+}
+
+// LoadJSON loads a JSON catalog for the given locale.
+func (b *Bundle) LoadJSON(locale string, data []byte) error {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to unmarshal %s catalog: %w", locale, err)
+	}
+	return b.loadValues(locale, values)
+}
+
+// LoadTOML loads a TOML catalog for the given locale. Only strings and tables are supported.
+func (b *Bundle) LoadTOML(locale string, data []byte) error {
+	values, err := parseTOML(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s catalog: %w", locale, err)
+	}
+	return b.loadValues(locale, values)
+}
+
+// LoadFile loads a catalog from a ".json" or ".toml" file. The locale is the name of the file, without the
+// extension; eg, "locales/pt-br.json".
+func (b *Bundle) LoadFile(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog: %w", err)
+	}
+	return b.load(filepath.Base(filePath), data)
+}
+
+// LoadFS loads all the ".json" and ".toml" catalogs in the given directory of the file system, such as an embed.FS.
+// The locale of each catalog is the name of the file, without the extension.
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog directory: %w", err)
+	}
+
+	for _, e := range entries {
+		ext := path.Ext(e.Name())
+		if e.IsDir() || (ext != ".json" && ext != ".toml") {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read catalog %s: %w", e.Name(), err)
+		}
+		if err = b.load(e.Name(), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load loads a catalog, based on its file name.
+func (b *Bundle) load(name string, data []byte) error {
+	ext := path.Ext(name)
+	locale := strings.TrimSuffix(name, ext)
+
+	switch ext {
+	case ".json":
+		return b.LoadJSON(locale, data)
+	case ".toml":
+		return b.LoadTOML(locale, data)
+	}
+	return fmt.Errorf("unsupported catalog format %q", ext)
+}
+
+// loadValues flattens the decoded catalog values, and adds them to the locale's catalog.
+func (b *Bundle) loadValues(locale string, values map[string]interface{}) error {
+	catalog := map[string]message{}
+	if err := flattenMessages(catalog, "", values); err != nil {
+		return fmt.Errorf("invalid %s catalog: %w", locale, err)
+	}
+	b.addCatalog(locale, catalog)
+	return nil
+}
+
+func (b *Bundle) addCatalog(locale string, catalog map[string]message) {
+	locale = normalizeLocale(locale)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.messages == nil {
+		b.messages = map[string]map[string]message{}
+	}
+	if b.messages[locale] == nil {
+		b.messages[locale] = map[string]message{}
+	}
+	for k, v := range catalog {
+		b.messages[locale][k] = v
+	}
+}
+
+// flattenMessages adds the messages of the values to the catalog, using dotted keys for nested values.
+func flattenMessages(catalog map[string]message, prefix string, values map[string]interface{}) error {
+	for k, v := range values {
+		key := prefix + k
+		switch v := v.(type) {
+		case string:
+			catalog[key] = message{text: v}
+		case map[string]interface{}:
+			if forms, ok := pluralForms(v); ok {
+				catalog[key] = message{plural: forms}
+				continue
+			}
+			if err := flattenMessages(catalog, key+".", v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("message %q must be a string or an object, got %T", key, v)
+		}
+	}
+	return nil
+}
+
+// pluralForms returns the plural forms of a plural message; that is, an object with a string for each of its keys,
+// all of which are plural categories, and which includes the "other" category.
+func pluralForms(values map[string]interface{}) (map[string]string, bool) {
+	if _, ok := values[PluralOther]; !ok {
+		return nil, false
+	}
+
+	forms := map[string]string{}
+	for k, v := range values {
+		s, ok := v.(string)
+		if !ok || !isPluralCategory(k) {
+			return nil, false
+		}
+		forms[k] = s
+	}
+	return forms, true
+}
+
+// SetPluralRule sets the plural rule of a locale, replacing the default rule returned by DefaultPluralRule.
+func (b *Bundle) SetPluralRule(locale string, rule PluralRule) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.pluralRules == nil {
+		b.pluralRules = map[string]PluralRule{}
+	}
+	b.pluralRules[normalizeLocale(locale)] = rule
+}
+
+// Locales returns all the locales which have a catalog, sorted.
+func (b *Bundle) Locales() []string {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	locales := make([]string, 0, len(b.messages))
+	for l := range b.messages {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Match returns the locale with a catalog which best matches the given locale: the locale itself, its base language
+// (eg, "pt" for "pt-br"), or another locale of the same base language (eg, "pt-br" for "pt").
+func (b *Bundle) Match(locale string) (string, bool) {
+	locale = normalizeLocale(locale)
+	if locale == "" {
+		return "", false
+	}
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if _, ok := b.messages[locale]; ok {
+		return locale, true
+	}
+	base := baseLanguage(locale)
+	if _, ok := b.messages[base]; ok {
+		return base, true
+	}
+
+	var match string
+	for l := range b.messages {
+		if baseLanguage(l) == base && (match == "" || l < match) {
+			match = l
+		}
+	}
+	return match, match != ""
+}
+
+// Localizer returns a Localizer for the locale which best matches the given locale, or the default locale if none
+// match.
+func (b *Bundle) Localizer(locale string) *Localizer {
+	match, ok := b.Match(locale)
+	if !ok {
+		match = normalizeLocale(b.DefaultLocale)
+	}
+	return &Localizer{bundle: b, locale: match}
+}
+
+// lookup returns a message of the given locale, falling back to the base language and the default locale.
+func (b *Bundle) lookup(locale string, key string) (message, string, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	for _, l := range []string{locale, baseLanguage(locale), normalizeLocale(b.DefaultLocale)} {
+		if msg, ok := b.messages[l][key]; ok {
+			return msg, l, true
+		}
+	}
+	return message{}, "", false
+}
+
+func (b *Bundle) pluralRule(locale string) PluralRule {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if rule, ok := b.pluralRules[locale]; ok {
+		return rule
+	}
+	if rule, ok := b.pluralRules[baseLanguage(locale)]; ok {
+		return rule
+	}
+	return DefaultPluralRule(locale)
+}
+
+// Localizer translates messages into a single locale. Messages missing from the locale are taken from the default
+// locale; messages missing from both are returned as their key.
+// A nil Localizer returns all messages as their key.
+type Localizer struct {
+	bundle *Bundle
+	locale string
+}
+
+// Locale returns the locale of the Localizer.
+func (l *Localizer) Locale() string {
+	if l == nil {
+		return ""
+	}
+	return l.locale
+}
+
+// T returns the message with the given key, with its placeholders replaced. Plural messages use their "other" form.
+func (l *Localizer) T(key string, vars Vars) string {
+	if l == nil {
+		return key
+	}
+
+	msg, _, ok := l.bundle.lookup(l.locale, key)
+	if !ok {
+		return key
+	}
+	if msg.plural != nil {
+		return format(msg.plural[PluralOther], vars)
+	}
+	return format(msg.text, vars)
+}
+
+// N returns the plural form of the message with the given key which matches the count, with its placeholders
+// replaced. The "{count}" placeholder is replaced with the count, unless set in the vars.
+func (l *Localizer) N(key string, count int, vars Vars) string {
+	if l == nil {
+		return key
+	}
+
+	if _, ok := vars["count"]; !ok {
+		withCount := Vars{"count": count}
+		for k, v := range vars {
+			withCount[k] = v
+		}
+		vars = withCount
+	}
+
+	msg, locale, ok := l.bundle.lookup(l.locale, key)
+	if !ok {
+		return key
+	}
+	if msg.plural == nil {
+		return format(msg.text, vars)
+	}
+
+	n := count
+	if n < 0 {
+		n = -n
+	}
+	// Use the rule of the locale the message was found in, which may be the default locale.
+	form, ok := msg.plural[l.bundle.pluralRule(locale)(n)]
+	if !ok {
+		form = msg.plural[PluralOther]
+	}
+	return format(form, vars)
+}
+
+// format replaces the "{name}" placeholders of the text with their vars.
+func format(text string, vars Vars) string {
+	if len(vars) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		sb.WriteString(text[:start])
+		if v, ok := vars[text[start+1:end]]; ok {
+			sb.WriteString(fmt.Sprint(v))
+		} else {
+			sb.WriteString(text[start : end+1])
+		}
+		text = text[end+1:]
+	}
+	sb.WriteString(text)
+	return sb.String()
+}
+
+// normalizeLocale lowercases the locale, and uses "-" as the region separator.
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(locale)), "_", "-")
+}
+
+// baseLanguage returns the language of a normalized locale, without its region; eg, "pt" for "pt-br".
+func baseLanguage(locale string) string {
+	lang, _, _ := strings.Cut(locale, "-")
+	return lang
+}
@@ -0,0 +1,119 @@
+package i18n_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/i18n"
+)
+
+const enCatalog = `{
+	"welcome": "Welcome, {name}!",
+	"cart": {
+		"items": {"one": "You have {count} item.", "other": "You have {count} items."},
+		"empty": "Your cart is empty."
+	},
+	"other": "Not a plural message"
+}`
+
+const ruCatalog = `
+welcome = "Добро пожаловать, {name}!"
+
+[cart]
+items = { one = "У вас {count} товар.", few = "У вас {count} товара.", many = "У вас {count} товаров.", other = "У вас {count} товара." }
+`
+
+func newBundle(t *testing.T) *i18n.Bundle {
+	bundle := i18n.NewBundle("en")
+	err := bundle.LoadFS(fstest.MapFS{
+		"locales/en.json":   {Data: []byte(enCatalog)},
+		"locales/ru.toml":   {Data: []byte(ruCatalog)},
+		"locales/README.md": {Data: []byte("not a catalog")},
+	}, "locales")
+	if err != nil {
+		t.Fatalf("failed to load catalogs: %v", err)
+	}
+	bundle.AddMessages("pt_BR", map[string]string{"welcome": "Bem-vindo, {name}!"})
+	return bundle
+}
+
+func TestLocalizer(t *testing.T) {
+	bundle := newBundle(t)
+
+	if locales := bundle.Locales(); len(locales) != 3 || locales[0] != "en" || locales[1] != "pt-br" || locales[2] != "ru" {
+		t.Errorf("unexpected locales: %v", locales)
+	}
+
+	for _, tc := range []struct {
+		locale   string
+		key      string
+		count    int
+		vars     i18n.Vars
+		expected string
+	}{
+		{locale: "en", key: "welcome", vars: i18n.Vars{"name": "Alice"}, expected: "Welcome, Alice!"},
+		{locale: "en", key: "welcome", expected: "Welcome, {name}!"},
+		{locale: "en", key: "cart.empty", expected: "Your cart is empty."},
+		{locale: "en", key: "other", expected: "Not a plural message"},
+		{locale: "en", key: "missing.key", expected: "missing.key"},
+		{locale: "en", key: "cart.items", count: 1, expected: "You have 1 item."},
+		{locale: "en", key: "cart.items", count: 0, expected: "You have 0 items."},
+		{locale: "ru", key: "welcome", vars: i18n.Vars{"name": "Иван"}, expected: "Добро пожаловать, Иван!"},
+		{locale: "ru-RU", key: "cart.items", count: 21, expected: "У вас 21 товар."},
+		{locale: "ru", key: "cart.items", count: 3, expected: "У вас 3 товара."},
+		{locale: "ru", key: "cart.items", count: 11, expected: "У вас 11 товаров."},
+		// Missing messages fall back to the default locale, with its plural rules.
+		{locale: "ru", key: "cart.empty", expected: "Your cart is empty."},
+		{locale: "pt", key: "cart.items", count: 1, expected: "You have 1 item."},
+		{locale: "pt", key: "welcome", vars: i18n.Vars{"name": "Ana"}, expected: "Bem-vindo, Ana!"},
+		{locale: "de", key: "welcome", vars: i18n.Vars{"name": "Max"}, expected: "Welcome, Max!"},
+	} {
+		l := bundle.Localizer(tc.locale)
+		var got string
+		if tc.count != 0 || tc.key == "cart.items" {
+			got = l.N(tc.key, tc.count, tc.vars)
+		} else {
+			got = l.T(tc.key, tc.vars)
+		}
+		if got != tc.expected {
+			t.Errorf("%s %s(%d): expected %q, got %q", tc.locale, tc.key, tc.count, tc.expected, got)
+		}
+	}
+
+	var nilLocalizer *i18n.Localizer
+	if got := nilLocalizer.T("welcome", nil); got != "welcome" {
+		t.Errorf("expected nil localizer to return the key, got %q", got)
+	}
+}
+
+func TestBundleMatch(t *testing.T) {
+	bundle := newBundle(t)
+
+	for locale, expected := range map[string]string{
+		"en":    "en",
+		"EN-us": "en",
+		"pt-br": "pt-br",
+		"pt_PT": "pt-br",
+		"pt":    "pt-br",
+		"de":    "",
+		"":      "",
+	} {
+		got, ok := bundle.Match(locale)
+		if got != expected || ok != (expected != "") {
+			t.Errorf("%q: expected match %q, got %q (%v)", locale, expected, got, ok)
+		}
+	}
+}
+
+func TestBundleInvalidCatalogs(t *testing.T) {
+	bundle := i18n.NewBundle("en")
+	if err := bundle.LoadJSON("en", []byte(`{"count": 1}`)); err == nil {
+		t.Error("expected error for non-string message")
+	}
+	if err := bundle.LoadTOML("en", []byte(`count = 1`)); err == nil {
+		t.Error("expected error for non-string TOML value")
+	}
+	if err := bundle.LoadJSON("en", []byte(`{`)); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
@@ -0,0 +1,221 @@
+package i18n
+
+// Plural categories, as defined by the Unicode CLDR. Plural messages define a form for each category used by their
+// language; the "other" form is always required.
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// PluralRule returns the plural category to use for a count.
+type PluralRule func(n int) string
+
+// isPluralCategory checks whether the key is one of the plural categories.
+func isPluralCategory(key string) bool {
+	switch key {
+	case PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther:
+		return true
+	}
+	return false
+}
+
+// DefaultPluralRule returns the CLDR plural rule for integer counts in the language of the given locale. Languages
+// without a known rule use the English rule, with "one" for 1, and "other" for everything else.
+func DefaultPluralRule(locale string) PluralRule {
+	switch baseLanguage(normalizeLocale(locale)) {
+	case "id", "ja", "km", "ko", "lo", "ms", "my", "th", "vi", "yue", "zh":
+		return pluralOtherOnly
+	case "am", "bn", "fa", "fr", "gu", "hi", "hy", "kn", "pa", "pt", "zu":
+		return pluralZeroOneOne
+	case "be", "ru", "uk":
+		return pluralEastSlavic
+	case "cs", "sk":
+		return pluralWestSlavic
+	case "pl":
+		return pluralPolish
+	case "bs", "hr", "sh", "sr":
+		return pluralSerboCroat
+	case "ar":
+		return pluralArabic
+	case "he", "iw":
+		return pluralHebrew
+	case "lt":
+		return pluralLithuanian
+	case "lv":
+		return pluralLatvian
+	case "mo", "ro":
+		return pluralRomanian
+	case "sl":
+		return pluralSlovenian
+	case "ga":
+		return pluralIrish
+	case "cy":
+		return pluralWelsh
+	}
+	return pluralOneOther
+}
+
+// The plural rules below only handle integer counts; fractional counts would need the full CLDR operands.
+var (
+	pluralOtherOnly PluralRule = func(n int) string {
+		return PluralOther
+	}
+
+	pluralOneOther PluralRule = func(n int) string {
+		if n == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	}
+
+	pluralZeroOneOne PluralRule = func(n int) string {
+		if n == 0 || n == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	}
+
+	pluralEastSlavic PluralRule = func(n int) string {
+		switch mod10, mod100 := n%10, n%100; {
+		case mod10 == 1 && mod100 != 11:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		}
+		return PluralMany
+	}
+
+	pluralWestSlavic PluralRule = func(n int) string {
+		switch {
+		case n == 1:
+			return PluralOne
+		case n >= 2 && n <= 4:
+			return PluralFew
+		}
+		return PluralOther
+	}
+
+	pluralPolish PluralRule = func(n int) string {
+		switch mod10, mod100 := n%10, n%100; {
+		case n == 1:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		}
+		return PluralMany
+	}
+
+	pluralSerboCroat PluralRule = func(n int) string {
+		switch mod10, mod100 := n%10, n%100; {
+		case mod10 == 1 && mod100 != 11:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		}
+		return PluralOther
+	}
+
+	pluralArabic PluralRule = func(n int) string {
+		switch mod100 := n % 100; {
+		case n == 0:
+			return PluralZero
+		case n == 1:
+			return PluralOne
+		case n == 2:
+			return PluralTwo
+		case mod100 >= 3 && mod100 <= 10:
+			return PluralFew
+		case mod100 >= 11:
+			return PluralMany
+		}
+		return PluralOther
+	}
+
+	pluralHebrew PluralRule = func(n int) string {
+		switch n {
+		case 1:
+			return PluralOne
+		case 2:
+			return PluralTwo
+		}
+		return PluralOther
+	}
+
+	pluralLithuanian PluralRule = func(n int) string {
+		switch mod10, mod100 := n%10, n%100; {
+		case mod100 >= 11 && mod100 <= 19:
+			return PluralOther
+		case mod10 == 1:
+			return PluralOne
+		case mod10 >= 2:
+			return PluralFew
+		}
+		return PluralOther
+	}
+
+	pluralLatvian PluralRule = func(n int) string {
+		switch mod10, mod100 := n%10, n%100; {
+		case mod10 == 0 || (mod100 >= 11 && mod100 <= 19):
+			return PluralZero
+		case mod10 == 1:
+			return PluralOne
+		}
+		return PluralOther
+	}
+
+	pluralRomanian PluralRule = func(n int) string {
+		switch mod100 := n % 100; {
+		case n == 1:
+			return PluralOne
+		case n == 0 || (mod100 >= 1 && mod100 <= 19):
+			return PluralFew
+		}
+		return PluralOther
+	}
+
+	pluralSlovenian PluralRule = func(n int) string {
+		switch n % 100 {
+		case 1:
+			return PluralOne
+		case 2:
+			return PluralTwo
+		case 3, 4:
+			return PluralFew
+		}
+		return PluralOther
+	}
+
+	pluralIrish PluralRule = func(n int) string {
+		switch {
+		case n == 1:
+			return PluralOne
+		case n == 2:
+			return PluralTwo
+		case n >= 3 && n <= 6:
+			return PluralFew
+		case n >= 7 && n <= 10:
+			return PluralMany
+		}
+		return PluralOther
+	}
+
+	pluralWelsh PluralRule = func(n int) string {
+		switch n {
+		case 0:
+			return PluralZero
+		case 1:
+			return PluralOne
+		case 2:
+			return PluralTwo
+		case 3:
+			return PluralFew
+		case 6:
+			return PluralMany
+		}
+		return PluralOther
+	}
+)
@@ -0,0 +1,52 @@
+package i18n_test
+
+import (
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/i18n"
+)
+
+func TestDefaultPluralRule(t *testing.T) {
+	for locale, expected := range map[string]map[int]string{
+		"en":    {0: "other", 1: "one", 2: "other", 21: "other"},
+		"fr":    {0: "one", 1: "one", 2: "other"},
+		"ja":    {0: "other", 1: "other", 2: "other"},
+		"ru-RU": {1: "one", 2: "few", 5: "many", 11: "many", 12: "many", 21: "one", 22: "few", 111: "many"},
+		"pl":    {1: "one", 2: "few", 5: "many", 21: "many", 22: "few"},
+		"cs":    {1: "one", 3: "few", 5: "other"},
+		"ar":    {0: "zero", 1: "one", 2: "two", 3: "few", 11: "many", 100: "other", 103: "few"},
+		"lt":    {1: "one", 2: "few", 10: "other", 11: "other", 21: "one"},
+		"lv":    {0: "zero", 1: "one", 2: "other", 11: "zero", 21: "one"},
+		"ro":    {0: "few", 1: "one", 19: "few", 20: "other", 101: "few"},
+		"cy":    {0: "zero", 1: "one", 2: "two", 3: "few", 6: "many", 7: "other"},
+		"xx":    {1: "one", 2: "other"},
+	} {
+		rule := i18n.DefaultPluralRule(locale)
+		for n, category := range expected {
+			if got := rule(n); got != category {
+				t.Errorf("%s(%d): expected %q, got %q", locale, n, category, got)
+			}
+		}
+	}
+}
+
+func TestSetPluralRule(t *testing.T) {
+	bundle := i18n.NewBundle("en")
+	bundle.AddMessages("en", nil)
+	if err := bundle.LoadJSON("en", []byte(`{"n": {"zero": "none", "one": "one", "other": "{count}"}}`)); err != nil {
+		t.Fatalf("failed to load catalog: %v", err)
+	}
+	bundle.SetPluralRule("en", func(n int) string {
+		if n == 0 {
+			return i18n.PluralZero
+		}
+		return i18n.DefaultPluralRule("en")(n)
+	})
+
+	l := bundle.Localizer("en")
+	for n, expected := range map[int]string{0: "none", 1: "one", 5: "5", -1: "one"} {
+		if got := l.N("n", n, nil); got != expected {
+			t.Errorf("%d: expected %q, got %q", n, expected, got)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package i18n
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+// dataKey is the ext.Context Data key under which the update's Localizer is stored.
+const dataKey = "gotgbot_i18n"
+
+// LocaleSource returns the preferred locale for an update, or an empty string if it has no preference.
+type LocaleSource func(b *gotgbot.Bot, ctx *ext.Context) (string, error)
+
+// UserLanguage is a LocaleSource which returns the LanguageCode of the update's effective user, as set in their
+// telegram client.
+func UserLanguage(b *gotgbot.Bot, ctx *ext.Context) (string, error) {
+	if ctx.EffectiveUser == nil {
+		return "", nil
+	}
+	return ctx.EffectiveUser.LanguageCode, nil
+}
+
+// UserOverride is a LocaleSource which returns the locale chosen by the update's effective user, as stored in the
+// LocaleStore by user ID.
+func UserOverride(store LocaleStore) LocaleSource {
+	return func(b *gotgbot.Bot, ctx *ext.Context) (string, error) {
+		if ctx.EffectiveUser == nil {
+			return "", nil
+		}
+		return store.GetLocale(ctx.EffectiveUser.Id)
+	}
+}
+
+// ChatSetting is a LocaleSource which returns the locale set for the update's effective chat, as stored in the
+// LocaleStore by chat ID.
+func ChatSetting(store LocaleStore) LocaleSource {
+	return func(b *gotgbot.Bot, ctx *ext.Context) (string, error) {
+		if ctx.EffectiveChat == nil {
+			return "", nil
+		}
+		return store.GetLocale(ctx.EffectiveChat.Id)
+	}
+}
+
+// LocaleStore allows for defining custom backends to store the locales chosen by users, or set for chats.
+// If you are looking to persist locales, you should implement this interface with your backend of choice.
+type LocaleStore interface {
+	// GetLocale returns the locale stored for the given user or chat ID, or an empty string if none is stored.
+	GetLocale(id int64) (string, error)
+	// SetLocale stores the locale for the given user or chat ID. An empty locale removes the stored locale.
+	SetLocale(id int64, locale string) error
+}
+
+var _ LocaleStore = &InMemoryLocaleStore{}
+
+// InMemoryLocaleStore is a thread-safe in-memory implementation of the LocaleStore interface.
+type InMemoryLocaleStore struct {
+	// locales maps user or chat IDs to their locale.
+	locales map[int64]string
+	// lock allows us to ensure synchronous data access.
+	lock sync.RWMutex
+}
+
+// NewInMemoryLocaleStore creates a new, empty, InMemoryLocaleStore.
+func NewInMemoryLocaleStore() *InMemoryLocaleStore {
+	return &InMemoryLocaleStore{
+		locales: map[int64]string{},
+	}
+}
+
+func (s *InMemoryLocaleStore) GetLocale(id int64) (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.locales[id], nil
+}
+
+func (s *InMemoryLocaleStore) SetLocale(id int64, locale string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.locales == nil {
+		s.locales = map[int64]string{}
+	}
+
+	if locale == "" {
+		delete(s.locales, id)
+		return nil
+	}
+	s.locales[id] = locale
+	return nil
+}
+
+var _ ext.Processor = Processor{}
+
+// Processor is an ext.Processor which picks the locale of each update, and makes its Localizer available to handlers
+// through FromContext, T and N.
+//
+// The locale is taken from the first of the Sources to return a locale which has a catalog in the Bundle; if none
+// do, the bundle's default locale is used.
+type Processor struct {
+	// Bundle holds the message catalogs.
+	Bundle *Bundle
+	// Sources are checked in order, to find the locale of the update.
+	Sources []LocaleSource
+	// Processor is the wrapped processor, which processes the update once the locale is set.
+	// If nil, ext.BaseProcessor is used.
+	Processor ext.Processor
+}
+
+// ProcessorOpts represents the optional values to create an i18n Processor.
+type ProcessorOpts struct {
+	// Sources are checked in order, to find the locale of the update. Defaults to UserLanguage. For example, to
+	// prioritise the locale chosen by the user, then the locale set for the chat, and finally the user's own language:
+	//	[]i18n.LocaleSource{i18n.UserOverride(userLocales), i18n.ChatSetting(chatLocales), i18n.UserLanguage}
+	Sources []LocaleSource
+	// Processor is the wrapped processor. Defaults to ext.BaseProcessor.
+	Processor ext.Processor
+}
+
+// NewProcessor creates a new i18n Processor, using the given bundle.
+func NewProcessor(bundle *Bundle, opts *ProcessorOpts) Processor {
+	p := Processor{
+		Bundle:  bundle,
+		Sources: []LocaleSource{UserLanguage},
+	}
+	if opts != nil {
+		if opts.Sources != nil {
+			p.Sources = opts.Sources
+		}
+		p.Processor = opts.Processor
+	}
+	return p
+}
+
+// ProcessUpdate sets the Localizer of the update, and then processes it with the wrapped processor.
+func (p Processor) ProcessUpdate(d *ext.Dispatcher, b *gotgbot.Bot, ctx *ext.Context) error {
+	l, err := p.Localizer(b, ctx)
+	if err != nil {
+		return err
+	}
+	ctx.Data[dataKey] = l
+
+	processor := p.Processor
+	if processor == nil {
+		processor = ext.BaseProcessor{}
+	}
+	return processor.ProcessUpdate(d, b, ctx)
+}
+
+// Localizer returns the Localizer for the update, based on the processor's sources.
+func (p Processor) Localizer(b *gotgbot.Bot, ctx *ext.Context) (*Localizer, error) {
+	for _, source := range p.Sources {
+		locale, err := source(b, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get update locale: %w", err)
+		}
+		if match, ok := p.Bundle.Match(locale); ok {
+			return &Localizer{bundle: p.Bundle, locale: match}, nil
+		}
+	}
+	return p.Bundle.Localizer(""), nil
+}
+
+// FromContext returns the Localizer of the update. It returns nil if the dispatcher doesn't use an i18n Processor;
+// a nil Localizer returns all messages as their key.
+func FromContext(ctx *ext.Context) *Localizer {
+	l, _ := ctx.Data[dataKey].(*Localizer)
+	return l
+}
+
+// SetLocale changes the locale of the update's Localizer, for the rest of the update; eg, after the user has chosen a
+// new locale. It has no effect if the dispatcher doesn't use an i18n Processor.
+func SetLocale(ctx *ext.Context, locale string) {
+	if l := FromContext(ctx); l != nil {
+		ctx.Data[dataKey] = l.bundle.Localizer(locale)
+	}
+}
+
+// T translates the message with the given key, in the locale of the update. See Localizer.T.
+func T(ctx *ext.Context, key string, vars Vars) string {
+	return FromContext(ctx).T(key, vars)
+}
+
+// N translates the plural message with the given key, in the locale of the update. See Localizer.N.
+func N(ctx *ext.Context, key string, count int, vars Vars) string {
+	return FromContext(ctx).N(key, count, vars)
+}
@@ -0,0 +1,122 @@
+package i18n_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/i18n"
+	"github.com/PaulSonOfLars/gotgbot/v2/gotgbottest"
+)
+
+func TestProcessor(t *testing.T) {
+	bundle := newBundle(t)
+	users := i18n.NewInMemoryLocaleStore()
+	chats := i18n.NewInMemoryLocaleStore()
+
+	d := ext.NewDispatcher(&ext.DispatcherOpts{
+		Processor: i18n.NewProcessor(bundle, &i18n.ProcessorOpts{
+			Sources: []i18n.LocaleSource{i18n.UserOverride(users), i18n.ChatSetting(chats), i18n.UserLanguage},
+		}),
+	})
+	d.AddHandler(handlers.NewCommand("start", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		_, err := ctx.EffectiveMessage.Reply(b, i18n.T(ctx, "welcome", i18n.Vars{"name": ctx.EffectiveUser.FirstName}), nil)
+		return err
+	}))
+	d.AddHandler(handlers.NewCommand("ru", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		if err := users.SetLocale(ctx.EffectiveUser.Id, "ru"); err != nil {
+			return err
+		}
+		i18n.SetLocale(ctx, "ru")
+		_, err := ctx.EffectiveMessage.Reply(b, i18n.N(ctx, "cart.items", 5, nil), nil)
+		return err
+	}))
+
+	c := gotgbottest.NewClient()
+	b := c.Bot()
+	send := func(userId int64, chatId int64, lang string, text string) string {
+		t.Helper()
+		upd := gotgbottest.TextMessageUpdate(userId, chatId, text)
+		upd.Message.From.LanguageCode = lang
+		c.Reset()
+		if err := d.ProcessUpdate(b, &upd, nil); err != nil {
+			t.Fatalf("failed to process update: %v", err)
+		}
+		return c.AssertCalled(t, "sendMessage", nil).Params["text"]
+	}
+
+	for _, tc := range []struct {
+		userId   int64
+		chatId   int64
+		lang     string
+		text     string
+		expected string
+	}{
+		{userId: 1, chatId: 1, lang: "pt-br", text: "/start", expected: "Bem-vindo, Test User!"},
+		{userId: 1, chatId: 1, lang: "de", text: "/start", expected: "Welcome, Test User!"},
+		{userId: 1, chatId: 1, lang: "de", text: "/ru", expected: "У вас 5 товаров."},
+		// The user's override takes precedence over their language.
+		{userId: 1, chatId: 1, lang: "en", text: "/start", expected: "Добро пожаловать, Test User!"},
+		{userId: 2, chatId: -100, lang: "en", text: "/start", expected: "Welcome, Test User!"},
+	} {
+		if got := send(tc.userId, tc.chatId, tc.lang, tc.text); got != tc.expected {
+			t.Errorf("%s from %d in %d (%s): expected %q, got %q", tc.text, tc.userId, tc.chatId, tc.lang, tc.expected, got)
+		}
+	}
+
+	// The chat setting applies to users without an override.
+	if err := chats.SetLocale(-100, "pt-BR"); err != nil {
+		t.Fatalf("failed to set chat locale: %v", err)
+	}
+	if got := send(2, -100, "en", "/start"); got != "Bem-vindo, Test User!" {
+		t.Errorf("expected chat locale to be used, got %q", got)
+	}
+
+	if got := i18n.T(gotgbottest.NewTextMessage(1, 1, "hi"), "welcome", nil); got != "welcome" {
+		t.Errorf("expected key without an i18n processor, got %q", got)
+	}
+}
+
+func TestSyncLocalizedBotCommands(t *testing.T) {
+	bundle := i18n.NewBundle("en")
+	bundle.AddMessages("en", map[string]string{"commands.start": "Start the bot"})
+	bundle.AddMessages("ru", map[string]string{"commands.start": "Запустить бота"})
+	bundle.AddMessages("pt-br", map[string]string{"commands.start": "Iniciar o bot"})
+	bundle.AddMessages("de", map[string]string{"welcome": "Willkommen"})
+
+	d := ext.NewDispatcher(nil)
+	noop := func(b *gotgbot.Bot, ctx *ext.Context) error { return nil }
+	d.AddHandler(handlers.NewCommand("start", noop).SetDescription("start"))
+	d.AddHandler(handlers.NewCommand("help", noop).SetDescription("Show help"))
+
+	c := gotgbottest.NewClient()
+	c.RespondWith("getMyCommands", []gotgbot.BotCommand{})
+	b := c.Bot()
+
+	if err := d.SyncBotCommands(b, &ext.SyncBotCommandsOpts{Localizer: bundle}); err != nil {
+		t.Fatalf("failed to sync bot commands: %v", err)
+	}
+
+	calls := c.CallsTo("setMyCommands")
+	if len(calls) != 2 {
+		t.Fatalf("expected commands to be set for the default language and ru, got %d calls", len(calls))
+	}
+	for i, expected := range []struct {
+		lang     string
+		commands []gotgbot.BotCommand
+	}{
+		{lang: "", commands: []gotgbot.BotCommand{{Command: "start", Description: "Start the bot"}, {Command: "help", Description: "Show help"}}},
+		{lang: "ru", commands: []gotgbot.BotCommand{{Command: "start", Description: "Запустить бота"}, {Command: "help", Description: "Show help"}}},
+	} {
+		var got []gotgbot.BotCommand
+		if err := json.Unmarshal([]byte(calls[i].Params["commands"]), &got); err != nil {
+			t.Fatalf("failed to unmarshal commands: %v", err)
+		}
+		if calls[i].Params["language_code"] != expected.lang || len(got) != len(expected.commands) ||
+			got[0] != expected.commands[0] || got[1] != expected.commands[1] {
+			t.Errorf("unexpected commands for %q: %+v", calls[i].Params["language_code"], got)
+		}
+	}
+}
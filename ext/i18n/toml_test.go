@@ -0,0 +1,73 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	got, err := parseTOML([]byte(`# Comment
+title = "Hello \"world\"\t\u00e9" # trailing comment
+literal = 'C:\path\{name}'
+"quoted key" = "q"
+a.b.c = "dotted"
+
+[menu]
+open = """
+Line one
+Line "two"\
+    continued"""
+raw = '''
+keep \n as is'''
+
+[menu.sub]
+empty = {}
+plural = { one = "1 item", other = "{count} items" }
+
+[a]
+d = "merged"
+`))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"title":      "Hello \"world\"\t\u00e9",
+		"literal":    `C:\path\{name}`,
+		"quoted key": "q",
+		"a": map[string]interface{}{
+			"b": map[string]interface{}{"c": "dotted"},
+			"d": "merged",
+		},
+		"menu": map[string]interface{}{
+			"open": "Line one\nLine \"two\"continued",
+			"raw":  `keep \n as is`,
+			"sub": map[string]interface{}{
+				"empty":  map[string]interface{}{},
+				"plural": map[string]interface{}{"one": "1 item", "other": "{count} items"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected result:\n%#v\nexpected:\n%#v", got, expected)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"number":           `a = 1`,
+		"array":            `a = ["x"]`,
+		"array of tables":  `[[a]]`,
+		"duplicate":        "a = \"x\"\na = \"y\"",
+		"not a table":      "a = \"x\"\n[a]",
+		"unterminated":     `a = "x`,
+		"unterminated ml":  `a = """x`,
+		"missing equals":   `a "x"`,
+		"trailing content": `a = "x" b`,
+		"bad escape":       `a = "\q"`,
+	} {
+		if _, err := parseTOML([]byte(src)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package ext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+)
+
+// ErrLeaderElectionUnsupported is returned by leader electors which cannot run on the current platform.
+var ErrLeaderElectionUnsupported = errors.New("leader election is not supported on this platform")
+
+// LeaderElector allows for running multiple replicas of a polling bot, while ensuring that only one of them polls for
+// updates at any given time. The other replicas stand by, and take over once the leader goes away.
+//
+// If you are looking to elect leaders across multiple machines, you should implement this interface with your
+// backend of choice (eg, a database lease, or a distributed lock).
+type LeaderElector interface {
+	// Acquire blocks until this instance becomes the leader for the given bot, or until the context is cancelled.
+	// The returned channel should be closed if leadership is lost afterwards (eg, when a lease fails to renew), at
+	// which point polling stops, and Acquire is called again. Implementations which cannot lose leadership may
+	// return a nil channel.
+	Acquire(ctx context.Context, b *gotgbot.Bot) (<-chan struct{}, error)
+	// Release gives up leadership for the given bot, allowing another instance to take over.
+	Release(b *gotgbot.Bot) error
+}
+
+var _ LeaderElector = &FileLeaderElector{}
+
+// DefaultLeaderRetryInterval is the default interval at which a FileLeaderElector checks if the lock is available.
+const DefaultLeaderRetryInterval = time.Second
+
+// FileLeaderElector is a LeaderElector which relies on local file locks. This is useful when running multiple
+// replicas on the same machine, or on a shared filesystem which supports file locks.
+// Since the lock is held by the operating system, it is released automatically if the leader process exits.
+type FileLeaderElector struct {
+	// Dir is the directory in which the lock files are created.
+	// Each bot uses its own lock file, named after the bot ID.
+	Dir string
+	// RetryInterval is how often to check whether the lock has become available.
+	// If 0, DefaultLeaderRetryInterval is used.
+	RetryInterval time.Duration
+
+	// locks keeps track of the lock files currently held, keyed by bot ID.
+	locks map[string]*os.File
+	// mux ensures the locks map is concurrency-safe.
+	mux sync.Mutex
+}
+
+// NewFileLeaderElector creates a new FileLeaderElector, storing lock files in the given directory.
+func NewFileLeaderElector(dir string) *FileLeaderElector {
+	return &FileLeaderElector{
+		Dir:           dir,
+		RetryInterval: DefaultLeaderRetryInterval,
+	}
+}
+
+func (f *FileLeaderElector) Acquire(ctx context.Context, b *gotgbot.Bot) (<-chan struct{}, error) {
+	retryInterval := f.RetryInterval
+	if retryInterval <= 0 {
+		retryInterval = DefaultLeaderRetryInterval
+	}
+
+	path := filepath.Join(f.Dir, "gotgbot-"+getBotId(b)+".lock")
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open lock file: %w", err)
+		}
+
+		ok, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock file: %w", err)
+		}
+		if ok {
+			f.mux.Lock()
+			if f.locks == nil {
+				f.locks = map[string]*os.File{}
+			}
+			f.locks[getBotId(b)] = file
+			f.mux.Unlock()
+
+			// File locks are only lost when released, so we never close this channel.
+			return nil, nil
+		}
+		file.Close()
+
+		t := time.NewTimer(retryInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (f *FileLeaderElector) Release(b *gotgbot.Bot) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	file, ok := f.locks[getBotId(b)]
+	if !ok {
+		return nil
+	}
+	delete(f.locks, getBotId(b))
+
+	// Closing the file releases the lock.
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to release lock file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package ext
+
+import "os"
+
+// tryLockFile is not supported on this platform.
+func tryLockFile(_ *os.File) (bool, error) {
+	return false, ErrLeaderElectionUnsupported
+}
@@ -0,0 +1,102 @@
+package ext_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+func TestFileLeaderElector(t *testing.T) {
+	dir := t.TempDir()
+	b := &gotgbot.Bot{Token: "123:abc"}
+
+	leader := ext.NewFileLeaderElector(dir)
+	standby := &ext.FileLeaderElector{Dir: dir, RetryInterval: 10 * time.Millisecond}
+
+	if _, err := leader.Acquire(context.Background(), b); err != nil {
+		t.Fatalf("failed to acquire leadership: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := standby.Acquire(ctx, b); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected standby to time out while leader holds the lock, got %v", err)
+	}
+
+	// Other bots should not be affected.
+	if _, err := standby.Acquire(context.Background(), &gotgbot.Bot{Token: "456:def"}); err != nil {
+		t.Fatalf("failed to acquire leadership for another bot: %v", err)
+	}
+
+	if err := leader.Release(b); err != nil {
+		t.Fatalf("failed to release leadership: %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := standby.Acquire(ctx, b); err != nil {
+		t.Fatalf("expected standby to take over once the leader is gone, got %v", err)
+	}
+}
+
+func TestUpdaterPollingLeaderElection(t *testing.T) {
+	server := basicTestServer(t, map[string]*testEndpoint{
+		"getUpdates": {reply: `{"ok": true, "result": []}`, delay: 10 * time.Millisecond},
+	})
+	defer server.Close()
+
+	reqOpts := &gotgbot.RequestOpts{
+		APIURL: server.URL,
+	}
+
+	dir := t.TempDir()
+	newReplica := func() (*ext.Updater, *gotgbot.Bot) {
+		b := &gotgbot.Bot{
+			Token: "123:SOME_TOKEN",
+			BotClient: &gotgbot.BaseBotClient{
+				DefaultRequestOpts: reqOpts,
+			},
+		}
+		u := ext.NewUpdater(ext.NewDispatcher(nil), nil)
+		err := u.StartPolling(b, &ext.PollingOpts{
+			GetUpdatesOpts: &gotgbot.GetUpdatesOpts{
+				RequestOpts: reqOpts,
+			},
+			LeaderElector: &ext.FileLeaderElector{Dir: dir, RetryInterval: 10 * time.Millisecond},
+		})
+		if err != nil {
+			t.Fatalf("failed to start polling: %v", err)
+		}
+		return u, b
+	}
+
+	u1, b1 := newReplica()
+	time.Sleep(50 * time.Millisecond)
+	u2, b2 := newReplica()
+	time.Sleep(50 * time.Millisecond)
+
+	if status, _ := u1.GetPollingStatus(b1.Token); status.Standby || status.LastSuccess.IsZero() {
+		t.Errorf("expected first replica to be polling, got %+v", status)
+	}
+	if status, _ := u2.GetPollingStatus(b2.Token); !status.Standby || !status.LastSuccess.IsZero() {
+		t.Errorf("expected second replica to be on standby, got %+v", status)
+	}
+
+	// Once the leader stops, the standby replica should take over.
+	if err := u1.Stop(); err != nil {
+		t.Fatalf("failed to stop first updater: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if status, _ := u2.GetPollingStatus(b2.Token); status.Standby || status.LastSuccess.IsZero() {
+		t.Errorf("expected second replica to have taken over, got %+v", status)
+	}
+
+	if err := u2.Stop(); err != nil {
+		t.Fatalf("failed to stop second updater: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package ext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+)
+
+// OffsetStore allows for persisting the long-polling offset across restarts.
+// The stored value is the offset of the next update to fetch; ie, the ID of the last processed update, plus one.
+// Offsets are only saved once the updates have been processed, so updates which were still being handled when the bot
+// stopped are fetched again after a restart: delivery is at-least-once.
+//
+// If you are looking to store offsets in a database, you should implement this interface with your backend of choice.
+type OffsetStore interface {
+	// LoadOffset returns the stored offset for the given bot.
+	// If no offset has been stored yet, it should return 0 and no error.
+	LoadOffset(b *gotgbot.Bot) (int64, error)
+	// SaveOffset stores the next offset to fetch for the given bot.
+	SaveOffset(b *gotgbot.Bot, offset int64) error
+}
+
+var (
+	_ OffsetStore = &InMemoryOffsetStore{}
+	_ OffsetStore = &FileOffsetStore{}
+)
+
+// InMemoryOffsetStore is a thread-safe in-memory implementation of the OffsetStore interface.
+// It does not persist data across restarts, but can be useful when restarting polling within the same process, or for
+// testing.
+type InMemoryOffsetStore struct {
+	// offsets maps the bot ID to the next offset to fetch.
+	offsets map[string]int64
+	// lock allows us to ensure synchronous data access.
+	lock sync.RWMutex
+}
+
+// NewInMemoryOffsetStore creates a new, empty, InMemoryOffsetStore.
+func NewInMemoryOffsetStore() *InMemoryOffsetStore {
+	return &InMemoryOffsetStore{
+		offsets: map[string]int64{},
+	}
+}
+
+func (s *InMemoryOffsetStore) LoadOffset(b *gotgbot.Bot) (int64, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.offsets[getBotId(b)], nil
+}
+
+func (s *InMemoryOffsetStore) SaveOffset(b *gotgbot.Bot, offset int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.offsets == nil {
+		s.offsets = map[string]int64{}
+	}
+
+	s.offsets[getBotId(b)] = offset
+	return nil
+}
+
+// FileOffsetStore stores polling offsets in a JSON file on disk, keyed by bot ID.
+// A single file can be shared by all the bots running on the same Updater.
+// Writes are atomic; the file is written to a temporary file, which is then renamed.
+type FileOffsetStore struct {
+	// Path is the path of the file to store the offsets in.
+	// The parent directory must already exist.
+	Path string
+
+	// lock allows us to ensure synchronous file access.
+	lock sync.Mutex
+}
+
+// NewFileOffsetStore creates a new FileOffsetStore, storing offsets in the file at the given path.
+func NewFileOffsetStore(path string) *FileOffsetStore {
+	return &FileOffsetStore{
+		Path: path,
+	}
+}
+
+func (s *FileOffsetStore) LoadOffset(b *gotgbot.Bot) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	offsets, err := s.readOffsets()
+	if err != nil {
+		return 0, err
+	}
+	return offsets[getBotId(b)], nil
+}
+
+func (s *FileOffsetStore) SaveOffset(b *gotgbot.Bot, offset int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	offsets, err := s.readOffsets()
+	if err != nil {
+		return err
+	}
+
+	offsets[getBotId(b)] = offset
+
+	bs, err := json.Marshal(offsets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offsets: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary offset file: %w", err)
+	}
+	// Clean up the temporary file if anything goes wrong; this is a noop once it has been renamed.
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(bs); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write offset file: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync offset file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close offset file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), s.Path); err != nil {
+		return fmt.Errorf("failed to replace offset file: %w", err)
+	}
+	return nil
+}
+
+// readOffsets reads the current offset file contents. A missing file is treated as empty.
+func (s *FileOffsetStore) readOffsets() (map[string]int64, error) {
+	offsets := map[string]int64{}
+
+	bs, err := os.ReadFile(s.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return offsets, nil
+		}
+		return nil, fmt.Errorf("failed to read offset file: %w", err)
+	}
+
+	if len(bs) == 0 {
+		return offsets, nil
+	}
+
+	if err := json.Unmarshal(bs, &offsets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal offset file: %w", err)
+	}
+	return offsets, nil
+}
+
+// getBotId extracts the bot ID from the bot token, so we can key data by bot without storing the token.
+func getBotId(b *gotgbot.Bot) string {
+	return strings.Split(b.Token, ":")[0]
+}
@@ -0,0 +1,266 @@
+package ext_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
+)
+
+func TestFileOffsetStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "offsets.json")
+
+	b1 := &gotgbot.Bot{Token: "123:abc"}
+	b2 := &gotgbot.Bot{Token: "456:def"}
+
+	s := ext.NewFileOffsetStore(path)
+	offset, err := s.LoadOffset(b1)
+	if err != nil {
+		t.Fatalf("failed to load offset from missing file: %v", err)
+	}
+	if offset != 0 {
+		t.Fatalf("expected empty offset, got %d", offset)
+	}
+
+	if err := s.SaveOffset(b1, 10); err != nil {
+		t.Fatalf("failed to save offset: %v", err)
+	}
+	if err := s.SaveOffset(b2, 20); err != nil {
+		t.Fatalf("failed to save offset: %v", err)
+	}
+
+	// Use a new store to make sure the data was actually persisted.
+	s = ext.NewFileOffsetStore(path)
+	for b, expected := range map[*gotgbot.Bot]int64{b1: 10, b2: 20} {
+		offset, err := s.LoadOffset(b)
+		if err != nil {
+			t.Fatalf("failed to load offset: %v", err)
+		}
+		if offset != expected {
+			t.Errorf("expected offset %d, got %d", expected, offset)
+		}
+	}
+}
+
+func TestUpdaterPollingOffsetStore(t *testing.T) {
+	var mux sync.Mutex
+	var offsets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/getUpdates") {
+			t.Errorf("unexpected API call to %s", r.URL.Path)
+			return
+		}
+
+		var params map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("failed to decode params: %v", err)
+			return
+		}
+
+		mux.Lock()
+		offsets = append(offsets, params["offset"])
+		first := len(offsets) == 1
+		mux.Unlock()
+
+		if first {
+			fmt.Fprint(w, `{"ok": true, "result": [{"update_id": 11, "message": {"text": "hello"}}]}`)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, `{"ok": true, "result": []}`)
+	}))
+	defer server.Close()
+
+	reqOpts := &gotgbot.RequestOpts{
+		APIURL: server.URL,
+	}
+
+	b := &gotgbot.Bot{
+		Token: "123:SOME_TOKEN",
+		BotClient: &gotgbot.BaseBotClient{
+			DefaultRequestOpts: reqOpts,
+		},
+	}
+
+	store := ext.NewInMemoryOffsetStore()
+	if err := store.SaveOffset(b, 10); err != nil {
+		t.Fatalf("failed to save initial offset: %v", err)
+	}
+
+	d := ext.NewDispatcher(nil)
+	u := ext.NewUpdater(d, nil)
+
+	err := u.StartPolling(b, &ext.PollingOpts{
+		GetUpdatesOpts: &gotgbot.GetUpdatesOpts{
+			RequestOpts: reqOpts,
+		},
+		OffsetStore: store,
+	})
+	if err != nil {
+		t.Fatalf("failed to start polling: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := u.Stop(); err != nil {
+		t.Fatalf("failed to stop updater: %v", err)
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+	if len(offsets) < 2 {
+		t.Fatalf("expected at least two getUpdates calls, got %d", len(offsets))
+	}
+	if offsets[0] != "10" {
+		t.Errorf("expected first call to use stored offset 10, got %q", offsets[0])
+	}
+	if offsets[1] != "12" {
+		t.Errorf("expected second call to use offset 12, got %q", offsets[1])
+	}
+
+	offset, err := store.LoadOffset(b)
+	if err != nil {
+		t.Fatalf("failed to load offset: %v", err)
+	}
+	if offset != 12 {
+		t.Errorf("expected saved offset to be 12, got %d", offset)
+	}
+}
+
+func TestUpdaterPollingOffsetStoreRestart(t *testing.T) {
+	var mux sync.Mutex
+	var offsets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("failed to decode params: %v", err)
+			return
+		}
+
+		mux.Lock()
+		offsets = append(offsets, params["offset"])
+		mux.Unlock()
+
+		// Update 21 is only confirmed once an offset above 21 is requested.
+		if params["offset"] == "21" {
+			fmt.Fprint(w, `{"ok": true, "result": [{"update_id": 21, "message": {"text": "hello"}}]}`)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, `{"ok": true, "result": []}`)
+	}))
+	defer server.Close()
+
+	reqOpts := &gotgbot.RequestOpts{
+		APIURL: server.URL,
+	}
+
+	b := &gotgbot.Bot{
+		Token: "123:SOME_TOKEN",
+		BotClient: &gotgbot.BaseBotClient{
+			DefaultRequestOpts: reqOpts,
+		},
+	}
+
+	store := ext.NewInMemoryOffsetStore()
+	if err := store.SaveOffset(b, 21); err != nil {
+		t.Fatalf("failed to save initial offset: %v", err)
+	}
+
+	opts := &ext.PollingOpts{
+		GetUpdatesOpts: &gotgbot.GetUpdatesOpts{
+			RequestOpts: reqOpts,
+		},
+		OffsetStore: store,
+	}
+
+	// The first run stops while the handler is still running.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	d := ext.NewDispatcher(nil)
+	d.AddHandler(handlers.NewMessage(message.All, func(b *gotgbot.Bot, ctx *ext.Context) error {
+		close(started)
+		<-release
+		return nil
+	}))
+	u := ext.NewUpdater(d, nil)
+
+	if err := u.StartPolling(b, opts); err != nil {
+		t.Fatalf("failed to start polling: %v", err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the handler to start")
+	}
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- u.Stop()
+	}()
+	// Give the polling loop time to stop, before letting the handler complete.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("failed to stop updater: %v", err)
+	}
+
+	mux.Lock()
+	if len(offsets) != 1 {
+		t.Errorf("expected no getUpdates calls while the update was being handled, got offsets %v", offsets)
+	}
+	mux.Unlock()
+
+	offset, err := store.LoadOffset(b)
+	if err != nil {
+		t.Fatalf("failed to load offset: %v", err)
+	}
+	if offset != 21 {
+		t.Fatalf("expected offset to remain 21 after stopping mid-update, got %d", offset)
+	}
+
+	// After restarting, the update is fetched and handled again.
+	handled := make(chan int64, 1)
+	d = ext.NewDispatcher(nil)
+	d.AddHandler(handlers.NewMessage(message.All, func(b *gotgbot.Bot, ctx *ext.Context) error {
+		handled <- ctx.UpdateId
+		return nil
+	}))
+	u = ext.NewUpdater(d, nil)
+
+	if err := u.StartPolling(b, opts); err != nil {
+		t.Fatalf("failed to restart polling: %v", err)
+	}
+
+	select {
+	case id := <-handled:
+		if id != 21 {
+			t.Errorf("expected update 21 to be handled again, got %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the update to be fetched again")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := u.Stop(); err != nil {
+		t.Fatalf("failed to stop updater: %v", err)
+	}
+
+	offset, err = store.LoadOffset(b)
+	if err != nil {
+		t.Fatalf("failed to load offset: %v", err)
+	}
+	if offset != 22 {
+		t.Errorf("expected offset to be saved as 22 once the update was handled, got %d", offset)
+	}
+}
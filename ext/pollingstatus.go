@@ -0,0 +1,79 @@
+package ext
+
+import (
+	"sync"
+	"time"
+)
+
+// PollingStatus describes the health of a bot's long-polling loop.
+// It can be used to implement liveness or readiness probes.
+type PollingStatus struct {
+	// Running is true while the polling loop is active.
+	// This becomes false once the bot is stopped, or if polling was aborted due to an unrecoverable error (eg, a
+	// revoked token).
+	Running bool
+	// Standby is true while the polling loop is waiting to acquire leadership from a LeaderElector.
+	Standby bool
+	// LastSuccess is the time of the last successful getUpdates call.
+	// This is the zero time if no call has succeeded yet.
+	LastSuccess time.Time
+	// LastFailure is the time of the last failed getUpdates call.
+	// This is the zero time if no call has failed yet.
+	LastFailure time.Time
+	// LastError is the error returned by the last failed getUpdates call, if any.
+	LastError error
+	// ConsecutiveFailures is the number of getUpdates calls which have failed since the last successful call.
+	ConsecutiveFailures int
+}
+
+// Healthy returns true if the polling loop is running, and has failed fewer than maxFailures times in a row.
+// Replicas on standby are considered healthy.
+func (s PollingStatus) Healthy(maxFailures int) bool {
+	return s.Running && s.ConsecutiveFailures < maxFailures
+}
+
+// pollingState keeps track of the PollingStatus of a polling loop in a thread-safe manner.
+type pollingState struct {
+	status PollingStatus
+	mux    sync.RWMutex
+}
+
+func (s *pollingState) get() PollingStatus {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.status
+}
+
+func (s *pollingState) setRunning(running bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.status.Running = running
+}
+
+func (s *pollingState) setStandby(standby bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.status.Standby = standby
+}
+
+func (s *pollingState) success() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.status.LastSuccess = time.Now()
+	s.status.ConsecutiveFailures = 0
+}
+
+// failure records a failed call, and returns the number of consecutive failures.
+func (s *pollingState) failure(err error) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.status.LastFailure = time.Now()
+	s.status.LastError = err
+	s.status.ConsecutiveFailures++
+	return s.status.ConsecutiveFailures
+}
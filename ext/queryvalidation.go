@@ -1,15 +1,122 @@
 package ext
 
 import (
+	"crypto/ed25519"
 	"crypto/hmac"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
+var (
+	ErrInvalidHash      = errors.New("invalid hash")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrMissingAuthDate  = errors.New("missing auth_date")
+	ErrAuthDateExpired  = errors.New("auth_date has expired")
+	ErrAuthDateInFuture = errors.New("auth_date is in the future")
+)
+
+// AuthDateClockSkew is how far in the future an auth_date may be, to allow for clock differences between telegram and
+// the local server. Dates further in the future are rejected when checking the maxAge of a query, since they would
+// take longer to expire.
+const AuthDateClockSkew = time.Minute
+
+// WebAppUser contains the data of a user, as sent in a webapp's initData.
+// See https://core.telegram.org/bots/webapps#webappuser for more details.
+type WebAppUser struct {
+	// A unique identifier for the user or bot.
+	Id int64 `json:"id"`
+	// Optional. True, if this user is a bot. Returns in the receiver field only.
+	IsBot bool `json:"is_bot,omitempty"`
+	// First name of the user or bot.
+	FirstName string `json:"first_name"`
+	// Optional. Last name of the user or bot.
+	LastName string `json:"last_name,omitempty"`
+	// Optional. Username of the user or bot.
+	Username string `json:"username,omitempty"`
+	// Optional. IETF language tag of the user's language. Returns in user field only.
+	LanguageCode string `json:"language_code,omitempty"`
+	// Optional. True, if this user is a Telegram Premium user.
+	IsPremium bool `json:"is_premium,omitempty"`
+	// Optional. True, if this user added the bot to the attachment menu.
+	AddedToAttachmentMenu bool `json:"added_to_attachment_menu,omitempty"`
+	// Optional. True, if this user allowed the bot to message them.
+	AllowsWriteToPm bool `json:"allows_write_to_pm,omitempty"`
+	// Optional. URL of the user’s profile photo.
+	PhotoUrl string `json:"photo_url,omitempty"`
+}
+
+// WebAppChat contains the data of a chat, as sent in a webapp's initData.
+// See https://core.telegram.org/bots/webapps#webappchat for more details.
+type WebAppChat struct {
+	// Unique identifier for this chat.
+	Id int64 `json:"id"`
+	// Type of chat, can be either "group", "supergroup" or "channel".
+	Type string `json:"type"`
+	// Title of the chat.
+	Title string `json:"title"`
+	// Optional. Username of the chat.
+	Username string `json:"username,omitempty"`
+	// Optional. URL of the chat’s photo.
+	PhotoUrl string `json:"photo_url,omitempty"`
+}
+
+// WebAppInitData contains the parsed and validated contents of a webapp's initData field.
+// See https://core.telegram.org/bots/webapps#webappinitdata for more details.
+type WebAppInitData struct {
+	// Optional. A unique identifier for the Web App session, required for sending messages via the
+	// answerWebAppQuery method.
+	QueryId string
+	// Optional. An object containing data about the current user.
+	User *WebAppUser
+	// Optional. An object containing data about the chat partner of the current user in the chat where the bot was
+	// launched via the attachment menu.
+	Receiver *WebAppUser
+	// Optional. An object containing data about the chat where the bot was launched via the attachment menu.
+	Chat *WebAppChat
+	// Optional. Type of the chat from which the Web App was opened.
+	ChatType string
+	// Optional. Global identifier, uniquely corresponding to the chat from which the Web App was opened.
+	ChatInstance string
+	// Optional. The value of the startattach parameter, passed via link.
+	StartParam string
+	// Optional. Time after which a message can be sent via the answerWebAppQuery method.
+	CanSendAfter time.Duration
+	// The time at which the form was opened.
+	AuthDate time.Time
+	// The hash used to validate the initData.
+	Hash string
+	// Optional. The Ed25519 signature, used to validate the initData without the bot token.
+	Signature string
+}
+
+// LoginWidgetData contains the parsed and validated contents of a login widget query.
+// See https://core.telegram.org/widgets/login#receiving-authorization-data for more details.
+type LoginWidgetData struct {
+	// The ID of the logged-in user.
+	Id int64
+	// The first name of the logged-in user.
+	FirstName string
+	// Optional. The last name of the logged-in user.
+	LastName string
+	// Optional. The username of the logged-in user.
+	Username string
+	// Optional. The URL of the logged-in user's profile photo.
+	PhotoUrl string
+	// The time at which the user logged in.
+	AuthDate time.Time
+	// The hash used to validate the query.
+	Hash string
+}
+
 // ValidateLoginQuery validates a login widget query.
 // See https://core.telegram.org/widgets/login#checking-authorization for more details.
 func ValidateLoginQuery(query url.Values, token string) (bool, error) {
@@ -45,6 +152,247 @@ func ValidateWebAppQuery(query url.Values, token string) (bool, error) {
 	return validateQuery(query, tokenHMAC)
 }
 
+// ParseLoginQuery validates a login widget query, and returns the logged-in user's data.
+// If maxAge is non-zero, queries with an auth_date older than maxAge are rejected with ErrAuthDateExpired, to avoid
+// replays of leaked queries. Queries dated in the future, beyond AuthDateClockSkew, are rejected with
+// ErrAuthDateInFuture.
+// Queries which fail validation return ErrInvalidHash.
+// See https://core.telegram.org/widgets/login#checking-authorization for more details.
+func ParseLoginQuery(query url.Values, token string, maxAge time.Duration) (*LoginWidgetData, error) {
+	ok, err := ValidateLoginQuery(query, token)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidHash
+	}
+
+	authDate, err := parseAuthDate(query, maxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseInt(query.Get("id"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return &LoginWidgetData{
+		Id:        id,
+		FirstName: query.Get("first_name"),
+		LastName:  query.Get("last_name"),
+		Username:  query.Get("username"),
+		PhotoUrl:  query.Get("photo_url"),
+		AuthDate:  authDate,
+		Hash:      query.Get("hash"),
+	}, nil
+}
+
+// ParseWebAppInitData validates a webapp's initData field, and returns the parsed contents.
+// If maxAge is non-zero, initData with an auth_date older than maxAge is rejected with ErrAuthDateExpired, to avoid
+// replays of leaked initData. InitData dated in the future, beyond AuthDateClockSkew, is rejected with
+// ErrAuthDateInFuture.
+// InitData which fails validation returns ErrInvalidHash.
+// See https://core.telegram.org/bots/webapps#validating-data-received-via-the-web-app for more details.
+func ParseWebAppInitData(initData string, token string, maxAge time.Duration) (*WebAppInitData, error) {
+	query, err := url.ParseQuery(initData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL query: %w", err)
+	}
+
+	return ParseWebAppQuery(query, token, maxAge)
+}
+
+// ParseWebAppQuery validates a webapp's initData query, and returns the parsed contents.
+// The input is expected to be the parsed initData query string.
+// See ParseWebAppInitData for more details.
+func ParseWebAppQuery(query url.Values, token string, maxAge time.Duration) (*WebAppInitData, error) {
+	ok, err := ValidateWebAppQuery(query, token)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidHash
+	}
+
+	return parseWebAppQuery(query, maxAge)
+}
+
+// parseWebAppQuery parses the contents of an already-validated webapp initData query.
+func parseWebAppQuery(query url.Values, maxAge time.Duration) (*WebAppInitData, error) {
+	authDate, err := parseAuthDate(query, maxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	data := WebAppInitData{
+		QueryId:      query.Get("query_id"),
+		ChatType:     query.Get("chat_type"),
+		ChatInstance: query.Get("chat_instance"),
+		StartParam:   query.Get("start_param"),
+		AuthDate:     authDate,
+		Hash:         query.Get("hash"),
+		Signature:    query.Get("signature"),
+	}
+
+	if v := query.Get("user"); v != "" {
+		if err := json.Unmarshal([]byte(v), &data.User); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal user: %w", err)
+		}
+	}
+	if v := query.Get("receiver"); v != "" {
+		if err := json.Unmarshal([]byte(v), &data.Receiver); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal receiver: %w", err)
+		}
+	}
+	if v := query.Get("chat"); v != "" {
+		if err := json.Unmarshal([]byte(v), &data.Chat); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal chat: %w", err)
+		}
+	}
+	if v := query.Get("can_send_after"); v != "" {
+		canSendAfter, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse can_send_after: %w", err)
+		}
+		data.CanSendAfter = time.Duration(canSendAfter) * time.Second
+	}
+
+	return &data, nil
+}
+
+// parseAuthDate parses the auth_date field of a query, and checks that it isn't older than maxAge (if non-zero), nor
+// in the future.
+func parseAuthDate(query url.Values, maxAge time.Duration) (time.Time, error) {
+	v := query.Get("auth_date")
+	if v == "" {
+		return time.Time{}, ErrMissingAuthDate
+	}
+
+	unix, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse auth_date: %w", err)
+	}
+
+	authDate := time.Unix(unix, 0)
+	if maxAge > 0 {
+		age := time.Since(authDate)
+		if age > maxAge {
+			return time.Time{}, fmt.Errorf("%w: data is %s old", ErrAuthDateExpired, age.Truncate(time.Second))
+		}
+		if -age > AuthDateClockSkew {
+			return time.Time{}, fmt.Errorf("%w: data is dated %s ahead", ErrAuthDateInFuture, (-age).Truncate(time.Second))
+		}
+	}
+	return authDate, nil
+}
+
+// TelegramEnvironment defines which telegram environment some data was signed for.
+type TelegramEnvironment int
+
+const (
+	// ProductionEnvironment is the default telegram environment.
+	ProductionEnvironment TelegramEnvironment = iota
+	// TestEnvironment is telegram's test environment.
+	// See https://core.telegram.org/bots/webapps#using-bots-in-the-test-environment for more details.
+	TestEnvironment
+)
+
+// telegramPublicKeys contains the hex-encoded Ed25519 public keys telegram uses to sign webapp initData.
+// See https://core.telegram.org/bots/webapps#validating-data-for-third-party-use for more details.
+var telegramPublicKeys = map[TelegramEnvironment]string{
+	ProductionEnvironment: "e7bf03a2fa4602af4580703d88dda5bb59f32ed8b02a56c187fe7d34caed242d",
+	TestEnvironment:       "40055058a4ee38156a06562e52eece92a771bcd8346a8c4615cb7376eddf72ec",
+}
+
+// ValidateWebAppInitDataThirdParty validates a webapp's initData field using telegram's Ed25519 signature.
+// Unlike ValidateWebAppInitData, this does not require the bot token; only the ID of the bot the webapp belongs to.
+// This allows for third-party services to validate initData.
+// See https://core.telegram.org/bots/webapps#validating-data-for-third-party-use for more details.
+func ValidateWebAppInitDataThirdParty(initData string, botId int64, env TelegramEnvironment) (bool, error) {
+	query, err := url.ParseQuery(initData)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse URL query: %w", err)
+	}
+
+	return ValidateWebAppQueryThirdParty(query, botId, env)
+}
+
+// ValidateWebAppQueryThirdParty validates a webapp's initData query using telegram's Ed25519 signature.
+// The input is expected to be the parsed initData query string.
+// See ValidateWebAppInitDataThirdParty for more details.
+func ValidateWebAppQueryThirdParty(query url.Values, botId int64, env TelegramEnvironment) (bool, error) {
+	hexKey, ok := telegramPublicKeys[env]
+	if !ok {
+		return false, fmt.Errorf("unknown telegram environment: %d", env)
+	}
+
+	publicKey, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode public key: %w", err)
+	}
+
+	return validateQuerySignature(query, botId, publicKey)
+}
+
+// ParseWebAppInitDataThirdParty validates a webapp's initData field using telegram's Ed25519 signature, and returns
+// the parsed contents.
+// If maxAge is non-zero, initData with an auth_date older than maxAge is rejected with ErrAuthDateExpired, and
+// initData dated in the future is rejected with ErrAuthDateInFuture.
+// InitData which fails validation returns ErrInvalidSignature.
+// See ValidateWebAppInitDataThirdParty for more details.
+func ParseWebAppInitDataThirdParty(initData string, botId int64, env TelegramEnvironment, maxAge time.Duration) (*WebAppInitData, error) {
+	query, err := url.ParseQuery(initData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL query: %w", err)
+	}
+
+	ok, err := ValidateWebAppQueryThirdParty(query, botId, env)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidSignature
+	}
+
+	return parseWebAppQuery(query, maxAge)
+}
+
+func validateQuerySignature(query url.Values, botId int64, publicKey ed25519.PublicKey) (bool, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return false, fmt.Errorf("invalid public key size: %d", len(publicKey))
+	}
+
+	// If no signature, we can't check; fail-fast.
+	signature := query.Get("signature")
+	if signature == "" {
+		return false, nil
+	}
+
+	// Signatures are base64url encoded; padding may or may not be included.
+	sig, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(signature, "="))
+	if err != nil {
+		return false, fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	// Make list of args for ordered sorting, ignoring the hash and signature keys.
+	args := make([]string, 0, len(query))
+	for x, y := range query {
+		if x == "hash" || x == "signature" {
+			continue
+		}
+		args = append(args, x+"="+y[0])
+	}
+
+	// Sort args to ensure consistency.
+	sort.Strings(args)
+
+	// The data is prefixed with the bot ID, and joined with newlines, as defined by telegram.
+	dataCheck := strconv.FormatInt(botId, 10) + ":WebAppData\n" + strings.Join(args, "\n")
+
+	return ed25519.Verify(publicKey, []byte(dataCheck), sig), nil
+}
+
 func validateQuery(query url.Values, secretKey []byte) (bool, error) {
 	// If no hash, we can't check; fail-fast.
 	hash := query.Get("hash")
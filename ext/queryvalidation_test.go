@@ -1,9 +1,16 @@
 package ext
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateLoginQuery(t *testing.T) {
@@ -124,3 +131,287 @@ func TestValidateWebApp(t *testing.T) {
 		}
 	})
 }
+
+func TestParseLoginQuery(t *testing.T) {
+	// Same known-good values as TestValidateLoginQuery.
+	query := url.Values{}
+	query.Set("id", "12345")
+	query.Set("first_name", "John")
+	query.Set("last_name", "Smith")
+	query.Set("username", "MrSmith")
+	query.Set("photo_url", "example.com")
+	query.Set("auth_date", "12345")
+	query.Set("hash", "67fbf533a5e28a9bf93e12ca06b706e91383b96f51b9486af229ddcbc07ea801")
+
+	t.Run("valid", func(t *testing.T) {
+		data, err := ParseLoginQuery(query, "test_token", 0)
+		if err != nil {
+			t.Fatalf("failed to parse login query: %v", err)
+		}
+		if data.Id != 12345 || data.FirstName != "John" || data.LastName != "Smith" || data.Username != "MrSmith" || data.PhotoUrl != "example.com" {
+			t.Errorf("unexpected login data: %+v", data)
+		}
+		if !data.AuthDate.Equal(time.Unix(12345, 0)) {
+			t.Errorf("unexpected auth date: %s", data.AuthDate)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParseLoginQuery(query, "invalid_token", 0)
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("ParseLoginQuery() with invalid values should return ErrInvalidHash, got %v", err)
+		}
+	})
+	t.Run("expired", func(t *testing.T) {
+		_, err := ParseLoginQuery(query, "test_token", time.Hour)
+		if !errors.Is(err, ErrAuthDateExpired) {
+			t.Errorf("ParseLoginQuery() with old auth_date should return ErrAuthDateExpired, got %v", err)
+		}
+	})
+}
+
+func TestParseWebAppInitData(t *testing.T) {
+	// Same known-good values as TestValidateWebApp.
+	testUser := `{"id":12345,"is_bot":false,"first_name":"John","last_name":"Smith","username":"MrSmith","language_code":"en-gb","photo_url":"example.com"}`
+	webAppQuery := url.Values{}
+	webAppQuery.Set("auth_date", "12345")
+	webAppQuery.Set("query_id", "12345")
+	webAppQuery.Set("user", testUser)
+	webAppQuery.Set("hash", "74e0df8d49d23ce7cb313f7baa2ee3fd96e51024aa398ad25ed6a90f91969746")
+
+	t.Run("valid", func(t *testing.T) {
+		data, err := ParseWebAppInitData(webAppQuery.Encode(), "test_token", 0)
+		if err != nil {
+			t.Fatalf("failed to parse webapp initData: %v", err)
+		}
+		if data.QueryId != "12345" {
+			t.Errorf("unexpected query ID: %s", data.QueryId)
+		}
+		if data.User == nil || data.User.Id != 12345 || data.User.Username != "MrSmith" || data.User.LanguageCode != "en-gb" {
+			t.Errorf("unexpected user: %+v", data.User)
+		}
+		if !data.AuthDate.Equal(time.Unix(12345, 0)) {
+			t.Errorf("unexpected auth date: %s", data.AuthDate)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParseWebAppInitData(webAppQuery.Encode(), "invalid_token", 0)
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("ParseWebAppInitData() with invalid values should return ErrInvalidHash, got %v", err)
+		}
+	})
+	t.Run("expired", func(t *testing.T) {
+		_, err := ParseWebAppInitData(webAppQuery.Encode(), "test_token", time.Hour)
+		if !errors.Is(err, ErrAuthDateExpired) {
+			t.Errorf("ParseWebAppInitData() with old auth_date should return ErrAuthDateExpired, got %v", err)
+		}
+	})
+	t.Run("fresh", func(t *testing.T) {
+		token := "test_token"
+		query := url.Values{}
+		query.Set("auth_date", strconv.FormatInt(time.Now().Unix(), 10))
+		query.Set("chat_type", "sender")
+		query.Set("start_param", "hello")
+		query.Set("user", testUser)
+		query.Set("hash", signWebAppQuery(t, query, token))
+
+		data, err := ParseWebAppInitData(query.Encode(), token, time.Hour)
+		if err != nil {
+			t.Fatalf("failed to parse fresh webapp initData: %v", err)
+		}
+		if data.ChatType != "sender" || data.StartParam != "hello" {
+			t.Errorf("unexpected initData contents: %+v", data)
+		}
+	})
+	t.Run("future", func(t *testing.T) {
+		token := "test_token"
+		for offset, expectedErr := range map[time.Duration]error{
+			// Small clock differences are allowed.
+			AuthDateClockSkew / 2: nil,
+			time.Hour:             ErrAuthDateInFuture,
+		} {
+			query := url.Values{}
+			query.Set("auth_date", strconv.FormatInt(time.Now().Add(offset).Unix(), 10))
+			query.Set("user", testUser)
+			query.Set("hash", signWebAppQuery(t, query, token))
+
+			_, err := ParseWebAppInitData(query.Encode(), token, time.Hour)
+			if !errors.Is(err, expectedErr) {
+				t.Errorf("ParseWebAppInitData() dated %s ahead should return %v, got %v", offset, expectedErr, err)
+			}
+		}
+	})
+}
+
+// signWebAppQuery generates the expected hash for a webapp query, to allow for generating fresh test data.
+func signWebAppQuery(t *testing.T, query url.Values, token string) string {
+	secretKey, err := generateHMAC256(token, []byte("WebAppData"))
+	if err != nil {
+		t.Fatalf("failed to generate secret key: %v", err)
+	}
+
+	args := make([]string, 0, len(query))
+	for k, v := range query {
+		args = append(args, k+"="+v[0])
+	}
+	sort.Strings(args)
+
+	hash, err := generateHMAC256(strings.Join(args, "\n"), secretKey)
+	if err != nil {
+		t.Fatalf("failed to generate hash: %v", err)
+	}
+	return string(getHex(hash))
+}
+
+func TestValidateWebAppThirdParty(t *testing.T) {
+	// Test vector signed with the Ed25519 key generated from the seed 0x00, 0x01, ..., 0x1f.
+	// This checks the signature logic with our own key, and makes sure that the telegram keys reject it; data signed by
+	// telegram is checked in TestValidateWebAppThirdPartyTelegramSigned.
+	const botId = 7342037359
+	publicKey, err := hex.DecodeString("03a107bff3ce10be1d70dd18e74bc09967e4d6309ba50d5f1ddc8664125531b8")
+	if err != nil {
+		t.Fatalf("failed to decode test public key: %v", err)
+	}
+
+	query := url.Values{}
+	query.Set("auth_date", "1733584787")
+	query.Set("chat_instance", "-4765953923906214720")
+	query.Set("chat_type", "sender")
+	query.Set("user", `{"id":279058397,"first_name":"Vladislav","last_name":"Kibenko","username":"vdkfrost","language_code":"ru","is_premium":true,"allows_write_to_pm":true}`)
+	query.Set("hash", "ignored")
+	query.Set("signature", "24g2_j1V_P0qAGHDl95loh2SqiRlRAFKKIy1mTabwl7Wt_phdalUdCFkBPl9EwzSTvMPW6Cm02pMxR-Qcpa_Cg")
+
+	t.Run("valid signature", func(t *testing.T) {
+		ok, err := validateQuerySignature(query, botId, publicKey)
+		if err != nil {
+			t.Fatalf("failed to validate signature: %v", err)
+		}
+		if !ok {
+			t.Errorf("validateQuerySignature() with valid values should be true")
+		}
+	})
+	t.Run("padded signature", func(t *testing.T) {
+		padded := cloneQuery(query)
+		padded.Set("signature", query.Get("signature")+"==")
+		ok, err := validateQuerySignature(padded, botId, publicKey)
+		if err != nil {
+			t.Fatalf("failed to validate signature: %v", err)
+		}
+		if !ok {
+			t.Errorf("validateQuerySignature() with padded signature should be true")
+		}
+	})
+	t.Run("wrong bot", func(t *testing.T) {
+		ok, err := validateQuerySignature(query, botId+1, publicKey)
+		if err != nil {
+			t.Fatalf("failed to validate signature: %v", err)
+		}
+		if ok {
+			t.Errorf("validateQuerySignature() with the wrong bot ID should be false")
+		}
+	})
+	t.Run("tampered data", func(t *testing.T) {
+		tampered := cloneQuery(query)
+		tampered.Set("chat_type", "private")
+		ok, err := validateQuerySignature(tampered, botId, publicKey)
+		if err != nil {
+			t.Fatalf("failed to validate signature: %v", err)
+		}
+		if ok {
+			t.Errorf("validateQuerySignature() with tampered data should be false")
+		}
+	})
+	t.Run("no signature", func(t *testing.T) {
+		unsigned := cloneQuery(query)
+		unsigned.Del("signature")
+		ok, err := ValidateWebAppQueryThirdParty(unsigned, botId, ProductionEnvironment)
+		if err != nil {
+			t.Fatalf("failed to validate signature: %v", err)
+		}
+		if ok {
+			t.Errorf("ValidateWebAppQueryThirdParty() with no signature should be false")
+		}
+	})
+	for _, env := range []TelegramEnvironment{ProductionEnvironment, TestEnvironment} {
+		t.Run(fmt.Sprintf("not signed by telegram env %d", env), func(t *testing.T) {
+			ok, err := ValidateWebAppInitDataThirdParty(query.Encode(), botId, env)
+			if err != nil {
+				t.Fatalf("failed to validate signature: %v", err)
+			}
+			if ok {
+				t.Errorf("ValidateWebAppInitDataThirdParty() should reject data not signed by telegram")
+			}
+
+			_, err = ParseWebAppInitDataThirdParty(query.Encode(), botId, env, 0)
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("ParseWebAppInitDataThirdParty() should return ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateWebAppThirdPartyTelegramSigned(t *testing.T) {
+	// The initData example from telegram's documentation, signed by telegram's production key.
+	// See https://core.telegram.org/bots/webapps#validating-data-for-third-party-use for more details.
+	const botId = 7342037359
+	const initData = "auth_date=1733584787&chat_instance=8134722200314281151&chat_type=private" +
+		"&signature=zL-ucjNyREiHDE8aihFwpfR9aggP2xiAo3NSpfe-p7IbCisNlDKlo7Kb6G4D0Ao2mBrSgEk4maLSdv6MLIlADQ" +
+		"&user=%7B%22id%22%3A279058397%2C%22first_name%22%3A%22Vladislav+%2B+-+%3F+%5C%2F%22%2C%22last_name%22%3A%22Kibenko%22" +
+		"%2C%22username%22%3A%22vdkfrost%22%2C%22language_code%22%3A%22ru%22%2C%22is_premium%22%3Atrue" +
+		"%2C%22allows_write_to_pm%22%3Atrue%2C%22photo_url%22%3A%22https%3A%5C%2F%5C%2Ft.me%5C%2Fi%5C%2Fuserpic%5C%2F320" +
+		"%5C%2F4FPEE4tmP3ATHa57u6MqTDih13LTOiMoKoLDRG4PnSA.svg%22%7D"
+
+	ok, err := ValidateWebAppInitDataThirdParty(initData, botId, ProductionEnvironment)
+	if err != nil {
+		t.Fatalf("failed to validate signature: %v", err)
+	}
+	if !ok {
+		t.Errorf("ValidateWebAppInitDataThirdParty() should accept data signed by telegram")
+	}
+
+	query, err := url.ParseQuery(initData)
+	if err != nil {
+		t.Fatalf("failed to parse initData: %v", err)
+	}
+	// The hash is ignored, and may be sent alongside the signature.
+	query.Set("hash", "2174df5b000556d044f3f020384e879c8efcab55ddea2ced4eb752e93e7080d6")
+	ok, err = ValidateWebAppQueryThirdParty(query, botId, ProductionEnvironment)
+	if err != nil {
+		t.Fatalf("failed to validate signature: %v", err)
+	}
+	if !ok {
+		t.Errorf("ValidateWebAppQueryThirdParty() should accept data signed by telegram")
+	}
+
+	data, err := ParseWebAppInitDataThirdParty(initData, botId, ProductionEnvironment, 0)
+	if err != nil {
+		t.Fatalf("failed to parse initData: %v", err)
+	}
+	if data.User == nil || data.User.Id != 279058397 || data.User.FirstName != `Vladislav + - ? /` || data.ChatType != "private" {
+		t.Errorf("unexpected initData contents: %+v", data)
+	}
+
+	for name, check := range map[string]func() (bool, error){
+		"test environment": func() (bool, error) {
+			return ValidateWebAppInitDataThirdParty(initData, botId, TestEnvironment)
+		},
+		"wrong bot": func() (bool, error) {
+			return ValidateWebAppInitDataThirdParty(initData, botId+1, ProductionEnvironment)
+		},
+	} {
+		ok, err := check()
+		if err != nil {
+			t.Fatalf("%s: failed to validate signature: %v", name, err)
+		}
+		if ok {
+			t.Errorf("%s: signature should be rejected", name)
+		}
+	}
+}
+
+func cloneQuery(query url.Values) url.Values {
+	out := url.Values{}
+	for k, v := range query {
+		out[k] = append([]string{}, v...)
+	}
+	return out
+}
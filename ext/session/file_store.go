@@ -0,0 +1,130 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FileStore stores each session in its own file, in a directory on disk. The files contain the session version on
+// the first line, followed by the session data.
+// Writes are atomic; each file is written to a temporary file, which is then renamed.
+//
+// Versions are only checked within the current process, so a directory should not be shared by multiple processes.
+type FileStore struct {
+	// Dir is the directory to store the session files in. It is created if it doesn't exist.
+	Dir string
+
+	// lock allows us to ensure synchronous file access.
+	lock sync.Mutex
+}
+
+// NewFileStore creates a new FileStore, storing sessions in the given directory.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{
+		Dir: dir,
+	}
+}
+
+func (s *FileStore) Load(key string) ([]byte, int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.read(key)
+}
+
+func (s *FileStore) Save(key string, data []byte, version int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.checkVersion(key, version); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	path := s.path(key)
+	tmp, err := os.CreateTemp(s.Dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary session file: %w", err)
+	}
+	// Clean up the temporary file if anything goes wrong; this is a noop once it has been renamed.
+	defer os.Remove(tmp.Name())
+
+	contents := append([]byte(strconv.FormatInt(version+1, 10)+"\n"), data...)
+	if _, err = tmp.Write(contents); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync session file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close session file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace session file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(key string, version int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.checkVersion(key, version); err != nil {
+		return err
+	}
+
+	err := os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove session file: %w", err)
+	}
+	return nil
+}
+
+// checkVersion checks that the stored version of the session matches the given version. The lock must be held.
+func (s *FileStore) checkVersion(key string, version int64) error {
+	_, current, err := s.read(key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if current != version {
+		return ErrConflict
+	}
+	return nil
+}
+
+// read reads the session file of the given key. The lock must be held.
+func (s *FileStore) read(key string) ([]byte, int64, error) {
+	bs, err := os.ReadFile(s.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, 0, ErrNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to read session file: %w", err)
+	}
+
+	versionLine, data, _ := bytes.Cut(bs, []byte("\n"))
+	version, err := strconv.ParseInt(string(versionLine), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid session file version: %w", err)
+	}
+	return data, version, nil
+}
+
+// path returns the path of the session file of the given key. Colons are replaced, since they aren't valid in file
+// names on all platforms.
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.Dir, strings.ReplaceAll(key, ":", "_")+".session")
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+var _ ext.Processor = Processor{}
+
+// Processor is an ext.Processor which makes sessions available to handlers, and saves them once the update has been
+// processed.
+//
+// If the wrapped processor returns an error, such as when processing is aborted, the sessions are not saved.
+// If saving fails, the error is returned to the dispatcher; in particular, ErrConflict is returned when a session was
+// modified by another update since it was loaded. In that case, the changes made by this update are not saved.
+type Processor struct {
+	// Store is where sessions are loaded from and saved to.
+	Store Store
+	// Processor is the wrapped processor, which processes the update once sessions are available.
+	// If nil, ext.BaseProcessor is used.
+	Processor ext.Processor
+}
+
+// ProcessorOpts represents the optional values to create a session Processor.
+type ProcessorOpts struct {
+	// Processor is the wrapped processor. Defaults to ext.BaseProcessor.
+	Processor ext.Processor
+}
+
+// NewProcessor creates a new session Processor, using the given store.
+func NewProcessor(store Store, opts *ProcessorOpts) Processor {
+	p := Processor{
+		Store: store,
+	}
+	if opts != nil {
+		p.Processor = opts.Processor
+	}
+	return p
+}
+
+// ProcessUpdate processes the update with the wrapped processor, and then saves any modified sessions.
+func (p Processor) ProcessUpdate(d *ext.Dispatcher, b *gotgbot.Bot, ctx *ext.Context) error {
+	s := &sessions{
+		store: p.Store,
+		bot:   b,
+		ctx:   ctx,
+	}
+	ctx.Data[dataKey] = s
+
+	processor := p.Processor
+	if processor == nil {
+		processor = ext.BaseProcessor{}
+	}
+
+	err := processor.ProcessUpdate(d, b, ctx)
+	if err != nil {
+		return err
+	}
+	return s.save()
+}
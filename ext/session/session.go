@@ -0,0 +1,275 @@
+// Package session provides per-user, per-chat, and per-user-in-chat session storage for handlers.
+//
+// Sessions are loaded lazily from a Store the first time a handler accesses them, through the typed User, Chat and
+// UserInChat accessors, and saved automatically once the update has been processed. To enable sessions, set the
+// dispatcher's Processor to a session Processor:
+//
+//	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{
+//		Processor: session.NewProcessor(session.NewInMemoryStore(), nil),
+//	})
+//
+// Sessions are versioned, to avoid lost updates when the same session is modified by concurrent updates; if a session
+// was modified since it was loaded, saving it fails with ErrConflict, rather than overwriting the other changes.
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+var (
+	ErrNotFound     = errors.New("session not found")
+	ErrConflict     = errors.New("session was modified concurrently")
+	ErrNoProcessor  = errors.New("sessions are not enabled; the dispatcher should use a session Processor")
+	ErrNoUser       = errors.New("update has no effective user")
+	ErrNoChat       = errors.New("update has no effective chat")
+	ErrTypeMismatch = errors.New("session was already loaded with a different type")
+)
+
+// Scope defines who a session belongs to.
+type Scope int
+
+const (
+	// ScopeUser gives a session to each user, shared across all chats.
+	ScopeUser Scope = iota
+	// ScopeChat gives a session to each chat, shared by all users in that chat.
+	ScopeChat
+	// ScopeUserInChat gives a unique session to each user in each chat.
+	ScopeUserInChat
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeUser:
+		return "user"
+	case ScopeChat:
+		return "chat"
+	case ScopeUserInChat:
+		return "member"
+	default:
+		return fmt.Sprintf("Scope(%d)", int(s))
+	}
+}
+
+// dataKey is the ext.Context Data key under which the update's sessions are stored.
+const dataKey = "gotgbot_sessions"
+
+// Key returns the storage key of the session for the current update, in the given scope. Keys are only made of
+// letters, digits, '-' and ':'; eg, "123456:member:-100123:42", for a bot with ID 123456, a chat with ID -100123 and a
+// user with ID 42.
+func Key(b *gotgbot.Bot, ctx *ext.Context, scope Scope) (string, error) {
+	botId := strings.Split(b.Token, ":")[0]
+
+	switch scope {
+	case ScopeUser:
+		if ctx.EffectiveUser == nil {
+			return "", ErrNoUser
+		}
+		return fmt.Sprintf("%s:user:%d", botId, ctx.EffectiveUser.Id), nil
+	case ScopeChat:
+		if ctx.EffectiveChat == nil {
+			return "", ErrNoChat
+		}
+		return fmt.Sprintf("%s:chat:%d", botId, ctx.EffectiveChat.Id), nil
+	case ScopeUserInChat:
+		if ctx.EffectiveUser == nil {
+			return "", ErrNoUser
+		}
+		if ctx.EffectiveChat == nil {
+			return "", ErrNoChat
+		}
+		return fmt.Sprintf("%s:member:%d:%d", botId, ctx.EffectiveChat.Id, ctx.EffectiveUser.Id), nil
+	default:
+		return "", fmt.Errorf("unknown session scope %s", scope)
+	}
+}
+
+// User returns the session of the current user. Changes made to the returned value are saved once the update has been
+// processed.
+func User[T any](ctx *ext.Context) (*T, error) {
+	return Get[T](ctx, ScopeUser)
+}
+
+// Chat returns the session of the current chat. Changes made to the returned value are saved once the update has been
+// processed.
+func Chat[T any](ctx *ext.Context) (*T, error) {
+	return Get[T](ctx, ScopeChat)
+}
+
+// UserInChat returns the session of the current user in the current chat. Changes made to the returned value are
+// saved once the update has been processed.
+func UserInChat[T any](ctx *ext.Context) (*T, error) {
+	return Get[T](ctx, ScopeUserInChat)
+}
+
+// Get returns the session of the given scope, loading it from the store if it hasn't been loaded yet by this update.
+// Sessions which don't exist yet are returned as the zero value of T.
+//
+// Sessions are stored as JSON, so only exported fields are persisted. A session must always be accessed with the same
+// type during an update; otherwise, ErrTypeMismatch is returned.
+func Get[T any](ctx *ext.Context, scope Scope) (*T, error) {
+	s, err := getSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ls, err := s.load(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if ls.value != nil {
+		v, ok := ls.value.(*T)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s session is a %T, not a %T", ErrTypeMismatch, scope, ls.value, v)
+		}
+		return v, nil
+	}
+
+	v := new(T)
+	if len(ls.data) > 0 && !ls.deleted {
+		if err := json.Unmarshal(ls.data, v); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s session: %w", scope, err)
+		}
+	}
+	// Keep track of the initial value, to only save sessions which were modified.
+	ls.initial, err = json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s session: %w", scope, err)
+	}
+	ls.value = v
+	return v, nil
+}
+
+// Delete deletes the session of the given scope once the update has been processed. Accessing the session again
+// during the same update returns a new, empty, session.
+func Delete(ctx *ext.Context, scope Scope) error {
+	s, err := getSessions(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ls, err := s.load(scope)
+	if err != nil {
+		return err
+	}
+	ls.value = nil
+	ls.deleted = true
+	return nil
+}
+
+func getSessions(ctx *ext.Context) (*sessions, error) {
+	s, ok := ctx.Data[dataKey].(*sessions)
+	if !ok {
+		return nil, ErrNoProcessor
+	}
+	return s, nil
+}
+
+// sessions holds the sessions loaded during a single update.
+type sessions struct {
+	store Store
+	bot   *gotgbot.Bot
+	ctx   *ext.Context
+
+	// lock allows us to ensure synchronous access to the loaded sessions.
+	lock sync.Mutex
+	// loaded holds the sessions loaded so far, by scope.
+	loaded map[Scope]*loadedSession
+}
+
+// loadedSession is a session loaded from the store.
+type loadedSession struct {
+	key string
+	// version is the stored version of the session; 0 if it didn't exist.
+	version int64
+	// data is the stored session data.
+	data []byte
+	// value is the session value returned to handlers, which is saved once the update has been processed.
+	value interface{}
+	// initial is the marshalled value, as it was when first returned to handlers.
+	initial []byte
+	// deleted marks the session for deletion. Sessions accessed again after being deleted start out empty, and are
+	// only stored if modified.
+	deleted bool
+}
+
+// load returns the session of the given scope, loading it from the store if needed. The lock must be held.
+func (s *sessions) load(scope Scope) (*loadedSession, error) {
+	if ls, ok := s.loaded[scope]; ok {
+		return ls, nil
+	}
+
+	key, err := Key(s.bot, s.ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	ls := &loadedSession{key: key}
+	ls.data, ls.version, err = s.store.Load(key)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("failed to load %s session: %w", scope, err)
+		}
+		// New session.
+		ls.data, ls.version = nil, 0
+	}
+
+	if s.loaded == nil {
+		s.loaded = map[Scope]*loadedSession{}
+	}
+	s.loaded[scope] = ls
+	return ls, nil
+}
+
+// save saves all the loaded sessions which were modified or deleted. All sessions are saved, even if some fail; the
+// first error is returned.
+func (s *sessions) save() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var firstErr error
+	for _, scope := range []Scope{ScopeUser, ScopeChat, ScopeUserInChat} {
+		ls, ok := s.loaded[scope]
+		if !ok {
+			continue
+		}
+
+		err := ls.save(s.store)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to save %s session: %w", scope, err)
+		}
+	}
+	return firstErr
+}
+
+func (ls *loadedSession) save(store Store) error {
+	if ls.value != nil {
+		bs, err := json.Marshal(ls.value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal session: %w", err)
+		}
+		if !bytes.Equal(bs, ls.initial) {
+			return store.Save(ls.key, bs, ls.version)
+		}
+	}
+
+	if ls.deleted && ls.version != 0 {
+		return store.Delete(ls.key, ls.version)
+	}
+	// Unchanged; nothing to save.
+	return nil
+}
@@ -0,0 +1,177 @@
+package session_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/session"
+	"github.com/PaulSonOfLars/gotgbot/v2/gotgbottest"
+)
+
+type counter struct {
+	Count int
+}
+
+func TestSessions(t *testing.T) {
+	store := session.NewInMemoryStore()
+	d := ext.NewDispatcher(&ext.DispatcherOpts{
+		Processor: session.NewProcessor(store, nil),
+	})
+	d.AddHandler(handlers.NewCommand("count", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		for _, get := range []func(*ext.Context) (*counter, error){
+			session.User[counter], session.Chat[counter], session.UserInChat[counter],
+		} {
+			c, err := get(ctx)
+			if err != nil {
+				return err
+			}
+			c.Count++
+		}
+		return nil
+	}))
+	d.AddHandler(handlers.NewCommand("reset", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		return session.Delete(ctx, session.ScopeUser)
+	}))
+	d.AddHandler(handlers.NewCommand("peek", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		_, err := session.User[counter](ctx)
+		return err
+	}))
+
+	b := gotgbottest.NewClient().Bot()
+	process := func(userId int64, chatId int64, text string) {
+		t.Helper()
+		upd := gotgbottest.TextMessageUpdate(userId, chatId, text)
+		if err := d.ProcessUpdate(b, &upd, nil); err != nil {
+			t.Fatalf("failed to process %s: %v", text, err)
+		}
+	}
+	count := func(key string) int {
+		t.Helper()
+		_, version, err := store.Load("123456789:" + key)
+		if errors.Is(err, session.ErrNotFound) {
+			return 0
+		}
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", key, err)
+		}
+		return int(version)
+	}
+
+	process(1, -100, "/count")
+	process(1, -100, "/count")
+	process(2, -100, "/count")
+	process(1, -200, "/count")
+
+	for key, expected := range map[string]int{
+		"user:1":         3,
+		"user:2":         1,
+		"chat:-100":      3,
+		"chat:-200":      1,
+		"member:-100:1":  2,
+		"member:-100:2":  1,
+		"member:-200:1":  1,
+		"member:-200:2":  0,
+		"user:3":         0,
+		"chat:-300":      0,
+		"member:-300:-3": 0,
+	} {
+		// Each count increments the stored version.
+		if v := count(key); v != expected {
+			t.Errorf("expected %s to be saved %d times, got %d", key, expected, v)
+		}
+	}
+
+	data, _, err := store.Load("123456789:user:1")
+	if err != nil || string(data) != `{"Count":3}` {
+		t.Errorf("unexpected stored session %s: %v", data, err)
+	}
+
+	// Unmodified sessions aren't saved, and missing sessions aren't created.
+	process(1, -100, "/peek")
+	process(3, -100, "/peek")
+	if v := count("user:1"); v != 3 {
+		t.Errorf("expected unmodified session not to be saved, got version %d", v)
+	}
+	if v := count("user:3"); v != 0 {
+		t.Errorf("expected empty session not to be created, got version %d", v)
+	}
+
+	process(1, -100, "/reset")
+	if v := count("user:1"); v != 0 {
+		t.Errorf("expected session to be deleted, got version %d", v)
+	}
+}
+
+func TestSessionConflict(t *testing.T) {
+	store := session.NewInMemoryStore()
+	d := ext.NewDispatcher(&ext.DispatcherOpts{
+		Processor: session.NewProcessor(store, nil),
+	})
+
+	// Simulate a concurrent update, which saves the session while this one is being processed.
+	d.AddHandler(handlers.NewCommand("race", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		c, err := session.User[counter](ctx)
+		if err != nil {
+			return err
+		}
+		c.Count++
+		return store.Save("123456789:user:"+strconv.FormatInt(ctx.EffectiveUser.Id, 10), []byte(`{"Count":10}`), 0)
+	}))
+
+	b := gotgbottest.NewClient().Bot()
+	upd := gotgbottest.TextMessageUpdate(1, 1, "/race")
+	err := d.ProcessUpdate(b, &upd, nil)
+	if !errors.Is(err, session.ErrConflict) {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+
+	data, version, _ := store.Load("123456789:user:1")
+	if string(data) != `{"Count":10}` || version != 1 {
+		t.Errorf("expected concurrent update to be kept, got %s at version %d", data, version)
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	b := gotgbottest.NewClient().Bot()
+
+	if _, err := session.User[counter](gotgbottest.NewTextMessage(1, 1, "hi")); !errors.Is(err, session.ErrNoProcessor) {
+		t.Errorf("expected ErrNoProcessor without a session processor, got %v", err)
+	}
+
+	var errs []error
+	d := ext.NewDispatcher(&ext.DispatcherOpts{
+		Processor: session.NewProcessor(session.NewInMemoryStore(), nil),
+	})
+	d.AddHandler(handlers.NewCommand("types", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		if _, err := session.User[counter](ctx); err != nil {
+			return err
+		}
+		_, err := session.User[map[string]string](ctx)
+		errs = append(errs, err)
+		return nil
+	}))
+	d.AddHandler(handlers.NewPoll(nil, func(b *gotgbot.Bot, ctx *ext.Context) error {
+		_, err := session.Chat[counter](ctx)
+		errs = append(errs, err)
+		_, err = session.User[counter](ctx)
+		errs = append(errs, err)
+		return nil
+	}))
+
+	upd := gotgbottest.TextMessageUpdate(1, 1, "/types")
+	if err := d.ProcessUpdate(b, &upd, nil); err != nil {
+		t.Fatalf("failed to process update: %v", err)
+	}
+	if err := d.ProcessUpdate(b, &gotgbot.Update{Poll: &gotgbot.Poll{Id: "1"}}, nil); err != nil {
+		t.Fatalf("failed to process update: %v", err)
+	}
+
+	if len(errs) != 3 || !errors.Is(errs[0], session.ErrTypeMismatch) || !errors.Is(errs[1], session.ErrNoChat) ||
+		!errors.Is(errs[2], session.ErrNoUser) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
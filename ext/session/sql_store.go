@@ -0,0 +1,144 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// DefaultSQLTable is the default name of the table used by the SQLStore.
+const DefaultSQLTable = "gotgbot_sessions"
+
+// SQLStore stores sessions in an SQL database, using the standard library's database/sql package; any driver can be
+// used. Sessions are stored in a table with the following columns, which can be created with CreateTable:
+//   - session_key: the session key, as the primary key.
+//   - data: the session data.
+//   - version: the session version.
+type SQLStore struct {
+	// DB is the database to store sessions in.
+	DB *sql.DB
+	// Table is the name of the table to store sessions in.
+	Table string
+	// Placeholder returns the query placeholder for the nth argument, starting at 1.
+	Placeholder func(n int) string
+}
+
+// SQLStoreOpts represents the optional values to create an SQLStore.
+type SQLStoreOpts struct {
+	// Table is the name of the table to store sessions in. Defaults to DefaultSQLTable.
+	Table string
+	// Placeholder returns the query placeholder for the nth argument, starting at 1. Defaults to QuestionPlaceholder,
+	// as used by MySQL and SQLite; PostgreSQL drivers should use DollarPlaceholder.
+	Placeholder func(n int) string
+}
+
+// QuestionPlaceholder returns "?" placeholders, as used by MySQL and SQLite.
+func QuestionPlaceholder(int) string {
+	return "?"
+}
+
+// DollarPlaceholder returns "$n" placeholders, as used by PostgreSQL.
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// NewSQLStore creates a new SQLStore, storing sessions in the given database.
+func NewSQLStore(db *sql.DB, opts *SQLStoreOpts) *SQLStore {
+	table := DefaultSQLTable
+	placeholder := QuestionPlaceholder
+
+	if opts != nil {
+		if opts.Table != "" {
+			table = opts.Table
+		}
+		if opts.Placeholder != nil {
+			placeholder = opts.Placeholder
+		}
+	}
+
+	return &SQLStore{
+		DB:          db,
+		Table:       table,
+		Placeholder: placeholder,
+	}
+}
+
+// CreateTable creates the session table, if it doesn't exist yet.
+func (s *SQLStore) CreateTable() error {
+	_, err := s.DB.Exec(`CREATE TABLE IF NOT EXISTS ` + s.Table + ` (
+	session_key VARCHAR(255) NOT NULL PRIMARY KEY,
+	data TEXT NOT NULL,
+	version BIGINT NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create session table: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) Load(key string) ([]byte, int64, error) {
+	var data string
+	var version int64
+
+	err := s.DB.QueryRow(
+		`SELECT data, version FROM `+s.Table+` WHERE session_key = `+s.Placeholder(1),
+		key,
+	).Scan(&data, &version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, ErrNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to load session: %w", err)
+	}
+	return []byte(data), version, nil
+}
+
+func (s *SQLStore) Save(key string, data []byte, version int64) error {
+	if version == 0 {
+		_, err := s.DB.Exec(
+			`INSERT INTO `+s.Table+` (session_key, data, version) VALUES (`+s.Placeholder(1)+`, `+s.Placeholder(2)+`, `+s.Placeholder(3)+`)`,
+			key, string(data), 1,
+		)
+		if err != nil {
+			// Drivers report primary key violations differently; check whether the session was created concurrently.
+			if _, _, loadErr := s.Load(key); loadErr == nil {
+				return ErrConflict
+			}
+			return fmt.Errorf("failed to insert session: %w", err)
+		}
+		return nil
+	}
+
+	res, err := s.DB.Exec(
+		`UPDATE `+s.Table+` SET data = `+s.Placeholder(1)+`, version = `+s.Placeholder(2)+` WHERE session_key = `+s.Placeholder(3)+` AND version = `+s.Placeholder(4),
+		string(data), version+1, key, version,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return checkAffected(res)
+}
+
+func (s *SQLStore) Delete(key string, version int64) error {
+	res, err := s.DB.Exec(
+		`DELETE FROM `+s.Table+` WHERE session_key = `+s.Placeholder(1)+` AND version = `+s.Placeholder(2),
+		key, version,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// checkAffected returns ErrConflict if no rows were affected by a versioned query.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package session
+
+import (
+	"sync"
+)
+
+// Store allows for defining custom backends to persist sessions in. Sessions are stored as opaque data, along with a
+// version which is incremented on every save.
+//
+// Implementations must check the version atomically with the write, so that concurrent updates to the same session
+// can't overwrite each other.
+type Store interface {
+	// Load returns the data and version of the session stored at the given key.
+	// If no session is stored, it should return the ErrNotFound error.
+	Load(key string) (data []byte, version int64, err error)
+
+	// Save stores the session data at the given key, if the currently stored version matches the given version; the
+	// stored version is then incremented. A version of 0 means that no session should be stored yet.
+	// If the versions don't match, it should return the ErrConflict error.
+	Save(key string, data []byte, version int64) error
+
+	// Delete removes the session stored at the given key, if the currently stored version matches the given version.
+	// If the versions don't match, it should return the ErrConflict error.
+	Delete(key string, version int64) error
+}
+
+var (
+	_ Store = &InMemoryStore{}
+	_ Store = &FileStore{}
+	_ Store = &SQLStore{}
+)
+
+// InMemoryStore is a thread-safe in-memory implementation of the Store interface.
+// It does not persist sessions across restarts.
+type InMemoryStore struct {
+	// sessions maps session keys to the stored sessions.
+	sessions map[string]storedSession
+	// lock allows us to ensure synchronous data access.
+	lock sync.RWMutex
+}
+
+type storedSession struct {
+	data    []byte
+	version int64
+}
+
+// NewInMemoryStore creates a new, empty, InMemoryStore.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		sessions: map[string]storedSession{},
+	}
+}
+
+func (s *InMemoryStore) Load(key string) ([]byte, int64, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	sess, ok := s.sessions[key]
+	if !ok {
+		return nil, 0, ErrNotFound
+	}
+	return append([]byte(nil), sess.data...), sess.version, nil
+}
+
+func (s *InMemoryStore) Save(key string, data []byte, version int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.sessions == nil {
+		s.sessions = map[string]storedSession{}
+	}
+
+	if s.sessions[key].version != version {
+		return ErrConflict
+	}
+
+	s.sessions[key] = storedSession{
+		data:    append([]byte(nil), data...),
+		version: version + 1,
+	}
+	return nil
+}
+
+func (s *InMemoryStore) Delete(key string, version int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.sessions[key].version != version {
+		return ErrConflict
+	}
+
+	delete(s.sessions, key)
+	return nil
+}
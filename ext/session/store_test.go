@@ -0,0 +1,193 @@
+package session_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/session"
+)
+
+func TestStores(t *testing.T) {
+	db := sql.OpenDB(&fakeSQLConnector{})
+	defer db.Close()
+	sqlStore := session.NewSQLStore(db, nil)
+	if err := sqlStore.CreateTable(); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	for name, store := range map[string]session.Store{
+		"memory": session.NewInMemoryStore(),
+		"file":   session.NewFileStore(t.TempDir() + "/sessions"),
+		"sql":    sqlStore,
+	} {
+		t.Run(name, func(t *testing.T) {
+			const key = "123:member:-100:1"
+
+			if _, _, err := store.Load(key); !errors.Is(err, session.ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+			if err := store.Save(key, []byte(`{"a":1}`), 1); !errors.Is(err, session.ErrConflict) {
+				t.Errorf("expected conflict when saving unknown version, got %v", err)
+			}
+
+			if err := store.Save(key, []byte(`{"a":1}`), 0); err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+			if err := store.Save(key, []byte(`{"a":2}`), 0); !errors.Is(err, session.ErrConflict) {
+				t.Errorf("expected conflict when creating existing session, got %v", err)
+			}
+
+			data, version, err := store.Load(key)
+			if err != nil || string(data) != `{"a":1}` || version != 1 {
+				t.Fatalf("unexpected session %q, version %d, err %v", data, version, err)
+			}
+
+			if err := store.Save(key, []byte(`{"a":3}`), 1); err != nil {
+				t.Fatalf("failed to update session: %v", err)
+			}
+			if err := store.Save(key, []byte(`{"a":4}`), 1); !errors.Is(err, session.ErrConflict) {
+				t.Errorf("expected conflict when saving stale version, got %v", err)
+			}
+			if err := store.Delete(key, 1); !errors.Is(err, session.ErrConflict) {
+				t.Errorf("expected conflict when deleting stale version, got %v", err)
+			}
+
+			data, version, err = store.Load(key)
+			if err != nil || string(data) != `{"a":3}` || version != 2 {
+				t.Fatalf("unexpected session %q, version %d, err %v", data, version, err)
+			}
+
+			if err := store.Delete(key, 2); err != nil {
+				t.Fatalf("failed to delete session: %v", err)
+			}
+			if _, _, err := store.Load(key); !errors.Is(err, session.ErrNotFound) {
+				t.Errorf("expected ErrNotFound after delete, got %v", err)
+			}
+		})
+	}
+}
+
+// fakeSQLConnector is a minimal in-memory database/sql driver, which only supports the queries made by the SQLStore.
+type fakeSQLConnector struct {
+	lock sync.Mutex
+	rows map[string]fakeSQLRow
+}
+
+type fakeSQLRow struct {
+	data    string
+	version int64
+}
+
+func (c *fakeSQLConnector) Connect(context.Context) (driver.Conn, error) {
+	return fakeSQLConn{c}, nil
+}
+
+func (c *fakeSQLConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeSQLConn struct {
+	db *fakeSQLConnector
+}
+
+func (c fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeSQLStmt{db: c.db, query: query}, nil
+}
+
+func (c fakeSQLConn) Close() error {
+	return nil
+}
+
+func (c fakeSQLConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type fakeSQLStmt struct {
+	db    *fakeSQLConnector
+	query string
+}
+
+func (s fakeSQLStmt) Close() error {
+	return nil
+}
+
+func (s fakeSQLStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.lock.Lock()
+	defer s.db.lock.Unlock()
+
+	if s.db.rows == nil {
+		s.db.rows = map[string]fakeSQLRow{}
+	}
+
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(s.query, "INSERT"):
+		key := args[0].(string)
+		if _, ok := s.db.rows[key]; ok {
+			return nil, errors.New("UNIQUE constraint failed")
+		}
+		s.db.rows[key] = fakeSQLRow{data: args[1].(string), version: args[2].(int64)}
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "UPDATE"):
+		key := args[2].(string)
+		if row, ok := s.db.rows[key]; !ok || row.version != args[3].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		s.db.rows[key] = fakeSQLRow{data: args[0].(string), version: args[1].(int64)}
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "DELETE"):
+		key := args[0].(string)
+		if row, ok := s.db.rows[key]; !ok || row.version != args[1].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		delete(s.db.rows, key)
+		return driver.RowsAffected(1), nil
+	}
+	return nil, errors.New("unsupported query: " + s.query)
+}
+
+func (s fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.lock.Lock()
+	defer s.db.lock.Unlock()
+
+	if !strings.HasPrefix(s.query, "SELECT") {
+		return nil, errors.New("unsupported query: " + s.query)
+	}
+	row, ok := s.db.rows[args[0].(string)]
+	if !ok {
+		return &fakeSQLRows{}, nil
+	}
+	return &fakeSQLRows{rows: [][]driver.Value{{row.data, row.version}}}, nil
+}
+
+type fakeSQLRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeSQLRows) Columns() []string {
+	return []string{"data", "version"}
+}
+
+func (r *fakeSQLRows) Close() error {
+	return nil
+}
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
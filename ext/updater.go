@@ -8,8 +8,10 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
@@ -24,6 +26,35 @@ var (
 
 type ErrorFunc func(error)
 
+// ErrPollingConflict is matched (via errors.Is) by the PollingConflictError returned when getUpdates fails due to a
+// conflict.
+var ErrPollingConflict = errors.New("polling conflict")
+
+// PollingConflictError is the error reported when getUpdates fails with a 409 Conflict error. This happens when
+// another instance is polling for updates with the same token ("terminated by other getUpdates request"), or when a
+// webhook is set.
+type PollingConflictError struct {
+	// Err is the underlying error returned by telegram.
+	Err *gotgbot.TelegramError
+}
+
+func (e *PollingConflictError) Error() string {
+	return "polling conflict: " + e.Err.Error()
+}
+
+func (e *PollingConflictError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PollingConflictError) Is(target error) bool {
+	return target == ErrPollingConflict
+}
+
+// WebhookActive returns true if the conflict is caused by a webhook being set, rather than another instance polling.
+func (e *PollingConflictError) WebhookActive() bool {
+	return strings.Contains(e.Err.Description, "webhook")
+}
+
 type Updater struct {
 	// Dispatcher is where all the incoming updates are sent to be processed.
 	// The Dispatcher runs in a separate goroutine, allowing for parallel update processing and dispatching.
@@ -107,8 +138,51 @@ type PollingOpts struct {
 	//    long-polling, Telegram responds to your request as soon as new messages are available.
 	//    When setting this, it is recommended you set your PollingOpts.Timeout value to be slightly bigger (eg, +1).
 	GetUpdatesOpts *gotgbot.GetUpdatesOpts
+	// OffsetStore allows for persisting the polling offset across restarts.
+	// If set, the starting offset is loaded from the store (unless GetUpdatesOpts.Offset is set), and the next offset
+	// is saved once every update in a batch has been processed by the Dispatcher; the next batch is only fetched after
+	// that. Delivery is at-least-once: updates which were still being handled when the bot stopped are fetched again
+	// after a restart, so handlers should tolerate duplicates.
+	// This requires the Dispatcher to implement TrackingUpdateDispatcher; otherwise, offsets are saved as soon as
+	// the updates have been handed over to the Dispatcher.
+	OffsetStore OffsetStore
+	// Backoff defines how long to wait after a failed getUpdates call, based on the number of consecutive failures.
+	// If nil, an exponential backoff starting at 1s, and capped at 1 minute, is used.
+	// If the error contains a retry_after value (flood wait), that value is respected instead, if longer.
+	// If the bot token is no longer authorized (eg, revoked), polling is stopped entirely.
+	Backoff BackoffPolicy
+	// ConflictBackoff defines how long to wait after a getUpdates call fails with a 409 Conflict error. This happens
+	// when another instance is polling with the same token, or if a webhook is set.
+	// If nil, an exponential backoff starting at 5s, and capped at 1 minute, is used.
+	ConflictBackoff BackoffPolicy
+	// OnConflict is called whenever getUpdates fails with a 409 Conflict error, before the ConflictBackoff delay.
+	// Returning false stops polling for this bot, which can be useful to stop replicas which should not be polling.
+	// The error is also passed to the Updater's UnhandledErrFunc, as a *PollingConflictError, including when polling
+	// is stopped.
+	OnConflict func(b *gotgbot.Bot, err *PollingConflictError) bool
+	// LeaderElector allows for running multiple replicas of the same bot, by ensuring that only one of them polls for
+	// updates at any one time. The others stand by until they are able to acquire leadership.
+	// If nil, polling starts immediately.
+	LeaderElector LeaderElector
 }
 
+var (
+	// defaultPollingBackoff is the default Backoff value for PollingOpts.
+	defaultPollingBackoff = ExponentialBackoff{
+		Initial:    time.Second,
+		Max:        time.Minute,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+	// defaultConflictBackoff is the default ConflictBackoff value for PollingOpts.
+	defaultConflictBackoff = ExponentialBackoff{
+		Initial:    5 * time.Second,
+		Max:        time.Minute,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+)
+
 // StartPolling starts polling updates from telegram using getUpdates long-polling.
 // See PollingOpts for optional values to set in production environments.
 func (u *Updater) StartPolling(b *gotgbot.Bot, opts *PollingOpts) error {
@@ -119,6 +193,9 @@ func (u *Updater) StartPolling(b *gotgbot.Bot, opts *PollingOpts) error {
 	//  - unnecessary unmarshalling of multiple full Update structs.
 	v := map[string]string{}
 	var reqOpts *gotgbot.RequestOpts
+	var offsetStore OffsetStore
+	var backoff BackoffPolicy = defaultPollingBackoff
+	var conflictBackoff BackoffPolicy = defaultConflictBackoff
 
 	if opts != nil {
 		if opts.EnableWebhookDeletion || opts.DropPendingUpdates {
@@ -155,51 +232,166 @@ func (u *Updater) StartPolling(b *gotgbot.Bot, opts *PollingOpts) error {
 				v["allowed_updates"] = string(bs)
 			}
 		}
+
+		offsetStore = opts.OffsetStore
+		if _, ok := v["offset"]; !ok && offsetStore != nil {
+			offset, err := offsetStore.LoadOffset(b)
+			if err != nil {
+				return fmt.Errorf("failed to load polling offset: %w", err)
+			}
+			if offset != 0 {
+				v["offset"] = strconv.FormatInt(offset, 10)
+			}
+		}
+
+		if opts.Backoff != nil {
+			backoff = opts.Backoff
+		}
+		if opts.ConflictBackoff != nil {
+			conflictBackoff = opts.ConflictBackoff
+		}
 	}
 
-	bData, err := u.botMapping.addBot(b, "", "")
+	cfg := pollingConfig{
+		reqOpts:         reqOpts,
+		v:               v,
+		offsetStore:     offsetStore,
+		backoff:         backoff,
+		conflictBackoff: conflictBackoff,
+	}
+	if opts != nil {
+		cfg.onConflict = opts.OnConflict
+		cfg.leaderElector = opts.LeaderElector
+	}
+
+	bData, err := u.botMapping.addBot(b, "", nil)
 	if err != nil {
 		return fmt.Errorf("failed to add bot with long polling: %w", err)
 	}
 
-	go u.Dispatcher.Start(b, bData.updateChan)
-	go u.pollingLoop(bData, reqOpts, v)
+	if td, ok := u.Dispatcher.(TrackingUpdateDispatcher); ok && cfg.offsetStore != nil {
+		// Track when updates are processed, so that offsets are only saved once they have been handled.
+		processing := &sync.WaitGroup{}
+		cfg.processing = processing
+		go td.StartTracking(b, bData.updateChan, func(json.RawMessage) { processing.Done() })
+	} else {
+		go u.Dispatcher.Start(b, bData.updateChan)
+	}
+
+	// Register the polling loop as a writer before starting it, so that stopping the bot always waits for it.
+	bData.updateWriterControl.Add(1)
+	go u.pollingLoop(bData, cfg)
 
 	return nil
 }
 
-func (u *Updater) pollingLoop(bData *botData, opts *gotgbot.RequestOpts, v map[string]string) {
-	bData.updateWriterControl.Add(1)
+// pollingConfig holds the settings used by a running polling loop.
+type pollingConfig struct {
+	// reqOpts are the request opts to use for each getUpdates call.
+	reqOpts *gotgbot.RequestOpts
+	// v contains the getUpdates parameters.
+	v map[string]string
+	// offsetStore is used to persist the polling offset, if set.
+	offsetStore OffsetStore
+	// processing counts the updates which have been sent to the dispatcher, but not yet processed.
+	// This is only set if an offsetStore is set, and the dispatcher is a TrackingUpdateDispatcher.
+	processing *sync.WaitGroup
+	// backoff defines how long to wait after a failed getUpdates call.
+	backoff BackoffPolicy
+	// conflictBackoff defines how long to wait after a getUpdates call failed due to a conflict.
+	conflictBackoff BackoffPolicy
+	// onConflict is called when a conflict is detected.
+	onConflict func(b *gotgbot.Bot, err *PollingConflictError) bool
+	// leaderElector ensures only one replica is polling, if set.
+	leaderElector LeaderElector
+}
+
+func (u *Updater) pollingLoop(bData *botData, cfg pollingConfig) {
 	defer bData.updateWriterControl.Done()
 
+	bData.pollingState.setRunning(true)
+	defer bData.pollingState.setRunning(false)
+
+	if cfg.leaderElector == nil {
+		u.poll(bData, cfg, nil)
+		return
+	}
+
+	for failures := 1; ; {
+		bData.pollingState.setStandby(true)
+		ctx, cancel := bData.stopContext()
+		lost, err := cfg.leaderElector.Acquire(ctx, bData.bot)
+		cancel()
+		if err != nil {
+			if bData.shouldStopUpdates() {
+				return
+			}
+
+			if u.UnhandledErrFunc != nil {
+				u.UnhandledErrFunc(fmt.Errorf("failed to acquire polling leadership: %w", err))
+			} else {
+				u.logf("Failed to acquire polling leadership: %s", err.Error())
+			}
+			if !bData.wait(cfg.backoff.Delay(failures)) {
+				return
+			}
+			failures++
+			continue
+		}
+		failures = 1
+		bData.pollingState.setStandby(false)
+
+		keepRunning := u.poll(bData, cfg, lost)
+
+		if err := cfg.leaderElector.Release(bData.bot); err != nil {
+			if u.UnhandledErrFunc != nil {
+				u.UnhandledErrFunc(fmt.Errorf("failed to release polling leadership: %w", err))
+			} else {
+				u.logf("Failed to release polling leadership: %s", err.Error())
+			}
+		}
+
+		if !keepRunning {
+			return
+		}
+	}
+}
+
+// poll runs the getUpdates loop until the bot is stopped, an unrecoverable error occurs, or leadership is lost.
+// It returns true if polling should resume once leadership is re-acquired.
+func (u *Updater) poll(bData *botData, cfg pollingConfig, leadershipLost <-chan struct{}) bool {
 	for {
 		// Check if updater loop has been terminated.
 		if bData.shouldStopUpdates() {
-			return
+			return false
+		}
+
+		// Check if another instance has taken over polling.
+		select {
+		case <-leadershipLost:
+			return true
+		default:
 		}
 
 		// Manually craft the getUpdate calls to improve memory management, reduce json parsing overheads, and
 		// unnecessary reallocation of url.Values in the polling loop.
-		r, err := bData.bot.Request("getUpdates", v, nil, opts)
+		r, err := bData.bot.Request("getUpdates", cfg.v, nil, cfg.reqOpts)
 		if err != nil {
-			if u.UnhandledErrFunc != nil {
-				u.UnhandledErrFunc(err)
-			} else {
-				u.logf("Failed to get updates; sleeping 1s: %s", err.Error())
-				time.Sleep(time.Second)
+			if !u.handlePollingError(bData, cfg, err, "Failed to get updates") {
+				return false
 			}
 			continue
+		}
 
-		} else if len(r) == 0 {
+		bData.pollingState.success()
+		if len(r) == 0 {
 			continue
 		}
 
 		var rawUpdates []json.RawMessage
 		if err := json.Unmarshal(r, &rawUpdates); err != nil {
-			if u.UnhandledErrFunc != nil {
-				u.UnhandledErrFunc(err)
-			} else {
-				u.logf("Failed to unmarshal updates: %s", err.Error())
+			if !u.handlePollingError(bData, cfg, err, "Failed to unmarshal updates") {
+				return false
 			}
 			continue
 		}
@@ -214,21 +406,94 @@ func (u *Updater) pollingLoop(bData *botData, opts *gotgbot.RequestOpts, v map[s
 
 		// Only unmarshal the last update, so we can get the next update ID.
 		if err := json.Unmarshal(rawUpdates[len(rawUpdates)-1], &lastUpdate); err != nil {
-			if u.UnhandledErrFunc != nil {
-				u.UnhandledErrFunc(err)
-			} else {
-				u.logf("Failed to unmarshal last update: %s", err.Error())
+			if !u.handlePollingError(bData, cfg, err, "Failed to unmarshal last update") {
+				return false
 			}
 			continue
 		}
 
-		v["offset"] = strconv.FormatInt(lastUpdate.UpdateId+1, 10)
+		cfg.v["offset"] = strconv.FormatInt(lastUpdate.UpdateId+1, 10)
+
+		if cfg.processing != nil {
+			cfg.processing.Add(len(rawUpdates))
+		}
 
 		for _, updData := range rawUpdates {
 			temp := updData // use new mem address to avoid loop conflicts
 			bData.updateChan <- temp
 		}
+
+		if cfg.offsetStore != nil {
+			// Wait for the updates to be processed before saving the offset, or fetching the next batch; this confirms
+			// the updates to telegram. If the bot is stopped first, the offset isn't saved, so the updates are fetched
+			// again after a restart.
+			if cfg.processing != nil && !bData.waitFor(cfg.processing) {
+				return false
+			}
+
+			if err := cfg.offsetStore.SaveOffset(bData.bot, lastUpdate.UpdateId+1); err != nil {
+				if u.UnhandledErrFunc != nil {
+					u.UnhandledErrFunc(err)
+				} else {
+					u.logf("Failed to save polling offset: %s", err.Error())
+				}
+			}
+		}
+	}
+}
+
+// handlePollingError records a polling failure, reports it, and waits for the relevant backoff delay.
+// It returns false if the polling loop should stop; either because the bot was stopped while waiting, or because the
+// error cannot be recovered from.
+func (u *Updater) handlePollingError(bData *botData, cfg pollingConfig, err error, msg string) bool {
+	failures := bData.pollingState.failure(err)
+	delay := cfg.backoff.Delay(failures)
+
+	var tgErr *gotgbot.TelegramError
+	if errors.As(err, &tgErr) {
+		switch tgErr.Code {
+		case http.StatusUnauthorized:
+			// The token has been revoked; retrying will never succeed, so we stop polling altogether.
+			err = fmt.Errorf("stopped polling, bot token is not authorized: %w", err)
+			if u.UnhandledErrFunc != nil {
+				u.UnhandledErrFunc(err)
+			} else {
+				u.logf("%s: %s", msg, err.Error())
+			}
+			return false
+
+		case http.StatusConflict:
+			// Another instance is polling, or a webhook is set. Retrying quickly will not help.
+			conflictErr := &PollingConflictError{Err: tgErr}
+			err = conflictErr
+			delay = cfg.conflictBackoff.Delay(failures)
+
+			if cfg.onConflict != nil && !cfg.onConflict(bData.bot, conflictErr) {
+				if u.UnhandledErrFunc != nil {
+					u.UnhandledErrFunc(err)
+				} else {
+					u.logf("%s; stopped polling: %s", msg, err.Error())
+				}
+				return false
+			}
+
+		case http.StatusTooManyRequests:
+			// Make sure to respect telegram's flood limits.
+			if tgErr.ResponseParams != nil {
+				if retryAfter := time.Duration(tgErr.ResponseParams.RetryAfter) * time.Second; retryAfter > delay {
+					delay = retryAfter
+				}
+			}
+		}
 	}
+
+	if u.UnhandledErrFunc != nil {
+		u.UnhandledErrFunc(err)
+	} else {
+		u.logf("%s; sleeping %s: %s", msg, delay.String(), err.Error())
+	}
+
+	return bData.wait(delay)
 }
 
 // Idle starts an infinite loop to avoid the program exciting while the background threads handle updates.
@@ -276,6 +541,16 @@ func (u *Updater) StopBot(token string) bool {
 	return true
 }
 
+// GetPollingStatus returns the current status of a bot's polling loop, which can be used for health checks.
+// Returns false if the bot is not known, or if it is not using long polling.
+func (u *Updater) GetPollingStatus(token string) (PollingStatus, bool) {
+	bData, ok := u.botMapping.getBot(token)
+	if !ok || bData.pollingState == nil {
+		return PollingStatus{}, false
+	}
+	return bData.pollingState.get(), true
+}
+
 func (u *Updater) StopAllBots() {
 	for _, bData := range u.botMapping.removeAllBots() {
 		bData.stop()
@@ -290,7 +565,13 @@ func (u *Updater) StartWebhook(b *gotgbot.Bot, urlPath string, opts WebhookOpts)
 		return ErrExpectedEmptyServer
 	}
 
-	err := u.AddWebhook(b, urlPath, &AddWebhookOpts{SecretToken: opts.SecretToken})
+	err := u.AddWebhook(b, urlPath, &AddWebhookOpts{
+		SecretToken:    opts.SecretToken,
+		AllowedSubnets: opts.AllowedSubnets,
+		TrustedProxies: opts.TrustedProxies,
+		MaxBodySize:    opts.MaxBodySize,
+		QueueSize:      opts.QueueSize,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to add webhook: %w", err)
 	}
@@ -298,10 +579,33 @@ func (u *Updater) StartWebhook(b *gotgbot.Bot, urlPath string, opts WebhookOpts)
 	return u.StartServer(opts)
 }
 
+// TelegramSubnets lists the subnets which telegram sends webhook updates from.
+// These can be used as the AllowedSubnets value of AddWebhookOpts.
+// See https://core.telegram.org/bots/webhooks#the-short-version for more details.
+var TelegramSubnets = []netip.Prefix{
+	netip.MustParsePrefix("149.154.160.0/20"),
+	netip.MustParsePrefix("91.108.4.0/22"),
+}
+
 // AddWebhookOpts stores any optional parameters for the Updater.AddWebhook method.
 type AddWebhookOpts struct {
 	// The secret token to be used to validate webhook authenticity.
 	SecretToken string
+	// AllowedSubnets restricts which source IPs can send webhook updates; requests from other IPs are rejected with
+	// a 403. TelegramSubnets contains the subnets telegram sends updates from.
+	// If empty, requests are accepted from any IP.
+	AllowedSubnets []netip.Prefix
+	// TrustedProxies lists the subnets of any reverse proxies in front of the webhook server. When a request comes from
+	// a trusted proxy, the source IP is read from the X-Forwarded-For (or X-Real-IP) header instead.
+	TrustedProxies []netip.Prefix
+	// MaxBodySize limits the size of incoming webhook requests, in bytes. Larger requests are rejected with a 413.
+	// If 0, the size is not limited.
+	MaxBodySize int64
+	// QueueSize defines how many webhook updates can be queued while waiting for the dispatcher.
+	// If 0, webhook requests only return once the dispatcher has picked up the update.
+	// If > 0, updates are acknowledged immediately. When the queue is full, requests are rejected with a 429, so that
+	// telegram backs off and sends the update again later.
+	QueueSize int
 }
 
 // AddWebhook prepares the webhook server to receive webhook updates for one bot, on a specific path.
@@ -312,12 +616,7 @@ func (u *Updater) AddWebhook(b *gotgbot.Bot, urlPath string, opts *AddWebhookOpt
 		return fmt.Errorf("expected a non-empty url path: %w", ErrEmptyPath)
 	}
 
-	secretToken := ""
-	if opts != nil {
-		secretToken = opts.SecretToken
-	}
-
-	bData, err := u.botMapping.addBot(b, urlPath, secretToken)
+	bData, err := u.botMapping.addBot(b, urlPath, opts)
 	if err != nil {
 		return fmt.Errorf("failed to add webhook for bot: %w", err)
 	}
@@ -333,8 +632,7 @@ func (u *Updater) SetAllBotWebhooks(domain string, opts *gotgbot.SetWebhookOpts)
 		_, err := data.bot.SetWebhook(strings.Join([]string{strings.TrimSuffix(domain, "/"), data.urlPath}, "/"), opts)
 		if err != nil {
 			// Extract the botID, so we don't intentionally log the token
-			botId := strings.Split(data.bot.Token, ":")[0]
-			return fmt.Errorf("failed to set webhook for %s: %w", botId, err)
+			return fmt.Errorf("failed to set webhook for %s: %w", getBotId(data.bot), err)
 		}
 	}
 	return nil
@@ -349,42 +647,50 @@ func (u *Updater) GetHandlerFunc(pathPrefix string) http.HandlerFunc {
 // StartServer starts the webhook server for all the bots added via AddWebhook.
 // It is recommended to call this BEFORE calling setWebhooks.
 // The opts parameter allows for specifying TLS settings.
+//
+// The server runs in a separate goroutine. Any errors encountered while serving are passed to the Updater's
+// UnhandledErrFunc, or logged to the ErrorLog if unset.
 func (u *Updater) StartServer(opts WebhookOpts) error {
-	var tls bool
-	switch {
-	case opts.CertFile == "" && opts.KeyFile == "":
-		tls = false
-	case opts.CertFile != "" && opts.KeyFile != "":
-		tls = true
-	default:
-		return ErrMissingCertOrKeyFile
-	}
-
-	ln, err := net.Listen(opts.GetListenNet(), opts.ListenAddr)
+	useTLS, err := opts.useTLS()
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s:%s: %w", opts.ListenNet, opts.ListenAddr, err)
+		return err
 	}
 
 	if u.webhookServer != nil {
 		return ErrExpectedEmptyServer
 	}
 
+	ln, err := net.Listen(opts.GetListenNet(), opts.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s:%s: %w", opts.ListenNet, opts.ListenAddr, err)
+	}
+
 	u.webhookServer = &http.Server{
 		Handler:           u.GetHandlerFunc("/"),
 		ReadTimeout:       opts.ReadTimeout,
 		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+		MaxHeaderBytes:    opts.MaxHeaderBytes,
+		TLSConfig:         opts.getTLSConfig(),
 	}
 
-	go func() {
-		if tls {
-			err = u.webhookServer.ServeTLS(ln, opts.CertFile, opts.KeyFile)
+	go func(server *http.Server) {
+		var err error
+		if useTLS {
+			err = server.ServeTLS(ln, opts.CertFile, opts.KeyFile)
 		} else {
-			err = u.webhookServer.Serve(ln)
+			err = server.Serve(ln)
 		}
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			panic("http server failed: " + err.Error())
+			err = fmt.Errorf("http server failed: %w", err)
+			if u.UnhandledErrFunc != nil {
+				u.UnhandledErrFunc(err)
+			} else {
+				u.logf("Webhook server stopped: %s", err.Error())
+			}
 		}
-	}()
+	}(u.webhookServer)
 
 	return nil
 }
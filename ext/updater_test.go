@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"strconv"
 	"strings"
 	"sync"
@@ -257,6 +258,83 @@ func TestUpdater_GetHandlerFunc(t *testing.T) {
 					"X-Telegram-Bot-Api-Secret-Token": "wrong",
 				},
 			},
+		}, {
+			name: "allowed subnet",
+			args: args{
+				urlPath: "123:hello",
+				opts: &ext.AddWebhookOpts{
+					AllowedSubnets: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+				},
+				httpResponse:  http.StatusOK,
+				handlerPrefix: "/",
+				requestPath:   "/123:hello",
+			},
+		}, {
+			name: "disallowed subnet",
+			args: args{
+				urlPath: "123:hello",
+				opts: &ext.AddWebhookOpts{
+					AllowedSubnets: ext.TelegramSubnets,
+				},
+				httpResponse:  http.StatusForbidden,
+				handlerPrefix: "/",
+				requestPath:   "/123:hello",
+			},
+		}, {
+			name: "untrusted forwarded IP",
+			args: args{
+				urlPath: "123:hello",
+				opts: &ext.AddWebhookOpts{
+					AllowedSubnets: ext.TelegramSubnets,
+				},
+				httpResponse:  http.StatusForbidden,
+				handlerPrefix: "/",
+				requestPath:   "/123:hello",
+				headers: map[string]string{
+					"X-Forwarded-For": "149.154.167.1",
+				},
+			},
+		}, {
+			name: "trusted proxy forwarded IP",
+			args: args{
+				urlPath: "123:hello",
+				opts: &ext.AddWebhookOpts{
+					AllowedSubnets: ext.TelegramSubnets,
+					TrustedProxies: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+				},
+				httpResponse:  http.StatusOK,
+				handlerPrefix: "/",
+				requestPath:   "/123:hello",
+				headers: map[string]string{
+					"X-Forwarded-For": "149.154.167.1, 127.0.0.1",
+				},
+			},
+		}, {
+			name: "spoofed forwarded IP through trusted proxy",
+			args: args{
+				urlPath: "123:hello",
+				opts: &ext.AddWebhookOpts{
+					AllowedSubnets: ext.TelegramSubnets,
+					TrustedProxies: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+				},
+				httpResponse:  http.StatusForbidden,
+				handlerPrefix: "/",
+				requestPath:   "/123:hello",
+				headers: map[string]string{
+					"X-Forwarded-For": "149.154.167.1, 10.0.0.1",
+				},
+			},
+		}, {
+			name: "body too large",
+			args: args{
+				urlPath: "123:hello",
+				opts: &ext.AddWebhookOpts{
+					MaxBodySize: 1,
+				},
+				httpResponse:  http.StatusRequestEntityTooLarge,
+				handlerPrefix: "/",
+				requestPath:   "/123:hello",
+			},
 		},
 	}
 	for _, tt := range tests {
@@ -296,6 +374,46 @@ func TestUpdater_GetHandlerFunc(t *testing.T) {
 	}
 }
 
+// blockedDispatcher is an UpdateDispatcher which never reads any updates.
+type blockedDispatcher struct{}
+
+func (blockedDispatcher) Start(_ *gotgbot.Bot, _ <-chan json.RawMessage) {}
+
+func (blockedDispatcher) Stop() {}
+
+func TestUpdaterWebhookQueue(t *testing.T) {
+	b := &gotgbot.Bot{
+		Token:     "SOME_TOKEN",
+		BotClient: &gotgbot.BaseBotClient{},
+	}
+
+	u := ext.NewUpdater(blockedDispatcher{}, nil)
+	if err := u.AddWebhook(b, "test", &ext.AddWebhookOpts{QueueSize: 1}); err != nil {
+		t.Fatalf("failed to add webhook: %v", err)
+	}
+
+	s := httptest.NewServer(u.GetHandlerFunc("/"))
+	defer s.Close()
+
+	// The first update should be queued and acknowledged immediately; the second should be rejected.
+	for _, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.URL+"/test", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("failed to build request: %v", err)
+		}
+
+		r, err := s.Client().Do(req)
+		if err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+		r.Body.Close()
+
+		if r.StatusCode != expected {
+			t.Errorf("expected code %d, got %d", expected, r.StatusCode)
+		}
+	}
+}
+
 func TestUpdaterAllowsWebhookDeletion(t *testing.T) {
 	server := basicTestServer(t, map[string]*testEndpoint{
 		"getUpdates":    {reply: `{"ok": true}`},
@@ -489,6 +607,205 @@ func TestUpdaterSupportsLongPollReAdding(t *testing.T) {
 	}
 }
 
+// constantBackoff is a simple BackoffPolicy which always waits for the same duration.
+type constantBackoff time.Duration
+
+func (c constantBackoff) Delay(_ int) time.Duration {
+	return time.Duration(c)
+}
+
+func TestUpdaterPollingBackoff(t *testing.T) {
+	server := basicTestServer(t, map[string]*testEndpoint{
+		"getUpdates": {reply: `{"ok": false, "error_code": 502, "description": "Bad Gateway"}`},
+	})
+	defer server.Close()
+
+	reqOpts := &gotgbot.RequestOpts{
+		APIURL: server.URL,
+	}
+
+	b := &gotgbot.Bot{
+		Token: "SOME_TOKEN",
+		BotClient: &gotgbot.BaseBotClient{
+			DefaultRequestOpts: reqOpts,
+		},
+	}
+
+	var errCount atomic.Int32
+	d := ext.NewDispatcher(nil)
+	u := ext.NewUpdater(d, &ext.UpdaterOpts{
+		// Even with an error handler set, we expect the backoff to be respected.
+		UnhandledErrFunc: func(err error) {
+			errCount.Add(1)
+		},
+	})
+
+	err := u.StartPolling(b, &ext.PollingOpts{
+		GetUpdatesOpts: &gotgbot.GetUpdatesOpts{
+			RequestOpts: reqOpts,
+		},
+		Backoff: constantBackoff(100 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("failed to start polling: %v", err)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+
+	status, ok := u.GetPollingStatus(b.Token)
+	if !ok {
+		t.Fatalf("expected polling status to be available")
+	}
+	if !status.Running {
+		t.Errorf("expected polling to still be running")
+	}
+	if status.Healthy(1) {
+		t.Errorf("expected polling status to be unhealthy")
+	}
+	if !status.LastSuccess.IsZero() {
+		t.Errorf("expected no successful polls, got %s", status.LastSuccess)
+	}
+	if status.ConsecutiveFailures < 2 || status.ConsecutiveFailures > 4 {
+		t.Errorf("expected 2-4 consecutive failures with backoff, got %d", status.ConsecutiveFailures)
+	}
+	if status.LastError == nil {
+		t.Errorf("expected the last error to be set")
+	}
+
+	if err := u.Stop(); err != nil {
+		t.Fatalf("failed to stop updater: %v", err)
+	}
+
+	if c := errCount.Load(); c < 2 || c > 4 {
+		t.Errorf("expected 2-4 errors to be reported, got %d", c)
+	}
+
+	if _, ok := u.GetPollingStatus(b.Token); ok {
+		t.Errorf("expected no polling status once the bot is stopped")
+	}
+}
+
+func TestUpdaterPollingStopsWhenUnauthorized(t *testing.T) {
+	server := basicTestServer(t, map[string]*testEndpoint{
+		"getUpdates": {reply: `{"ok": false, "error_code": 401, "description": "Unauthorized"}`},
+	})
+	defer server.Close()
+
+	reqOpts := &gotgbot.RequestOpts{
+		APIURL: server.URL,
+	}
+
+	b := &gotgbot.Bot{
+		Token: "SOME_TOKEN",
+		BotClient: &gotgbot.BaseBotClient{
+			DefaultRequestOpts: reqOpts,
+		},
+	}
+
+	var errCount atomic.Int32
+	d := ext.NewDispatcher(nil)
+	u := ext.NewUpdater(d, &ext.UpdaterOpts{
+		UnhandledErrFunc: func(err error) {
+			var tgErr *gotgbot.TelegramError
+			if !errors.As(err, &tgErr) || tgErr.Code != http.StatusUnauthorized {
+				t.Errorf("expected an unauthorized telegram error, got %v", err)
+			}
+			errCount.Add(1)
+		},
+	})
+
+	err := u.StartPolling(b, &ext.PollingOpts{
+		GetUpdatesOpts: &gotgbot.GetUpdatesOpts{
+			RequestOpts: reqOpts,
+		},
+		Backoff: constantBackoff(time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("failed to start polling: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	status, ok := u.GetPollingStatus(b.Token)
+	if !ok {
+		t.Fatalf("expected polling status to be available")
+	}
+	if status.Running {
+		t.Errorf("expected polling to have stopped")
+	}
+	if c := errCount.Load(); c != 1 {
+		t.Errorf("expected a single error to be reported, got %d", c)
+	}
+
+	if err := u.Stop(); err != nil {
+		t.Fatalf("failed to stop updater: %v", err)
+	}
+}
+
+func TestUpdaterPollingConflict(t *testing.T) {
+	server := basicTestServer(t, map[string]*testEndpoint{
+		"getUpdates": {reply: `{"ok": false, "error_code": 409, "description": "Conflict: terminated by other getUpdates request; make sure that only one bot instance is running"}`},
+	})
+	defer server.Close()
+
+	reqOpts := &gotgbot.RequestOpts{
+		APIURL: server.URL,
+	}
+
+	b := &gotgbot.Bot{
+		Token: "SOME_TOKEN",
+		BotClient: &gotgbot.BaseBotClient{
+			DefaultRequestOpts: reqOpts,
+		},
+	}
+
+	var errCount atomic.Int32
+	d := ext.NewDispatcher(nil)
+	u := ext.NewUpdater(d, &ext.UpdaterOpts{
+		UnhandledErrFunc: func(err error) {
+			if !errors.Is(err, ext.ErrPollingConflict) {
+				t.Errorf("expected a polling conflict error, got %v", err)
+			}
+			errCount.Add(1)
+		},
+	})
+
+	var conflicts atomic.Int32
+	err := u.StartPolling(b, &ext.PollingOpts{
+		GetUpdatesOpts: &gotgbot.GetUpdatesOpts{
+			RequestOpts: reqOpts,
+		},
+		ConflictBackoff: constantBackoff(time.Millisecond),
+		OnConflict: func(b *gotgbot.Bot, err *ext.PollingConflictError) bool {
+			if err.WebhookActive() {
+				t.Errorf("conflict should not be caused by a webhook")
+			}
+			// Stop polling after the second conflict.
+			return conflicts.Add(1) < 2
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to start polling: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if status, _ := u.GetPollingStatus(b.Token); status.Running {
+		t.Errorf("expected polling to have stopped after conflicts")
+	}
+	if c := conflicts.Load(); c != 2 {
+		t.Errorf("expected 2 conflicts, got %d", c)
+	}
+	// Every conflict is reported, including the one which stopped polling.
+	if c := errCount.Load(); c != 2 {
+		t.Errorf("expected 2 reported conflict errors, got %d", c)
+	}
+
+	if err := u.Stop(); err != nil {
+		t.Fatalf("failed to stop updater: %v", err)
+	}
+}
+
 type testEndpoint struct {
 	delay time.Duration
 	// Will reply these until we run out of replies, at which point we repeat "reply"
@@ -0,0 +1,214 @@
+package ext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWebAppAuthMaxAge is the default maximum age of the initData accepted by WebAppAuth.
+const DefaultWebAppAuthMaxAge = time.Hour * 24
+
+var (
+	ErrMissingInitData = errors.New("missing webapp initData")
+	ErrUnknownBot      = errors.New("unknown bot")
+)
+
+// WebAppAuthOpts is the set of fields used to configure the WebAppAuth middleware.
+type WebAppAuthOpts struct {
+	// MaxAge is the maximum age of the initData's auth_date; older initData is rejected with ErrAuthDateExpired.
+	// If 0, DefaultWebAppAuthMaxAge is used. If negative, the age of the initData is not checked.
+	MaxAge time.Duration
+	// QueryParam is the name of the URL query parameter to read the initData from, if it isn't found in the request
+	// headers. If empty, the query is not checked.
+	QueryParam string
+	// BotIdFunc allows for selecting which bot the request is for; eg, based on the request path.
+	// If nil, or if it returns 0, the initData is checked against all the tokens known to the middleware.
+	BotIdFunc func(r *http.Request) int64
+	// ErrorHandler is called to write the response when a request fails authentication.
+	// If nil, a 401 Unauthorized response is returned.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// WebAppAuth is a net/http middleware which authenticates requests made from a webapp to its backend.
+// The webapp is expected to send its raw Telegram.WebApp.initData string in the "Authorization" header, using the
+// "tma" scheme (eg: "Authorization: tma <initData>"), or in the "X-Telegram-Init-Data" header.
+//
+// Multiple bots can be served by the same middleware; the initData is validated against the token of each known bot,
+// and the matching bot ID is stored in the request context.
+type WebAppAuth struct {
+	maxAge       time.Duration
+	queryParam   string
+	botIdFunc    func(r *http.Request) int64
+	errorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// tokens maps bot IDs to their respective tokens.
+	tokens map[int64]string
+	// botIds keeps track of the order in which bots were added, so that validation order is deterministic.
+	botIds []int64
+	// lock allows us to ensure synchronous access to the tokens.
+	lock sync.RWMutex
+}
+
+// NewWebAppAuth creates a new WebAppAuth middleware, authenticating initData for the given bot tokens.
+func NewWebAppAuth(tokens []string, opts *WebAppAuthOpts) (*WebAppAuth, error) {
+	a := &WebAppAuth{
+		maxAge: DefaultWebAppAuthMaxAge,
+		tokens: map[int64]string{},
+	}
+
+	if opts != nil {
+		if opts.MaxAge != 0 {
+			a.maxAge = opts.MaxAge
+		}
+		a.queryParam = opts.QueryParam
+		a.botIdFunc = opts.BotIdFunc
+		a.errorHandler = opts.ErrorHandler
+	}
+
+	for _, token := range tokens {
+		if err := a.AddToken(token); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// AddToken adds a new bot token to authenticate initData against.
+func (a *WebAppAuth) AddToken(token string) error {
+	botId, err := strconv.ParseInt(strings.Split(token, ":")[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to get bot ID from token: %w", err)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if _, ok := a.tokens[botId]; !ok {
+		a.botIds = append(a.botIds, botId)
+	}
+	a.tokens[botId] = token
+	return nil
+}
+
+// Middleware wraps the given http.Handler, only calling it for authenticated requests.
+// The parsed initData can be obtained from the request context with WebAppInitDataFromContext.
+func (a *WebAppAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		botId, data, err := a.Authenticate(r)
+		if err != nil {
+			if a.errorHandler != nil {
+				a.errorHandler(w, r, err)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), webAppBotIdKey{}, botId)
+		ctx = context.WithValue(ctx, webAppInitDataKey{}, data)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Authenticate validates the initData contained in the request, returning the ID of the bot it was issued for, as
+// well as the parsed contents.
+func (a *WebAppAuth) Authenticate(r *http.Request) (int64, *WebAppInitData, error) {
+	initData := a.getInitData(r)
+	if initData == "" {
+		return 0, nil, ErrMissingInitData
+	}
+
+	query, err := url.ParseQuery(initData)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse initData: %w", err)
+	}
+
+	maxAge := a.maxAge
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	if a.botIdFunc != nil {
+		if botId := a.botIdFunc(r); botId != 0 {
+			token, ok := a.tokens[botId]
+			if !ok {
+				return 0, nil, fmt.Errorf("%w: %d", ErrUnknownBot, botId)
+			}
+
+			data, err := ParseWebAppQuery(query, token, maxAge)
+			if err != nil {
+				return 0, nil, err
+			}
+			return botId, data, nil
+		}
+	}
+
+	for _, botId := range a.botIds {
+		ok, err := ValidateWebAppQuery(query, a.tokens[botId])
+		if err != nil {
+			return 0, nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		data, err := parseWebAppQuery(query, maxAge)
+		if err != nil {
+			return 0, nil, err
+		}
+		return botId, data, nil
+	}
+	return 0, nil, ErrInvalidHash
+}
+
+// getInitData reads the raw initData from the request headers, or the configured query parameter.
+func (a *WebAppAuth) getInitData(r *http.Request) string {
+	if scheme, initData, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "tma") {
+		return strings.TrimSpace(initData)
+	}
+	if initData := r.Header.Get("X-Telegram-Init-Data"); initData != "" {
+		return initData
+	}
+	if a.queryParam != "" {
+		return r.URL.Query().Get(a.queryParam)
+	}
+	return ""
+}
+
+type (
+	webAppBotIdKey    struct{}
+	webAppInitDataKey struct{}
+)
+
+// WebAppInitDataFromContext returns the initData which was authenticated by the WebAppAuth middleware.
+func WebAppInitDataFromContext(ctx context.Context) (*WebAppInitData, bool) {
+	data, ok := ctx.Value(webAppInitDataKey{}).(*WebAppInitData)
+	return data, ok
+}
+
+// WebAppUserFromContext returns the webapp user which was authenticated by the WebAppAuth middleware.
+// This returns false if the request was authenticated, but the initData contained no user.
+func WebAppUserFromContext(ctx context.Context) (*WebAppUser, bool) {
+	data, ok := WebAppInitDataFromContext(ctx)
+	if !ok || data.User == nil {
+		return nil, false
+	}
+	return data.User, true
+}
+
+// WebAppBotIdFromContext returns the ID of the bot for which the request was authenticated by the WebAppAuth
+// middleware.
+func WebAppBotIdFromContext(ctx context.Context) (int64, bool) {
+	botId, ok := ctx.Value(webAppBotIdKey{}).(int64)
+	return botId, ok
+}
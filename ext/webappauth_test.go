@@ -0,0 +1,158 @@
+package ext
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestInitData(t *testing.T, token string, authDate time.Time) string {
+	query := url.Values{}
+	query.Set("auth_date", strconv.FormatInt(authDate.Unix(), 10))
+	query.Set("query_id", "AAHdF6IQAAAAAN0XohDhrOrc")
+	query.Set("user", `{"id":279058397,"first_name":"Vladislav","username":"vdkfrost"}`)
+	query.Set("hash", signWebAppQuery(t, query, token))
+	return query.Encode()
+}
+
+func TestWebAppAuth(t *testing.T) {
+	const (
+		token1 = "123:abc"
+		token2 = "456:def"
+	)
+
+	auth, err := NewWebAppAuth([]string{token1, token2}, &WebAppAuthOpts{
+		MaxAge:     time.Hour,
+		QueryParam: "initData",
+	})
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+
+	var gotBotId int64
+	var gotUser *WebAppUser
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBotId, _ = WebAppBotIdFromContext(r.Context())
+		gotUser, _ = WebAppUserFromContext(r.Context())
+	}))
+
+	now := time.Now()
+	for name, testParams := range map[string]struct {
+		setRequest func(r *http.Request)
+		wantStatus int
+		wantBotId  int64
+	}{
+		"authorization header": {
+			setRequest: func(r *http.Request) {
+				r.Header.Set("Authorization", "tma "+newTestInitData(t, token1, now))
+			},
+			wantStatus: http.StatusOK,
+			wantBotId:  123,
+		},
+		"init data header for second bot": {
+			setRequest: func(r *http.Request) {
+				r.Header.Set("X-Telegram-Init-Data", newTestInitData(t, token2, now))
+			},
+			wantStatus: http.StatusOK,
+			wantBotId:  456,
+		},
+		"query param": {
+			setRequest: func(r *http.Request) {
+				r.URL.RawQuery = url.Values{"initData": {newTestInitData(t, token1, now)}}.Encode()
+			},
+			wantStatus: http.StatusOK,
+			wantBotId:  123,
+		},
+		"missing init data": {
+			setRequest: func(r *http.Request) {},
+			wantStatus: http.StatusUnauthorized,
+		},
+		"unknown bot": {
+			setRequest: func(r *http.Request) {
+				r.Header.Set("Authorization", "tma "+newTestInitData(t, "789:ghi", now))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		"expired": {
+			setRequest: func(r *http.Request) {
+				r.Header.Set("Authorization", "tma "+newTestInitData(t, token1, now.Add(-2*time.Hour)))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gotBotId, gotUser = 0, nil
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/api", nil)
+			testParams.setRequest(r)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != testParams.wantStatus {
+				t.Fatalf("expected status %d, got %d", testParams.wantStatus, w.Code)
+			}
+			if testParams.wantStatus != http.StatusOK {
+				return
+			}
+			if gotBotId != testParams.wantBotId {
+				t.Errorf("expected bot ID %d, got %d", testParams.wantBotId, gotBotId)
+			}
+			if gotUser == nil || gotUser.Id != 279058397 {
+				t.Errorf("expected authenticated user in context, got %+v", gotUser)
+			}
+		})
+	}
+}
+
+func TestWebAppAuthBotIdFunc(t *testing.T) {
+	const token = "123:abc"
+
+	var gotErr error
+	auth, err := NewWebAppAuth([]string{token}, &WebAppAuthOpts{
+		BotIdFunc: func(r *http.Request) int64 {
+			botId, _ := strconv.ParseInt(r.URL.Query().Get("bot"), 10, 64)
+			return botId
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			gotErr = err
+			w.WriteHeader(http.StatusForbidden)
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "http://localhost/api?bot=456", nil)
+	r.Header.Set("Authorization", "tma "+newTestInitData(t, token, time.Now()))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected custom error handler status, got %d", w.Code)
+	}
+	if !errors.Is(gotErr, ErrUnknownBot) {
+		t.Fatalf("expected ErrUnknownBot, got %v", gotErr)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "http://localhost/api?bot=123", nil)
+	r.Header.Set("Authorization", "tma "+newTestInitData(t, token, time.Now()))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestNewWebAppAuthInvalidToken(t *testing.T) {
+	if _, err := NewWebAppAuth([]string{"not-a-token"}, nil); err == nil {
+		t.Fatal("expected an error for a token without a bot ID")
+	}
+}
@@ -1,6 +1,8 @@
 package ext
 
 import (
+	"crypto/tls"
+	"net/netip"
 	"time"
 )
 
@@ -19,13 +21,72 @@ type WebhookOpts struct {
 	// request.
 	// See http.Server for more details.
 	ReadHeaderTimeout time.Duration
+	// WriteTimeout is passed to the http server to limit the time it takes to write a response.
+	// See http.Server for more details.
+	WriteTimeout time.Duration
+	// IdleTimeout is passed to the http server to limit how long to keep idle keep-alive connections open.
+	// See http.Server for more details.
+	IdleTimeout time.Duration
+	// MaxHeaderBytes is passed to the http server to limit the size of incoming request headers.
+	// See http.Server for more details.
+	MaxHeaderBytes int
 
 	// HTTPS cert and key files for custom signed certificates
 	CertFile string
 	KeyFile  string
+	// TLSConfig optionally defines the TLS configuration to use for the webhook server.
+	// This allows for in-memory certificates, or for ACME setups (eg, using autocert.Manager's TLSConfig method).
+	// If set, the server uses HTTPS; the CertFile and KeyFile fields can then be left empty.
+	TLSConfig *tls.Config
+	// GetCertificate optionally defines how to obtain the certificate for each incoming connection.
+	// This allows for reloading certificates without restarting the server, or for ACME setups (eg, using
+	// autocert.Manager's GetCertificate method).
+	// If set, the server uses HTTPS, and this takes precedence over TLSConfig.GetCertificate.
+	GetCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
 
 	// SecretToken to be used by the bots on this webhook. Used as a security measure to ensure that you set the webhook.
 	SecretToken string
+	// AllowedSubnets restricts which source IPs can send webhook updates.
+	// See AddWebhookOpts.AllowedSubnets for more details.
+	AllowedSubnets []netip.Prefix
+	// TrustedProxies lists the subnets of any reverse proxies in front of the webhook server.
+	// See AddWebhookOpts.TrustedProxies for more details.
+	TrustedProxies []netip.Prefix
+	// MaxBodySize limits the size of incoming webhook requests, in bytes.
+	// See AddWebhookOpts.MaxBodySize for more details.
+	MaxBodySize int64
+	// QueueSize defines how many webhook updates can be queued while waiting for the dispatcher.
+	// See AddWebhookOpts.QueueSize for more details.
+	QueueSize int
+}
+
+// useTLS determines whether the webhook server should be serving HTTPS, based on the given opts.
+func (w *WebhookOpts) useTLS() (bool, error) {
+	switch {
+	case w.CertFile == "" && w.KeyFile == "":
+		return w.TLSConfig != nil || w.GetCertificate != nil, nil
+	case w.CertFile != "" && w.KeyFile != "":
+		return true, nil
+	default:
+		return false, ErrMissingCertOrKeyFile
+	}
+}
+
+// getTLSConfig returns the TLS configuration to use for the webhook server, if any.
+func (w *WebhookOpts) getTLSConfig() *tls.Config {
+	if w.TLSConfig == nil && w.GetCertificate == nil {
+		return nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if w.TLSConfig != nil {
+		// Clone the config, so we can safely modify it.
+		cfg = w.TLSConfig.Clone()
+	}
+	if w.GetCertificate != nil {
+		cfg.GetCertificate = w.GetCertificate
+	}
+	return cfg
 }
 
 func (w *WebhookOpts) GetListenNet() string {
@@ -0,0 +1,123 @@
+package ext_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+func TestUpdaterStartServerRequiresCertAndKey(t *testing.T) {
+	u := ext.NewUpdater(ext.NewDispatcher(nil), nil)
+
+	err := u.StartServer(ext.WebhookOpts{
+		ListenAddr: "127.0.0.1:0",
+		CertFile:   "cert.pem",
+	})
+	if !errors.Is(err, ext.ErrMissingCertOrKeyFile) {
+		t.Fatalf("expected missing key file error, got %v", err)
+	}
+}
+
+func TestUpdaterStartServerWithGetCertificate(t *testing.T) {
+	cert := newTestCertificate(t)
+
+	// Reserve a free port for the server.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve port: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	b := &gotgbot.Bot{
+		Token:     "SOME_TOKEN",
+		BotClient: &gotgbot.BaseBotClient{},
+	}
+
+	var certRequests atomic.Int32
+	d := ext.NewDispatcher(nil)
+	u := ext.NewUpdater(d, nil)
+	if err := u.AddWebhook(b, "test", nil); err != nil {
+		t.Fatalf("failed to add webhook: %v", err)
+	}
+
+	err = u.StartServer(ext.WebhookOpts{
+		ListenAddr:   addr,
+		WriteTimeout: time.Second,
+		IdleTimeout:  time.Second,
+		GetCertificate: func(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			certRequests.Add(1)
+			return cert, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	client := http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // Self-signed test certificate.
+		},
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "https://"+addr+"/test", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+
+	r, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send request over TLS: %v", err)
+	}
+	r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", r.StatusCode)
+	}
+	if certRequests.Load() == 0 {
+		t.Errorf("expected GetCertificate to have been called")
+	}
+
+	if err := u.Stop(); err != nil {
+		t.Fatalf("failed to stop updater: %v", err)
+	}
+}
+
+// newTestCertificate generates a self-signed in-memory certificate for localhost.
+func newTestCertificate(t *testing.T) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+}
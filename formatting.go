@@ -12,6 +12,7 @@ var mdMap = map[string]string{
 	"bold":   "*",
 	"italic": "_",
 	"code":   "`",
+	"pre":    "```",
 }
 
 var mdV2Map = map[string]string{
@@ -26,14 +27,15 @@ var mdV2Map = map[string]string{
 }
 
 var htmlMap = map[string]string{
-	"bold":          "b",
-	"italic":        "i",
-	"code":          "code",
-	"pre":           "pre",
-	"underline":     "u",
-	"strikethrough": "s",
-	"spoiler":       "span class=\"tg-spoiler\"",
-	"blockquote":    "blockquote",
+	"bold":                  "b",
+	"italic":                "i",
+	"code":                  "code",
+	"pre":                   "pre",
+	"underline":             "u",
+	"strikethrough":         "s",
+	"spoiler":               "span class=\"tg-spoiler\"",
+	"blockquote":            "blockquote",
+	"expandable_blockquote": "blockquote expandable",
 }
 
 // OriginalMD gets the original markdown formatting of a message text.
@@ -66,13 +68,16 @@ func (m Message) OriginalCaptionHTML() string {
 	return getOrigMsgHTML(utf16.Encode([]rune(m.Caption)), m.CaptionEntities)
 }
 
-// Does not support nesting. only look at upper entities.
+// getOrigMsgMD converts the entities to legacy markdown.
+// Legacy markdown does not support nesting, so only the outermost supported entities are kept. It also does not
+// support underline, strikethrough, spoiler, blockquote and custom emoji entities, which are dropped.
+// See https://core.telegram.org/bots/api#markdown-style for more details.
 func getOrigMsgMD(utf16Data []uint16, ents []MessageEntity) string {
 	out := strings.Builder{}
 	prev := int64(0)
-	for _, ent := range getUpperEntities(ents) {
+	for _, ent := range getUpperEntities(getSupportedMDEntities(ents)) {
 		newPrev := ent.Offset + ent.Length
-		prevText := string(utf16.Decode(utf16Data[prev:ent.Offset]))
+		prevText := escapeContainedMDV1([]rune(string(utf16.Decode(utf16Data[prev:ent.Offset]))), mdV1SpecialChars)
 
 		text := utf16.Decode(utf16Data[ent.Offset:newPrev])
 		pre, cleanCntnt, post := splitEdgeWhitespace(string(text), ent)
@@ -80,7 +85,7 @@ func getOrigMsgMD(utf16Data []uint16, ents []MessageEntity) string {
 
 		switch ent.Type {
 		case "bold", "italic", "code":
-			out.WriteString(prevText + pre + mdMap[ent.Type] + escapeContainedMDV1(cleanCntntRune, []rune(mdMap[ent.Type])) + mdMap[ent.Type] + post)
+			out.WriteString(prevText + pre + mdMap[ent.Type] + reopenContainedMDV1(cleanCntntRune, mdMap[ent.Type]) + mdMap[ent.Type] + post)
 		case "pre":
 			if ent.Language == "" {
 				out.WriteString(prevText + pre + mdMap[ent.Type] + escapeContainedMDV1(cleanCntntRune, []rune(mdMap[ent.Type])) + mdMap[ent.Type] + post)
@@ -91,13 +96,41 @@ func getOrigMsgMD(utf16Data []uint16, ents []MessageEntity) string {
 			out.WriteString(prevText + pre + "[" + escapeContainedMDV1(cleanCntntRune, []rune("[]()")) + "](tg://user?id=" + strconv.FormatInt(ent.User.Id, 10) + ")" + post)
 		case "text_link":
 			out.WriteString(prevText + pre + "[" + escapeContainedMDV1(cleanCntntRune, []rune("[]()")) + "](" + ent.Url + ")" + post)
-		default:
-			continue
 		}
 		prev = newPrev
 	}
 
-	out.WriteString(string(utf16.Decode(utf16Data[prev:])))
+	out.WriteString(escapeContainedMDV1([]rune(string(utf16.Decode(utf16Data[prev:]))), mdV1SpecialChars))
+	return out.String()
+}
+
+// mdV1SpecialChars contains all the characters which must be escaped outside of entities in legacy markdown.
+var mdV1SpecialChars = []rune("_*`[")
+
+// getSupportedMDEntities filters out the entities which cannot be represented in legacy markdown, so that they don't
+// hide any supported entities nested within them.
+func getSupportedMDEntities(ents []MessageEntity) []MessageEntity {
+	supported := make([]MessageEntity, 0, len(ents))
+	for _, e := range ents {
+		switch e.Type {
+		case "bold", "italic", "code", "pre", "text_mention", "text_link":
+			supported = append(supported, e)
+		}
+	}
+	return supported
+}
+
+// reopenContainedMDV1 handles marker characters contained within a legacy markdown entity. Escaping is not allowed
+// within entities, so the entity is closed, the character is escaped, and the entity is reopened; eg, "_snake_\__case_".
+func reopenContainedMDV1(data []rune, marker string) string {
+	out := strings.Builder{}
+	for _, x := range data {
+		if string(x) == marker {
+			out.WriteString(marker + "\\" + marker + marker)
+			continue
+		}
+		out.WriteRune(x)
+	}
 	return out.String()
 }
 
@@ -120,19 +153,18 @@ func getOrigMsgHTML(utf16Data []uint16, ents []MessageEntity) string {
 
 func getOrigMsgMDV2(utf16Data []uint16, ents []MessageEntity) string {
 	if len(ents) == 0 {
-		return string(utf16.Decode(utf16Data))
+		return EscapeMarkdownV2(string(utf16.Decode(utf16Data)))
 	}
 
-	bd := strings.Builder{}
+	out := ""
 	prev := int64(0)
 	for _, e := range getUpperEntities(ents) {
 		data, end := fillNestedMarkdownV2(utf16Data, e, prev, getChildEntities(e, ents))
-		bd.WriteString(data)
+		out = concatMarkdownV2(out, data)
 		prev = end
 	}
 
-	bd.WriteString(string(utf16.Decode(utf16Data[prev:])))
-	return bd.String()
+	return concatMarkdownV2(out, EscapeMarkdownV2(string(utf16.Decode(utf16Data[prev:]))))
 }
 
 func fillNestedHTML(data []uint16, ent MessageEntity, start int64, entities []MessageEntity) (string, int64) {
@@ -166,11 +198,11 @@ func fillNestedMarkdownV2(data []uint16, ent MessageEntity, start int64, entitie
 	entEnd := ent.Offset + ent.Length
 	if len(entities) == 0 || entEnd < entities[0].Offset {
 		// no nesting; just return straight away and move to next.
-		return writeFinalMarkdownV2(data, ent, start, string(utf16.Decode(data[ent.Offset:entEnd]))), entEnd
+		return writeFinalMarkdownV2(data, ent, start, escapeMarkdownV2Entity(string(utf16.Decode(data[ent.Offset:entEnd])), ent)), entEnd
 	}
 	subPrev := ent.Offset
 	subEnd := ent.Offset
-	bd := strings.Builder{}
+	out := ""
 	for _, e := range getUpperEntities(entities) {
 		if e.Offset < subEnd || e == ent {
 			continue
@@ -179,14 +211,14 @@ func fillNestedMarkdownV2(data []uint16, ent MessageEntity, start int64, entitie
 			break
 		}
 
-		out, end := fillNestedMarkdownV2(data, e, subPrev, getChildEntities(e, entities))
-		bd.WriteString(out)
+		nested, end := fillNestedMarkdownV2(data, e, subPrev, getChildEntities(e, entities))
+		out = concatMarkdownV2(out, nested)
 		subPrev = end
 	}
 
-	bd.WriteString(string(utf16.Decode(data[subPrev:entEnd])))
+	out = concatMarkdownV2(out, escapeMarkdownV2Entity(string(utf16.Decode(data[subPrev:entEnd])), ent))
 
-	return writeFinalMarkdownV2(data, ent, start, bd.String()), entEnd
+	return writeFinalMarkdownV2(data, ent, start, out), entEnd
 }
 
 func writeFinalHTML(data []uint16, ent MessageEntity, start int64, cntnt string) string {
@@ -199,35 +231,35 @@ func writeFinalHTML(data []uint16, ent MessageEntity, start int64, cntnt string)
 		if ent.Language == "" {
 			return prevText + "<pre>" + cntnt + "</pre>"
 		}
-		// <pre><code class="lang">text</code></pre>
-		return prevText + `<pre><code class="` + ent.Language + `">` + cntnt + "</code></pre>"
+		// <pre><code class="language-lang">text</code></pre>
+		return prevText + `<pre><code class="language-` + html.EscapeString(ent.Language) + `">` + cntnt + "</code></pre>"
 	case "custom_emoji":
-		return prevText + `<tg-emoji emoji-id="` + ent.CustomEmojiId + `">` + cntnt + "</tg-emoji>"
+		return prevText + `<tg-emoji emoji-id="` + html.EscapeString(ent.CustomEmojiId) + `">` + cntnt + "</tg-emoji>"
 	case "text_mention":
 		return prevText + `<a href="tg://user?id=` + strconv.FormatInt(ent.User.Id, 10) + `">` + cntnt + "</a>"
 	case "text_link":
-		return prevText + `<a href="` + ent.Url + `">` + cntnt + "</a>"
-	case "blockquote":
-		return prevText + `<blockquote>` + cntnt + "</blockquote>"
+		return prevText + `<a href="` + html.EscapeString(ent.Url) + `">` + cntnt + "</a>"
+	case "blockquote", "expandable_blockquote":
+		return prevText + "<" + htmlMap[ent.Type] + ">" + cntnt + "</" + closeHTMLTag(htmlMap[ent.Type]) + ">"
 	default:
+		// Entities which are automatically detected by telegram (mentions, hashtags, URLs, etc) do not need any
+		// formatting.
 		return prevText + cntnt
 	}
 }
 
-// closeHTMLTag makes sure to generate the correct HTML closing tag for a given opening tag.
+// closeHTMLTag makes sure to generate the correct HTML closing tag for a given opening tag, by dropping any attributes.
 func closeHTMLTag(s string) string {
-	if !strings.HasPrefix(s, "span") {
-		return s
-	}
-	return "span"
+	tag, _, _ := strings.Cut(s, " ")
+	return tag
 }
 
 func writeFinalMarkdownV2(data []uint16, ent MessageEntity, start int64, cntnt string) string {
-	prevText := string(utf16.Decode(data[start:ent.Offset]))
+	prevText := EscapeMarkdownV2(string(utf16.Decode(data[start:ent.Offset])))
 	pre, cleanCntnt, post := splitEdgeWhitespace(cntnt, ent)
 	switch ent.Type {
 	case "bold", "italic", "code", "underline", "strikethrough", "spoiler":
-		return prevText + pre + mdV2Map[ent.Type] + cleanCntnt + mdV2Map[ent.Type] + post
+		return concatMarkdownV2(prevText+pre, mdV2Map[ent.Type], cleanCntnt, mdV2Map[ent.Type]) + post
 	case "pre":
 		if ent.Language == "" {
 			return prevText + pre + "```\n" + cleanCntnt + "```" + post
@@ -236,16 +268,75 @@ func writeFinalMarkdownV2(data []uint16, ent MessageEntity, start int64, cntnt s
 	case "custom_emoji":
 		// Yes, custom emoji have a weird little ! at the front
 		// https://core.telegram.org/bots/api#markdownv2-style
-		return prevText + pre + "![" + cleanCntnt + "](tg://emoji?id=" + ent.CustomEmojiId + ")" + post
+		return prevText + pre + "![" + cleanCntnt + "](tg://emoji?id=" + escapeMarkdownV2Link(ent.CustomEmojiId) + ")" + post
 	case "text_mention":
 		return prevText + pre + "[" + cleanCntnt + "](tg://user?id=" + strconv.FormatInt(ent.User.Id, 10) + ")" + post
 	case "text_link":
-		return prevText + pre + "[" + cleanCntnt + "](" + ent.Url + ")" + post
+		return prevText + pre + "[" + cleanCntnt + "](" + escapeMarkdownV2Link(ent.Url) + ")" + post
 	case "blockquote":
 		return prevText + pre + ">" + strings.Join(strings.Split(cleanCntnt, "\n"), "\n>") + post
+	case "expandable_blockquote":
+		return prevText + pre + "**>" + strings.Join(strings.Split(cleanCntnt, "\n"), "\n>") + "||" + post
 	default:
-		return prevText + cntnt
+		// Entities which are automatically detected by telegram (mentions, hashtags, URLs, etc) do not need any
+		// formatting.
+		return concatMarkdownV2(prevText, cntnt)
+	}
+}
+
+// EscapeMarkdownV2 escapes all the characters which have a special meaning in MarkdownV2, so that the input is
+// displayed as-is.
+// See https://core.telegram.org/bots/api#markdownv2-style for more details.
+func EscapeMarkdownV2(s string) string {
+	return escapeContainedMDV1([]rune(s), mdV2SpecialChars)
+}
+
+// mdV2SpecialChars contains all the characters which must be escaped in MarkdownV2 text.
+var mdV2SpecialChars = []rune("\\_*[]()~`>#+-=|{}.!")
+
+// escapeMarkdownV2Entity escapes the text contained within an entity. Code and pre entities only require escaping
+// backticks and backslashes.
+func escapeMarkdownV2Entity(s string, ent MessageEntity) string {
+	switch ent.Type {
+	case "code", "pre":
+		return escapeContainedMDV1([]rune(s), []rune("\\`"))
+	default:
+		return EscapeMarkdownV2(s)
+	}
+}
+
+// escapeMarkdownV2Link escapes the contents of the (...) part of inline links and custom emoji.
+func escapeMarkdownV2Link(s string) string {
+	return escapeContainedMDV1([]rune(s), []rune("\\)"))
+}
+
+// concatMarkdownV2 joins MarkdownV2 strings.
+// Since "__" is always parsed as underline, an italic marker followed by another underscore is ambiguous; these are
+// separated by an empty bold entity, as recommended by the Telegram docs.
+func concatMarkdownV2(parts ...string) string {
+	bd := strings.Builder{}
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		if strings.HasPrefix(p, "_") && endsWithMarkdownV2Marker(bd.String(), '_') {
+			bd.WriteString("**")
+		}
+		bd.WriteString(p)
+	}
+	return bd.String()
+}
+
+// endsWithMarkdownV2Marker checks whether a MarkdownV2 string ends with an unescaped marker character.
+func endsWithMarkdownV2Marker(s string, marker byte) bool {
+	if !strings.HasSuffix(s, string(marker)) {
+		return false
+	}
+	backslashes := 0
+	for i := len(s) - 2; i >= 0 && s[i] == '\\'; i-- {
+		backslashes++
 	}
+	return backslashes%2 == 0
 }
 
 func getUpperEntities(ents []MessageEntity) []MessageEntity {
@@ -0,0 +1,206 @@
+package gotgbot
+
+import (
+	"testing"
+)
+
+func TestOriginalFormatting(t *testing.T) {
+	for name, testParams := range map[string]struct {
+		text     string
+		entities []MessageEntity
+		md       string
+		mdV2     string
+		html     string
+	}{
+		"no entities": {
+			text: "1 < 2_3*4.",
+			md:   `1 < 2\_3\*4.`,
+			mdV2: `1 < 2\_3\*4\.`,
+			html: `1 &lt; 2_3*4.`,
+		},
+		"simple styles": {
+			text: "bold italic underline strike spoiler",
+			entities: []MessageEntity{
+				{Type: "bold", Offset: 0, Length: 4},
+				{Type: "italic", Offset: 5, Length: 6},
+				{Type: "underline", Offset: 12, Length: 9},
+				{Type: "strikethrough", Offset: 22, Length: 6},
+				{Type: "spoiler", Offset: 29, Length: 7},
+			},
+			md:   `*bold* _italic_ underline strike spoiler`,
+			mdV2: `*bold* _italic_ __underline__ ~strike~ ||spoiler||`,
+			html: `<b>bold</b> <i>italic</i> <u>underline</u> <s>strike</s> <span class="tg-spoiler">spoiler</span>`,
+		},
+		"nested within unsupported markdown entity": {
+			text: "some bold text",
+			entities: []MessageEntity{
+				{Type: "underline", Offset: 0, Length: 14},
+				{Type: "bold", Offset: 5, Length: 4},
+			},
+			md:   `some *bold* text`,
+			mdV2: `__some *bold* text__`,
+			html: `<u>some <b>bold</b> text</u>`,
+		},
+		"snake case in italics": {
+			text: "snake_case",
+			entities: []MessageEntity{
+				{Type: "italic", Offset: 0, Length: 10},
+			},
+			md:   `_snake_\__case_`,
+			mdV2: `_snake\_case_`,
+			html: `<i>snake_case</i>`,
+		},
+		"code and pre": {
+			text: "a `b` c\nfmt.Println()",
+			entities: []MessageEntity{
+				{Type: "code", Offset: 0, Length: 7},
+				{Type: "pre", Offset: 8, Length: 13, Language: "go"},
+			},
+			md:   "`a `\\``b`\\`` c`\n```go\nfmt.Println()```",
+			mdV2: "`a \\`b\\` c`\n```go\nfmt.Println()```",
+			html: "<code>a `b` c</code>\n<pre><code class=\"language-go\">fmt.Println()</code></pre>",
+		},
+		"links and mentions": {
+			text: "link user",
+			entities: []MessageEntity{
+				{Type: "text_link", Offset: 0, Length: 4, Url: "https://example.com/?q=\"a\"&b=(c)"},
+				{Type: "text_mention", Offset: 5, Length: 4, User: &User{Id: 123}},
+			},
+			md:   "[link](https://example.com/?q=\"a\"&b=(c)) [user](tg://user?id=123)",
+			mdV2: "[link](https://example.com/?q=\"a\"&b=(c\\)) [user](tg://user?id=123)",
+			html: `<a href="https://example.com/?q=&#34;a&#34;&amp;b=(c)">link</a> <a href="tg://user?id=123">user</a>`,
+		},
+		"custom emoji": {
+			text: "👍 ok",
+			entities: []MessageEntity{
+				{Type: "custom_emoji", Offset: 0, Length: 2, CustomEmojiId: "5368324170671202286"},
+			},
+			md:   "👍 ok",
+			mdV2: "![👍](tg://emoji?id=5368324170671202286) ok",
+			html: `<tg-emoji emoji-id="5368324170671202286">👍</tg-emoji> ok`,
+		},
+		"blockquotes": {
+			text: "quote\nline\nexpandable\nline",
+			entities: []MessageEntity{
+				{Type: "blockquote", Offset: 0, Length: 11},
+				{Type: "expandable_blockquote", Offset: 11, Length: 15},
+			},
+			md:   "quote\nline\nexpandable\nline",
+			mdV2: ">quote\n>line\n**>expandable\n>line||",
+			html: "<blockquote>quote\nline\n</blockquote><blockquote expandable>expandable\nline</blockquote>",
+		},
+		"automatic entities": {
+			text: "hi @user, see https://example.com #tag",
+			entities: []MessageEntity{
+				{Type: "mention", Offset: 3, Length: 5},
+				{Type: "url", Offset: 14, Length: 19},
+				{Type: "hashtag", Offset: 34, Length: 4},
+			},
+			md:   "hi @user, see https://example.com #tag",
+			mdV2: `hi @user, see https://example\.com \#tag`,
+			html: "hi @user, see https://example.com #tag",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := Message{Text: testParams.text, Entities: testParams.entities}
+			if out := m.OriginalMD(); out != testParams.md {
+				t.Errorf("unexpected markdown:\n%s\nexpected:\n%s", out, testParams.md)
+			}
+			if out := m.OriginalMDV2(); out != testParams.mdV2 {
+				t.Errorf("unexpected markdownV2:\n%s\nexpected:\n%s", out, testParams.mdV2)
+			}
+			if out := m.OriginalHTML(); out != testParams.html {
+				t.Errorf("unexpected HTML:\n%s\nexpected:\n%s", out, testParams.html)
+			}
+		})
+	}
+}
+
+// TestOriginalFormattingEscaping checks the escaping of the OriginalMDV2 and OriginalHTML output. Older versions wrote
+// the message contents as-is, which telegram failed to parse whenever they contained special characters; the
+// previous output is kept alongside the expected one to document the change.
+func TestOriginalFormattingEscaping(t *testing.T) {
+	for name, testParams := range map[string]struct {
+		text     string
+		entities []MessageEntity
+		// oldMDV2 and oldHTML are the outputs of older versions.
+		oldMDV2 string
+		oldHTML string
+		mdV2    string
+		html    string
+	}{
+		"no special characters": {
+			text:     "hello world",
+			entities: []MessageEntity{{Type: "bold", Offset: 6, Length: 5}},
+			oldMDV2:  "hello *world*",
+			oldHTML:  "hello <b>world</b>",
+			mdV2:     "hello *world*",
+			html:     "hello <b>world</b>",
+		},
+		"plain text": {
+			text:    "1+1=2.",
+			oldMDV2: "1+1=2.",
+			oldHTML: "1+1=2.",
+			mdV2:    `1\+1\=2\.`,
+			html:    "1+1=2.",
+		},
+		"text around and within entities": {
+			text:     "a-b c_d!",
+			entities: []MessageEntity{{Type: "bold", Offset: 4, Length: 3}},
+			oldMDV2:  "a-b *c_d*!",
+			oldHTML:  "a-b <b>c_d</b>!",
+			mdV2:     `a\-b *c\_d*\!`,
+			html:     "a-b <b>c_d</b>!",
+		},
+		"code": {
+			text:     "run `ls` now",
+			entities: []MessageEntity{{Type: "code", Offset: 4, Length: 4}},
+			oldMDV2:  "run ``ls`` now",
+			oldHTML:  "run <code>`ls`</code> now",
+			mdV2:     "run `\\`ls\\`` now",
+			html:     "run <code>`ls`</code> now",
+		},
+		"pre with language": {
+			text:     "x := 1",
+			entities: []MessageEntity{{Type: "pre", Offset: 0, Length: 6, Language: "go"}},
+			oldMDV2:  "```go\nx := 1```",
+			oldHTML:  `<pre><code class="go">x := 1</code></pre>`,
+			mdV2:     "```go\nx := 1```",
+			html:     `<pre><code class="language-go">x := 1</code></pre>`,
+		},
+		"link": {
+			text:     "docs",
+			entities: []MessageEntity{{Type: "text_link", Offset: 0, Length: 4, Url: "https://example.com/?a=(1)&b=2"}},
+			oldMDV2:  "[docs](https://example.com/?a=(1)&b=2)",
+			oldHTML:  `<a href="https://example.com/?a=(1)&b=2">docs</a>`,
+			mdV2:     `[docs](https://example.com/?a=(1\)&b=2)`,
+			html:     `<a href="https://example.com/?a=(1)&amp;b=2">docs</a>`,
+		},
+		"italic followed by underline": {
+			text:     "ab",
+			entities: []MessageEntity{{Type: "italic", Offset: 0, Length: 1}, {Type: "underline", Offset: 1, Length: 1}},
+			oldMDV2:  "_a___b__",
+			oldHTML:  "<i>a</i><u>b</u>",
+			mdV2:     "_a_**__b__",
+			html:     "<i>a</i><u>b</u>",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := Message{Text: testParams.text, Entities: testParams.entities}
+			if out := m.OriginalMDV2(); out != testParams.mdV2 {
+				t.Errorf("unexpected MarkdownV2 output:\n%s\nexpected:\n%s\nolder versions returned:\n%s", out, testParams.mdV2, testParams.oldMDV2)
+			}
+			if out := m.OriginalHTML(); out != testParams.html {
+				t.Errorf("unexpected HTML output:\n%s\nexpected:\n%s\nolder versions returned:\n%s", out, testParams.html, testParams.oldHTML)
+			}
+		})
+	}
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	in := `_*[]()~` + "`" + `>#+-=|{}.!\`
+	expected := `\_\*\[\]\(\)\~\` + "`" + `\>\#\+\-\=\|\{\}\.\!\\`
+	if out := EscapeMarkdownV2(in); out != expected {
+		t.Errorf("unexpected escaped value: %s", out)
+	}
+}
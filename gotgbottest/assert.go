@@ -0,0 +1,79 @@
+package gotgbottest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// AssertCalled fails the test if no call to the method with the given params was received, and returns the latest
+// matching call. Only the given params are compared; other params of the call are ignored.
+func (r *Recorder) AssertCalled(t testing.TB, method string, params map[string]string) Call {
+	t.Helper()
+
+	calls := r.CallsTo(method)
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i].matches(method, params) {
+			return calls[i]
+		}
+	}
+
+	t.Errorf("expected call to %s with params %v; got calls:\n%s", method, params, describeCalls(calls))
+	return Call{}
+}
+
+// AssertNotCalled fails the test if any call to the method was received.
+func (r *Recorder) AssertNotCalled(t testing.TB, method string) {
+	t.Helper()
+
+	if calls := r.CallsTo(method); len(calls) > 0 {
+		t.Errorf("expected no calls to %s; got calls:\n%s", method, describeCalls(calls))
+	}
+}
+
+// AssertCallCount fails the test if the method wasn't called exactly n times.
+func (r *Recorder) AssertCallCount(t testing.TB, method string, n int) {
+	t.Helper()
+
+	if calls := r.CallsTo(method); len(calls) != n {
+		t.Errorf("expected %d calls to %s, got %d:\n%s", n, method, len(calls), describeCalls(calls))
+	}
+}
+
+// AssertSentMessage fails the test if no message with the given text was sent to the chat, and returns the latest
+// matching call.
+func (r *Recorder) AssertSentMessage(t testing.TB, chatId int64, text string) Call {
+	t.Helper()
+
+	return r.AssertCalled(t, "sendMessage", map[string]string{
+		"chat_id": strconv.FormatInt(chatId, 10),
+		"text":    text,
+	})
+}
+
+// describeCalls returns a readable description of calls, for test failures.
+func describeCalls(calls []Call) string {
+	if len(calls) == 0 {
+		return "\t(none)"
+	}
+
+	var sb strings.Builder
+	for _, c := range calls {
+		keys := make([]string, 0, len(c.Params))
+		for k := range c.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		sb.WriteString("\t" + c.Method + "(")
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(k + "=" + strconv.Quote(c.Params[k]))
+		}
+		sb.WriteString(")\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,119 @@
+package gotgbottest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+)
+
+// Client is an in-memory gotgbot.BotClient, for unit testing handlers without any HTTP requests.
+//
+// Every method call is recorded, and can be inspected with Calls or the assertion helpers. Method results can be
+// configured with Handle and HandleOnce; by default:
+//   - getMe returns the client's BotUser.
+//   - send*, forwardMessage and copyMessage return a new Message in the requested chat.
+//   - sendMediaGroup returns a new Message for each of the media, and sendChatAction returns true.
+//   - forwardMessages and copyMessages return a new MessageId for each of the messages.
+//   - all other methods return true.
+type Client struct {
+	Recorder
+}
+
+var _ gotgbot.BotClient = &Client{}
+
+// NewClient creates a new in-memory Client.
+func NewClient() *Client {
+	return &Client{
+		Recorder: newRecorder(),
+	}
+}
+
+// Bot returns a bot using the client, with the DefaultToken.
+func (c *Client) Bot() *gotgbot.Bot {
+	return &gotgbot.Bot{
+		Token:     DefaultToken,
+		User:      c.BotUser,
+		BotClient: c,
+	}
+}
+
+// RequestWithContext records the method call, and returns the configured result.
+func (c *Client) RequestWithContext(ctx context.Context, token string, method string, params map[string]string, data map[string]gotgbot.NamedReader, opts *gotgbot.RequestOpts) (json.RawMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to execute request to %s: %w", method, err)
+	}
+
+	call := Call{
+		Token:  token,
+		Method: method,
+		Params: map[string]string{},
+		Files:  map[string]File{},
+	}
+	for k, v := range params {
+		// Drop empty values, to match the params received by the Server.
+		if v != "" {
+			call.Params[k] = v
+		}
+	}
+	for field, file := range data {
+		fileName := file.Name()
+		if fileName == "" {
+			fileName = field
+		}
+		bs, err := io.ReadAll(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file contents of field %s: %w", field, err)
+		}
+		call.Files[field] = File{Name: fileName, Data: bs}
+	}
+
+	var result interface{}
+	var err error
+	if responder := c.record(call); responder != nil {
+		result, err = responder(call)
+	} else {
+		result = c.defaultResponse(call)
+	}
+
+	if err != nil {
+		var tgErr *gotgbot.TelegramError
+		if !errors.As(err, &tgErr) {
+			tgErr = &gotgbot.TelegramError{Code: http.StatusInternalServerError, Description: "Internal Server Error: " + err.Error()}
+		}
+		// Copy the error, to set the call details as the BaseBotClient would.
+		e := *tgErr
+		e.Method = method
+		e.Params = params
+		return nil, &e
+	}
+
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result of %s: %w", method, err)
+	}
+	return bs, nil
+}
+
+// TimeoutContext returns a cancellable context; requests to the Client never time out.
+func (c *Client) TimeoutContext(opts *gotgbot.RequestOpts) (context.Context, context.CancelFunc) {
+	return context.WithCancel(context.Background())
+}
+
+// GetAPIURL returns the API URL defined in the request opts, or the default API URL.
+func (c *Client) GetAPIURL(opts *gotgbot.RequestOpts) string {
+	if opts != nil && opts.APIURL != "" {
+		return strings.TrimSuffix(opts.APIURL, "/")
+	}
+	return gotgbot.DefaultAPIURL
+}
+
+// FileURL returns the URL a file would be downloaded from.
+func (c *Client) FileURL(token string, tgFilePath string, opts *gotgbot.RequestOpts) string {
+	return fmt.Sprintf("%s/file/bot%s/%s", c.GetAPIURL(opts), token, tgFilePath)
+}
@@ -0,0 +1,117 @@
+package gotgbottest_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
+	"github.com/PaulSonOfLars/gotgbot/v2/gotgbottest"
+)
+
+func TestClientHandlers(t *testing.T) {
+	c := gotgbottest.NewClient()
+	b := c.Bot()
+
+	c.RespondWith("getChat", gotgbot.Chat{Id: -100, Type: "supergroup", Title: "Custom Chat"})
+
+	start := handlers.NewCommand("start", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		chat, err := b.GetChat(ctx.EffectiveChat.Id, nil)
+		if err != nil {
+			return err
+		}
+		_, err = ctx.EffectiveMessage.Reply(b, "Welcome to "+chat.Title, nil)
+		return err
+	})
+	ctx := gotgbottest.NewTextMessage(1, -100, "/start now")
+	if !start.CheckUpdate(b, ctx) {
+		t.Fatal("command should match canned command message")
+	}
+	if err := start.HandleUpdate(b, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.AssertSentMessage(t, -100, "Welcome to Custom Chat")
+
+	cb := handlers.NewCallback(callbackquery.Equal("ok"), func(b *gotgbot.Bot, ctx *ext.Context) error {
+		_, err := ctx.CallbackQuery.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: "done"})
+		return err
+	})
+	ctx = gotgbottest.NewCallbackQuery(1, 1, "ok")
+	if !cb.CheckUpdate(b, ctx) {
+		t.Fatal("callback handler should match canned callback query")
+	}
+	c.HandleOnce("answerCallbackQuery", func(gotgbottest.Call) (interface{}, error) {
+		return nil, gotgbottest.ErrBadRequest("query is too old")
+	})
+	err := cb.HandleUpdate(b, ctx)
+	var tgErr *gotgbot.TelegramError
+	if !errors.As(err, &tgErr) || tgErr.Code != http.StatusBadRequest || tgErr.Method != "answerCallbackQuery" {
+		t.Errorf("expected bad request error, got %v", err)
+	}
+	c.AssertCalled(t, "answerCallbackQuery", map[string]string{"callback_query_id": ctx.CallbackQuery.Id, "text": "done"})
+
+	join := handlers.NewChatJoinRequest(nil, func(b *gotgbot.Bot, ctx *ext.Context) error {
+		_, err := b.ApproveChatJoinRequest(ctx.EffectiveChat.Id, ctx.EffectiveUser.Id, nil)
+		return err
+	})
+	ctx = gotgbottest.NewChatJoinRequest(2, -200)
+	if !join.CheckUpdate(b, ctx) {
+		t.Fatal("join request handler should match canned join request")
+	}
+	if err := join.HandleUpdate(b, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.AssertCalled(t, "approveChatJoinRequest", map[string]string{"chat_id": "-200", "user_id": "2"})
+}
+
+func TestClientFiles(t *testing.T) {
+	c := gotgbottest.NewClient()
+	b := c.Bot()
+
+	_, err := b.SendPhoto(1, gotgbot.NamedFile{FileName: "cat.jpg", File: strings.NewReader("meow")}, nil)
+	if err != nil {
+		t.Fatalf("failed to send photo: %v", err)
+	}
+
+	call := c.AssertCalled(t, "sendPhoto", map[string]string{"chat_id": "1"})
+	if f := call.Files["photo"]; f.Name != "cat.jpg" || string(f.Data) != "meow" {
+		t.Errorf("unexpected recorded files: %+v", call.Files)
+	}
+}
+
+func TestDefaultResponses(t *testing.T) {
+	s := gotgbottest.NewServer()
+	defer s.Close()
+
+	for name, b := range map[string]*gotgbot.Bot{"client": gotgbottest.NewClient().Bot(), "server": s.Bot()} {
+		t.Run(name, func(t *testing.T) {
+			ok, err := b.SendChatAction(1, "typing", nil)
+			if err != nil || !ok {
+				t.Errorf("expected sendChatAction to return true, got %v (%v)", ok, err)
+			}
+
+			msgs, err := b.SendMediaGroup(1, []gotgbot.InputMedia{
+				gotgbot.InputMediaPhoto{Media: "photo1", Caption: "first"},
+				gotgbot.InputMediaPhoto{Media: "photo2"},
+			}, nil)
+			if err != nil {
+				t.Fatalf("failed to send media group: %v", err)
+			}
+			if len(msgs) != 2 || msgs[0].Caption != "first" || msgs[0].Chat.Id != 1 || msgs[0].MessageId == msgs[1].MessageId {
+				t.Errorf("unexpected media group messages: %+v", msgs)
+			}
+
+			ids, err := b.CopyMessages(2, 1, []int64{msgs[0].MessageId, msgs[1].MessageId}, nil)
+			if err != nil {
+				t.Fatalf("failed to copy messages: %v", err)
+			}
+			if len(ids) != 2 || ids[0].MessageId == 0 {
+				t.Errorf("unexpected copied message IDs: %+v", ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package gotgbottest
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf16"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+var (
+	// lastMessageId is used to give each canned message a unique ID.
+	lastMessageId int64
+	// lastCallbackQueryId is used to give each canned callback query a unique ID.
+	lastCallbackQueryId int64
+)
+
+// NewContext creates a new ext.Context from an update, as the Dispatcher would.
+func NewContext(upd gotgbot.Update) *ext.Context {
+	return ext.NewContext(&upd, nil)
+}
+
+// NewTextMessage creates a context for a text message sent by the user in the chat. See TextMessageUpdate.
+func NewTextMessage(userId int64, chatId int64, text string) *ext.Context {
+	return NewContext(TextMessageUpdate(userId, chatId, text))
+}
+
+// NewCallbackQuery creates a context for a callback query sent by the user, from a bot message in the chat.
+// See CallbackQueryUpdate.
+func NewCallbackQuery(userId int64, chatId int64, data string) *ext.Context {
+	return NewContext(CallbackQueryUpdate(userId, chatId, data))
+}
+
+// NewChatJoinRequest creates a context for a request by the user to join the chat. See ChatJoinRequestUpdate.
+func NewChatJoinRequest(userId int64, chatId int64) *ext.Context {
+	return NewContext(ChatJoinRequestUpdate(userId, chatId))
+}
+
+// TextMessageUpdate creates an update for a text message sent by the user in the chat. If the text starts with a
+// forward-slash, the first word is marked as a bot command.
+// The chat is a private chat if the user and chat IDs are the same, and a supergroup otherwise.
+// The update ID is left unset, so it can be assigned by Server.QueueUpdate.
+func TextMessageUpdate(userId int64, chatId int64, text string) gotgbot.Update {
+	var entities []gotgbot.MessageEntity
+	if strings.HasPrefix(text, "/") {
+		cmd := strings.Fields(text)[0]
+		entities = []gotgbot.MessageEntity{{
+			Type:   "bot_command",
+			Offset: 0,
+			Length: int64(len(utf16.Encode([]rune(cmd)))),
+		}}
+	}
+
+	return gotgbot.Update{
+		Message: &gotgbot.Message{
+			MessageId: atomic.AddInt64(&lastMessageId, 1),
+			From:      newUser(userId),
+			Date:      time.Now().Unix(),
+			Chat:      newChat(userId, chatId),
+			Text:      text,
+			Entities:  entities,
+		},
+	}
+}
+
+// CallbackQueryUpdate creates an update for a callback query sent by the user, from a bot message in the chat.
+func CallbackQueryUpdate(userId int64, chatId int64, data string) gotgbot.Update {
+	msgId := atomic.AddInt64(&lastMessageId, 1)
+	return gotgbot.Update{
+		CallbackQuery: &gotgbot.CallbackQuery{
+			Id:   strconv.FormatInt(atomic.AddInt64(&lastCallbackQueryId, 1), 10),
+			From: *newUser(userId),
+			Message: gotgbot.Message{
+				MessageId: msgId,
+				Date:      time.Now().Unix(),
+				Chat:      newChat(userId, chatId),
+			},
+			ChatInstance: "chat_instance",
+			Data:         data,
+		},
+	}
+}
+
+// ChatJoinRequestUpdate creates an update for a request by the user to join the chat.
+func ChatJoinRequestUpdate(userId int64, chatId int64) gotgbot.Update {
+	return gotgbot.Update{
+		ChatJoinRequest: &gotgbot.ChatJoinRequest{
+			Chat:       newChat(0, chatId),
+			From:       *newUser(userId),
+			UserChatId: userId,
+			Date:       time.Now().Unix(),
+		},
+	}
+}
+
+func newUser(userId int64) *gotgbot.User {
+	return &gotgbot.User{
+		Id:        userId,
+		FirstName: "Test User",
+		Username:  "test_user",
+	}
+}
+
+func newChat(userId int64, chatId int64) gotgbot.Chat {
+	if userId == chatId {
+		return gotgbot.Chat{Id: chatId, Type: "private", FirstName: "Test User"}
+	}
+	return gotgbot.Chat{Id: chatId, Type: "supergroup", Title: "Test Chat"}
+}
@@ -0,0 +1,257 @@
+package gotgbottest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+const (
+	// EntryTypeUpdate is the type of recorded entries containing an incoming update.
+	EntryTypeUpdate = "update"
+	// EntryTypeCall is the type of recorded entries containing an outgoing method call, and its response.
+	EntryTypeCall = "call"
+
+	// redactedToken replaces the bot token in recordings.
+	redactedToken = "<TOKEN>"
+)
+
+// RecordedFile describes a file uploaded as part of a recorded call. Only a hash of the file contents is recorded.
+type RecordedFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// RecordedError is the telegram error returned by a recorded call.
+type RecordedError struct {
+	Code        int                         `json:"code"`
+	Description string                      `json:"description"`
+	Parameters  *gotgbot.ResponseParameters `json:"parameters,omitempty"`
+}
+
+// RecordEntry is a single line of a recording.
+type RecordEntry struct {
+	// Type is either EntryTypeUpdate or EntryTypeCall.
+	Type string `json:"type"`
+	// Update is the raw incoming update, for EntryTypeUpdate entries.
+	Update json.RawMessage `json:"update,omitempty"`
+	// The following fields are set for EntryTypeCall entries.
+	Method string                  `json:"method,omitempty"`
+	Params map[string]string       `json:"params,omitempty"`
+	Files  map[string]RecordedFile `json:"files,omitempty"`
+	Result json.RawMessage         `json:"result,omitempty"`
+	Error  *RecordedError          `json:"error,omitempty"`
+}
+
+// Recording records live bot traffic as JSON lines, to be replayed with Replay: the updates received by a
+// dispatcher wrapped with Dispatcher, and the method calls made by a client wrapped with Client.
+//
+// The bot token is redacted from everything recorded, and uploaded files are replaced by a hash of their contents.
+// Further redaction can be done with the Redact hook.
+//
+// Recording errors do not affect the bot: calls return their results, and updates are processed, as if they weren't
+// recorded. Errors are logged to ErrorLog instead.
+type Recording struct {
+	// Redact is called on each entry before it is written, to allow for redacting sensitive data.
+	Redact func(entry *RecordEntry)
+	// ErrorLog specifies an optional logger for errors writing entries.
+	// If nil, logging is done via the log package's standard logger.
+	ErrorLog *log.Logger
+
+	w io.Writer
+	// lock ensures entries are written one at a time.
+	lock sync.Mutex
+}
+
+// NewRecording creates a new Recording, writing to w.
+func NewRecording(w io.Writer) *Recording {
+	return &Recording{w: w}
+}
+
+// Client wraps a BotClient, recording all the calls it makes.
+func (r *Recording) Client(c gotgbot.BotClient) gotgbot.BotClient {
+	return &recordingClient{BotClient: c, recording: r}
+}
+
+// Dispatcher wraps an UpdateDispatcher, recording all the raw updates it receives from the Updater.
+func (r *Recording) Dispatcher(d ext.UpdateDispatcher) ext.UpdateDispatcher {
+	return &recordingDispatcher{UpdateDispatcher: d, recording: r}
+}
+
+// write redacts and writes an entry, logging any errors.
+func (r *Recording) write(token string, entry RecordEntry) {
+	if err := r.writeEntry(token, entry); err != nil {
+		r.logf("failed to record %s entry: %s", entry.Type, err.Error())
+	}
+}
+
+func (r *Recording) writeEntry(token string, entry RecordEntry) error {
+	redactEntry(&entry, token)
+	if r.Redact != nil {
+		r.Redact(&entry)
+	}
+
+	// Keep the recording readable; there's no HTML to escape.
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		return fmt.Errorf("failed to marshal recorded entry: %w", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	_, err := r.w.Write(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to write recorded entry: %w", err)
+	}
+	return nil
+}
+
+func (r *Recording) logf(format string, args ...interface{}) {
+	if r.ErrorLog != nil {
+		r.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// redactEntry removes any occurrence of the bot token from the entry.
+func redactEntry(entry *RecordEntry, token string) {
+	if token == "" {
+		return
+	}
+
+	// Also redact the secret part of the token, in case it appears on its own.
+	secrets := []string{token}
+	if _, secret, ok := strings.Cut(token, ":"); ok && secret != "" {
+		secrets = append(secrets, secret)
+	}
+
+	redact := func(s string) string {
+		for _, secret := range secrets {
+			s = strings.ReplaceAll(s, secret, redactedToken)
+		}
+		return s
+	}
+
+	for k, v := range entry.Params {
+		entry.Params[k] = redact(v)
+	}
+	if entry.Error != nil {
+		entry.Error.Description = redact(entry.Error.Description)
+	}
+	if entry.Result != nil {
+		entry.Result = json.RawMessage(redact(string(entry.Result)))
+	}
+	if entry.Update != nil {
+		entry.Update = json.RawMessage(redact(string(entry.Update)))
+	}
+}
+
+// recordingClient records the calls made by the wrapped BotClient.
+type recordingClient struct {
+	gotgbot.BotClient
+	recording *Recording
+}
+
+func (c *recordingClient) RequestWithContext(ctx context.Context, token string, method string, params map[string]string, data map[string]gotgbot.NamedReader, opts *gotgbot.RequestOpts) (json.RawMessage, error) {
+	entry := RecordEntry{
+		Type:   EntryTypeCall,
+		Method: method,
+		Params: map[string]string{},
+	}
+	for k, v := range params {
+		if v != "" {
+			entry.Params[k] = v
+		}
+	}
+
+	if len(data) > 0 {
+		// Files are read into memory, so that they can be both hashed and sent.
+		entry.Files = map[string]RecordedFile{}
+		buffered := make(map[string]gotgbot.NamedReader, len(data))
+		for field, file := range data {
+			bs, err := io.ReadAll(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file contents of field %s: %w", field, err)
+			}
+			buffered[field] = gotgbot.NamedFile{File: bytes.NewReader(bs), FileName: file.Name()}
+
+			fileName := file.Name()
+			if fileName == "" {
+				fileName = field
+			}
+			sum := sha256.Sum256(bs)
+			entry.Files[field] = RecordedFile{Name: fileName, SHA256: hex.EncodeToString(sum[:])}
+		}
+		data = buffered
+	}
+
+	result, err := c.BotClient.RequestWithContext(ctx, token, method, params, data, opts)
+	if err != nil {
+		var tgErr *gotgbot.TelegramError
+		if !errors.As(err, &tgErr) {
+			// Network errors and the like aren't API responses; there's nothing to replay.
+			return result, err
+		}
+		entry.Error = &RecordedError{
+			Code:        tgErr.Code,
+			Description: tgErr.Description,
+			Parameters:  tgErr.ResponseParams,
+		}
+	} else {
+		entry.Result = result
+	}
+
+	c.recording.write(token, entry)
+	return result, err
+}
+
+// recordingDispatcher records the raw updates received by the wrapped UpdateDispatcher.
+type recordingDispatcher struct {
+	ext.UpdateDispatcher
+	recording *Recording
+}
+
+var _ ext.TrackingUpdateDispatcher = &recordingDispatcher{}
+
+func (d *recordingDispatcher) Start(b *gotgbot.Bot, updates <-chan json.RawMessage) {
+	d.StartTracking(b, updates, nil)
+}
+
+// StartTracking records the updates in the same way as Start. If the wrapped dispatcher doesn't track updates, they
+// are reported as processed once they have been handed over to it.
+func (d *recordingDispatcher) StartTracking(b *gotgbot.Bot, updates <-chan json.RawMessage, done func(update json.RawMessage)) {
+	td, tracking := d.UpdateDispatcher.(ext.TrackingUpdateDispatcher)
+
+	recorded := make(chan json.RawMessage)
+	go func() {
+		defer close(recorded)
+		for upd := range updates {
+			d.recording.write(b.Token, RecordEntry{Type: EntryTypeUpdate, Update: upd})
+			recorded <- upd
+			if !tracking && done != nil {
+				done(upd)
+			}
+		}
+	}()
+
+	if tracking {
+		td.StartTracking(b, recorded, done)
+		return
+	}
+	d.UpdateDispatcher.Start(b, recorded)
+}
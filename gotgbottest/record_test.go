@@ -0,0 +1,274 @@
+package gotgbottest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/gotgbottest"
+)
+
+func greetDispatcher(greeting string) *ext.Dispatcher {
+	d := ext.NewDispatcher(&ext.DispatcherOpts{
+		Error: func(b *gotgbot.Bot, ctx *ext.Context, err error) ext.DispatcherAction {
+			return ext.DispatcherActionNoop
+		},
+	})
+	d.AddHandler(handlers.NewCommand("start", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		chat, err := b.GetChat(ctx.EffectiveChat.Id, nil)
+		if err != nil {
+			_, err = ctx.EffectiveMessage.Reply(b, "Unknown chat", nil)
+			return err
+		}
+		_, err = ctx.EffectiveMessage.Reply(b, greeting+", "+chat.Title, nil)
+		return err
+	}))
+	return d
+}
+
+func recordSession(t *testing.T) []byte {
+	s := gotgbottest.NewServer()
+	defer s.Close()
+	s.HandleOnce("getChat", func(gotgbottest.Call) (interface{}, error) {
+		return gotgbot.Chat{Id: -100, Type: "supergroup", Title: "Gophers"}, nil
+	})
+	s.HandleOnce("getChat", func(gotgbottest.Call) (interface{}, error) {
+		return nil, gotgbottest.ErrBadRequest("chat not found")
+	})
+
+	buf := &bytes.Buffer{}
+	rec := gotgbottest.NewRecording(buf)
+	b := s.Bot()
+	b.BotClient = rec.Client(b.BotClient)
+
+	u := ext.NewUpdater(rec.Dispatcher(greetDispatcher("Hello")), nil)
+	err := u.StartPolling(b, &ext.PollingOpts{
+		GetUpdatesOpts: &gotgbot.GetUpdatesOpts{
+			Timeout: 1,
+			RequestOpts: &gotgbot.RequestOpts{
+				Timeout: 2 * time.Second,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to start polling: %v", err)
+	}
+
+	s.QueueUpdate(gotgbottest.TextMessageUpdate(1, -100, "/start"))
+	if _, ok := s.WaitForCall("sendMessage", map[string]string{"text": "Hello, Gophers"}, 5*time.Second); !ok {
+		t.Fatal("expected the bot to reply")
+	}
+	s.QueueUpdate(gotgbottest.TextMessageUpdate(1, -200, "/start"))
+	if _, ok := s.WaitForCall("sendMessage", map[string]string{"text": "Unknown chat"}, 5*time.Second); !ok {
+		t.Fatal("expected the bot to reply")
+	}
+
+	if err := u.Stop(); err != nil {
+		t.Fatalf("failed to stop updater: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRecording(t *testing.T) {
+	recording := recordSession(t)
+
+	if strings.Contains(string(recording), "TEST_TOKEN") {
+		t.Errorf("recording should not contain the token:\n%s", recording)
+	}
+
+	entries, err := gotgbottest.ReadRecording(bytes.NewReader(recording))
+	if err != nil {
+		t.Fatalf("failed to read recording: %v", err)
+	}
+	var updates, sent int
+	var chatErr *gotgbottest.RecordedError
+	for _, e := range entries {
+		switch {
+		case e.Type == gotgbottest.EntryTypeUpdate:
+			updates++
+		case e.Method == "sendMessage":
+			sent++
+		case e.Method == "getChat" && e.Error != nil:
+			chatErr = e.Error
+		}
+	}
+	if updates != 2 || sent != 2 {
+		t.Errorf("expected 2 updates and 2 messages, got %d and %d", updates, sent)
+	}
+	if chatErr == nil || chatErr.Code != 400 || chatErr.Description != "Bad Request: chat not found" {
+		t.Errorf("expected recorded getChat error, got %+v", chatErr)
+	}
+}
+
+func TestRecordingRedact(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rec := gotgbottest.NewRecording(buf)
+	rec.Redact = func(e *gotgbottest.RecordEntry) {
+		if e.Params["text"] != "" {
+			e.Params["text"] = "<TEXT>"
+		}
+	}
+
+	c := gotgbottest.NewClient()
+	b := c.Bot()
+	b.BotClient = rec.Client(c)
+
+	_, err := b.SendMessage(1, "my secret is "+strings.Split(gotgbottest.DefaultToken, ":")[1], nil)
+	if err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	_, err = b.SendDocument(1, gotgbot.NamedFile{FileName: "notes.txt", File: strings.NewReader("hello")}, nil)
+	if err != nil {
+		t.Fatalf("failed to send document: %v", err)
+	}
+
+	// Files are still sent in full.
+	if call := c.AssertCalled(t, "sendDocument", nil); string(call.Files["document"].Data) != "hello" {
+		t.Errorf("unexpected file sent: %+v", call.Files)
+	}
+
+	entries, err := gotgbottest.ReadRecording(buf)
+	if err != nil {
+		t.Fatalf("failed to read recording: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if text := entries[0].Params["text"]; text != "<TEXT>" {
+		t.Errorf("expected text to be redacted, got %q", text)
+	}
+	if !strings.Contains(string(entries[0].Result), "my secret is <TOKEN>") {
+		t.Errorf("expected token to be redacted from result, got %s", entries[0].Result)
+	}
+	doc := entries[1].Files["document"]
+	if doc.Name != "notes.txt" || doc.SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("unexpected recorded file: %+v", doc)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRecordingWriteError(t *testing.T) {
+	logs := &bytes.Buffer{}
+	rec := gotgbottest.NewRecording(failingWriter{})
+	rec.ErrorLog = log.New(logs, "", 0)
+
+	c := gotgbottest.NewClient()
+	b := c.Bot()
+	b.BotClient = rec.Client(c)
+
+	// Recording errors don't affect the call.
+	msg, err := b.SendMessage(1, "hello", nil)
+	if err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	if msg.Text != "hello" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if !strings.Contains(logs.String(), "disk full") {
+		t.Errorf("expected write error to be logged, got %q", logs.String())
+	}
+}
+
+func TestReplay(t *testing.T) {
+	recording := recordSession(t)
+
+	res, err := gotgbottest.Replay(bytes.NewReader(recording), greetDispatcher("Hello"), nil)
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if !res.OK() {
+		t.Errorf("expected replay to match recording:\n%s", res.Diff())
+	}
+
+	res, err = gotgbottest.Replay(bytes.NewReader(recording), greetDispatcher("Welcome"), nil)
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if len(res.Missing) != 1 || len(res.Unexpected) != 1 {
+		t.Fatalf("expected one changed call, got:\n%s", res.Diff())
+	}
+	diff := res.Diff()
+	if !strings.Contains(diff, "- sendMessage(") || !strings.Contains(diff, "text=Hello, Gophers") ||
+		!strings.Contains(diff, "+ sendMessage(") || !strings.Contains(diff, "text=Welcome, Gophers") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+
+	res, err = gotgbottest.Replay(bytes.NewReader(recording), greetDispatcher("Welcome"), &gotgbottest.ReplayOpts{
+		IgnoreParams: []string{"text"},
+	})
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if !res.OK() {
+		t.Errorf("expected ignored params to match:\n%s", res.Diff())
+	}
+}
+
+func broadcastDispatcher() *ext.Dispatcher {
+	d := ext.NewDispatcher(nil)
+	d.AddHandler(handlers.NewCommand("broadcast", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		// Send to all chats concurrently.
+		wg := sync.WaitGroup{}
+		errs := make(chan error, 10)
+		for i := int64(1); i <= 10; i++ {
+			wg.Add(1)
+			go func(chatId int64) {
+				defer wg.Done()
+				_, err := b.SendMessage(chatId, "news for "+strconv.FormatInt(chatId, 10), nil)
+				errs <- err
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	return d
+}
+
+func TestReplayConcurrentCalls(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rec := gotgbottest.NewRecording(buf)
+	c := gotgbottest.NewClient()
+	b := c.Bot()
+	b.BotClient = rec.Client(c)
+
+	upd, err := json.Marshal(gotgbottest.TextMessageUpdate(1, 1, "/broadcast"))
+	if err != nil {
+		t.Fatalf("failed to marshal update: %v", err)
+	}
+	updates := make(chan json.RawMessage, 1)
+	updates <- upd
+	close(updates)
+
+	d := rec.Dispatcher(broadcastDispatcher())
+	d.Start(b, updates)
+	d.Stop()
+	c.AssertCallCount(t, "sendMessage", 10)
+
+	res, err := gotgbottest.Replay(buf, broadcastDispatcher(), nil)
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if !res.OK() {
+		t.Errorf("expected replay to match recording:\n%s", res.Diff())
+	}
+}
@@ -0,0 +1,276 @@
+package gotgbottest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+)
+
+// DefaultToken is the token of the bots created by Server.Bot and Client.Bot.
+const DefaultToken = "123456789:TEST_TOKEN"
+
+// File is a file uploaded as part of a method call.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Call is a recorded Bot API method call.
+type Call struct {
+	// Token is the token of the bot which made the call.
+	Token string
+	// Method is the Bot API method called; eg, "sendMessage".
+	Method string
+	// Params are the parameters of the call. Object parameters are JSON-encoded, and empty params are omitted.
+	Params map[string]string
+	// Files are the files uploaded with the call, by field name.
+	Files map[string]File
+}
+
+// matches checks whether the call is to the given method, and contains all the given params.
+func (c Call) matches(method string, params map[string]string) bool {
+	if c.Method != method {
+		return false
+	}
+	for k, v := range params {
+		if c.Params[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Responder returns the result of a method call. Returning a *gotgbot.TelegramError returns an error response with
+// the same code, description, and response parameters; any other error is returned as an internal server error.
+type Responder func(call Call) (interface{}, error)
+
+// ErrBadRequest returns a 400 Bad Request error, as returned by telegram.
+func ErrBadRequest(description string) *gotgbot.TelegramError {
+	return &gotgbot.TelegramError{Code: http.StatusBadRequest, Description: "Bad Request: " + description}
+}
+
+// ErrForbidden returns a 403 Forbidden error, as returned by telegram; eg, when the bot was blocked by the user.
+func ErrForbidden(description string) *gotgbot.TelegramError {
+	return &gotgbot.TelegramError{Code: http.StatusForbidden, Description: "Forbidden: " + description}
+}
+
+// ErrTooManyRequests returns a 429 Too Many Requests error, as returned by telegram when the bot is rate limited.
+func ErrTooManyRequests(retryAfter int64) *gotgbot.TelegramError {
+	return &gotgbot.TelegramError{
+		Code:           http.StatusTooManyRequests,
+		Description:    "Too Many Requests: retry after " + strconv.FormatInt(retryAfter, 10),
+		ResponseParams: &gotgbot.ResponseParameters{RetryAfter: retryAfter},
+	}
+}
+
+// Recorder records method calls and their configured responses. It is embedded in both the Server and the Client, to
+// configure responses and make assertions on the calls they received.
+type Recorder struct {
+	// BotUser is the user returned by getMe.
+	BotUser gotgbot.User
+
+	// lock allows us to ensure synchronous access to the fields below.
+	lock sync.Mutex
+	// calls is the list of calls received, in order.
+	calls []Call
+	// callAdded is closed and replaced whenever a call is recorded.
+	callAdded chan struct{}
+	// responders are the persistent responders set with Handle.
+	responders map[string]Responder
+	// onceResponders are the single-use responders set with HandleOnce.
+	onceResponders map[string][]Responder
+	// lastMessageId is the ID of the latest message returned by the default responses.
+	lastMessageId int64
+}
+
+func newRecorder() Recorder {
+	return Recorder{
+		BotUser: gotgbot.User{
+			Id:        123456789,
+			IsBot:     true,
+			FirstName: "Test Bot",
+			Username:  "test_bot",
+		},
+		callAdded:      make(chan struct{}),
+		responders:     map[string]Responder{},
+		onceResponders: map[string][]Responder{},
+	}
+}
+
+// Handle sets the responder for all calls to a method, replacing the default behaviour.
+func (r *Recorder) Handle(method string, resp Responder) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.responders[method] = resp
+}
+
+// HandleOnce sets the responder for the next call to a method. Responders set with HandleOnce are used in order, and
+// take precedence over those set with Handle.
+func (r *Recorder) HandleOnce(method string, resp Responder) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.onceResponders[method] = append(r.onceResponders[method], resp)
+}
+
+// RespondWith sets the result returned by all calls to a method.
+func (r *Recorder) RespondWith(method string, result interface{}) {
+	r.Handle(method, func(Call) (interface{}, error) {
+		return result, nil
+	})
+}
+
+// RespondError sets the error returned by all calls to a method.
+func (r *Recorder) RespondError(method string, err *gotgbot.TelegramError) {
+	r.Handle(method, func(Call) (interface{}, error) {
+		return nil, err
+	})
+}
+
+// Calls returns all the method calls received so far, in order.
+func (r *Recorder) Calls() []Call {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]Call(nil), r.calls...)
+}
+
+// CallsTo returns all the calls to the given method received so far, in order.
+func (r *Recorder) CallsTo(method string) []Call {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var calls []Call
+	for _, c := range r.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Reset clears the recorded calls.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.calls = nil
+}
+
+// WaitForCall waits for a call to the method with the given params to be received, and returns it. This is useful
+// when updates are processed asynchronously, such as when polling. Calls received before WaitForCall are also checked.
+func (r *Recorder) WaitForCall(method string, params map[string]string, timeout time.Duration) (Call, bool) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		r.lock.Lock()
+		for _, c := range r.calls {
+			if c.matches(method, params) {
+				r.lock.Unlock()
+				return c, true
+			}
+		}
+		callAdded := r.callAdded
+		r.lock.Unlock()
+
+		select {
+		case <-callAdded:
+		case <-deadline.C:
+			return Call{}, false
+		}
+	}
+}
+
+// record records a call, and returns the responder configured for its method, if any.
+func (r *Recorder) record(call Call) Responder {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.calls = append(r.calls, call)
+	close(r.callAdded)
+	r.callAdded = make(chan struct{})
+
+	if rs := r.onceResponders[call.Method]; len(rs) > 0 {
+		r.onceResponders[call.Method] = rs[1:]
+		return rs[0]
+	}
+	return r.responders[call.Method]
+}
+
+// defaultResponse returns the default result for methods which don't have a responder.
+func (r *Recorder) defaultResponse(call Call) interface{} {
+	switch {
+	case call.Method == "getMe":
+		return r.BotUser
+	case call.Method == "sendChatAction":
+		return true
+	case call.Method == "sendMediaGroup":
+		return r.newMediaGroup(call)
+	case call.Method == "forwardMessages" || call.Method == "copyMessages":
+		return r.newMessageIds(call)
+	case strings.HasPrefix(call.Method, "send") || call.Method == "forwardMessage" || call.Method == "copyMessage":
+		return r.newMessage(call)
+	}
+	return true
+}
+
+// newMediaGroup returns a new message for each of the media sent by a sendMediaGroup call.
+func (r *Recorder) newMediaGroup(call Call) []gotgbot.Message {
+	var media []struct {
+		Caption string `json:"caption"`
+	}
+	_ = json.Unmarshal([]byte(call.Params["media"]), &media)
+
+	msgs := make([]gotgbot.Message, 0, len(media))
+	for _, m := range media {
+		msg := r.newMessage(call)
+		msg.Caption = m.Caption
+		msg.MediaGroupId = "media_group"
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// newMessageIds returns a new message ID for each of the messages forwarded or copied by a call.
+func (r *Recorder) newMessageIds(call Call) []gotgbot.MessageId {
+	var ids []int64
+	_ = json.Unmarshal([]byte(call.Params["message_ids"]), &ids)
+
+	out := make([]gotgbot.MessageId, 0, len(ids))
+	for range ids {
+		out = append(out, gotgbot.MessageId{MessageId: r.newMessage(call).MessageId})
+	}
+	return out
+}
+
+// newMessage returns a new message sent by the bot, in the chat requested by the call.
+func (r *Recorder) newMessage(call Call) gotgbot.Message {
+	r.lock.Lock()
+	r.lastMessageId++
+	msgId := r.lastMessageId
+	r.lock.Unlock()
+
+	chatId, _ := strconv.ParseInt(call.Params["chat_id"], 10, 64)
+	msg := gotgbot.Message{
+		MessageId: msgId,
+		From:      &r.BotUser,
+		Date:      time.Now().Unix(),
+		Chat:      gotgbot.Chat{Id: chatId, Type: "private"},
+		Text:      call.Params["text"],
+		Caption:   call.Params["caption"],
+	}
+	if chatId < 0 {
+		msg.Chat.Type = "supergroup"
+	}
+	if entities := call.Params["entities"]; entities != "" {
+		_ = json.Unmarshal([]byte(entities), &msg.Entities)
+	}
+	return msg
+}
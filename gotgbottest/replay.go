@@ -0,0 +1,274 @@
+package gotgbottest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+// updateMethods are the methods used to fetch updates and set up the bot, which are never compared when replaying.
+var updateMethods = []string{"getMe", "getUpdates", "setWebhook", "deleteWebhook", "getWebhookInfo", "close", "logOut"}
+
+// ReplayOpts represents the optional values to replay a recording.
+type ReplayOpts struct {
+	// IgnoreMethods are the methods whose calls aren't compared; eg, methods called by background tasks rather than by
+	// handlers. Calls to getMe and the update methods are always ignored.
+	IgnoreMethods []string
+	// IgnoreParams are the params which aren't compared, for params which are expected to vary between runs; eg,
+	// timestamps or randomly generated IDs.
+	IgnoreParams []string
+}
+
+// ReplayResult is the difference between the calls of a recording, and the calls made while replaying it.
+type ReplayResult struct {
+	// Missing are the recorded calls which weren't made when replaying.
+	Missing []RecordEntry
+	// Unexpected are the calls made when replaying which weren't recorded.
+	Unexpected []RecordEntry
+	// Errors are the errors returned by the dispatcher when processing the recorded updates.
+	Errors []error
+}
+
+// OK checks whether the replay made the same calls as the recording, without errors.
+func (r *ReplayResult) OK() bool {
+	return len(r.Missing) == 0 && len(r.Unexpected) == 0 && len(r.Errors) == 0
+}
+
+// Diff returns a human-readable diff of the calls, with missing calls prefixed with "-" and unexpected calls with "+".
+// It returns an empty string if the calls are the same.
+func (r *ReplayResult) Diff() string {
+	var sb strings.Builder
+	for _, e := range r.Missing {
+		sb.WriteString("- " + describeEntry(e) + "\n")
+	}
+	for _, e := range r.Unexpected {
+		sb.WriteString("+ " + describeEntry(e) + "\n")
+	}
+	for _, err := range r.Errors {
+		sb.WriteString("! " + err.Error() + "\n")
+	}
+	return sb.String()
+}
+
+// ReadRecording reads all the entries of a recording.
+func ReadRecording(r io.Reader) ([]RecordEntry, error) {
+	var entries []RecordEntry
+	dec := json.NewDecoder(r)
+	for {
+		var e RecordEntry
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode recorded entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, e)
+	}
+}
+
+// Replay feeds the updates of a recording through the dispatcher, one at a time and in order, and compares the calls
+// made by the handlers with the recorded calls. This allows for catching behaviour changes when refactoring handlers.
+//
+// The calls are made to an in-memory Client, which returns the recorded result of the matching call: the first unused
+// recorded call to the same method with the same params, or failing that, the first unused recorded call to the same
+// method. Calls which can't be matched to any recorded call return the Client's default response.
+// Calls are compared regardless of their order, since handlers may run concurrently when recording.
+func Replay(r io.Reader, d *ext.Dispatcher, opts *ReplayOpts) (*ReplayResult, error) {
+	entries, err := ReadRecording(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreMethods := map[string]bool{}
+	for _, m := range updateMethods {
+		ignoreMethods[m] = true
+	}
+	ignoreParams := map[string]bool{}
+	if opts != nil {
+		for _, m := range opts.IgnoreMethods {
+			ignoreMethods[m] = true
+		}
+		for _, p := range opts.IgnoreParams {
+			ignoreParams[p] = true
+		}
+	}
+
+	c := NewClient()
+	b := c.Bot()
+
+	var updates []json.RawMessage
+	recorded := map[string][]*replayedCall{}
+	for _, e := range entries {
+		switch e.Type {
+		case EntryTypeUpdate:
+			updates = append(updates, e.Update)
+		case EntryTypeCall:
+			if e.Method == "getMe" && e.Result != nil {
+				// Replay as the recorded bot, so that handlers depending on the bot's user behave the same way.
+				if err := json.Unmarshal(e.Result, &c.BotUser); err == nil {
+					b.User = c.BotUser
+				}
+			}
+			recorded[e.Method] = append(recorded[e.Method], &replayedCall{
+				entry: e,
+				key:   entryKey(e, ignoreParams),
+			})
+		}
+	}
+
+	for method, calls := range recorded {
+		c.Handle(method, replayResponder(c, calls, ignoreParams))
+	}
+
+	res := &ReplayResult{}
+	for i, raw := range updates {
+		var upd gotgbot.Update
+		if err := json.Unmarshal(raw, &upd); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal recorded update %d: %w", i+1, err)
+		}
+		if err := d.ProcessUpdate(b, &upd, nil); err != nil {
+			res.Errors = append(res.Errors, fmt.Errorf("failed to process update %d: %w", upd.UpdateId, err))
+		}
+	}
+
+	// Compare the recorded and replayed calls as multisets.
+	pending := map[string][]RecordEntry{}
+	var recordedKeys []string
+	for _, e := range entries {
+		if e.Type != EntryTypeCall || ignoreMethods[e.Method] {
+			continue
+		}
+		k := entryKey(e, ignoreParams)
+		pending[k] = append(pending[k], e)
+		recordedKeys = append(recordedKeys, k)
+	}
+	for _, call := range c.Calls() {
+		if ignoreMethods[call.Method] {
+			continue
+		}
+		e := callEntry(call)
+		k := entryKey(e, ignoreParams)
+		if len(pending[k]) == 0 {
+			res.Unexpected = append(res.Unexpected, e)
+			continue
+		}
+		pending[k] = pending[k][1:]
+	}
+	// Report missing calls in the order they were recorded.
+	for _, k := range recordedKeys {
+		if len(pending[k]) > 0 {
+			res.Missing = append(res.Missing, pending[k][0])
+			pending[k] = pending[k][1:]
+		}
+	}
+	return res, nil
+}
+
+// replayedCall is a recorded call, which may be used to respond to a replayed call.
+type replayedCall struct {
+	entry RecordEntry
+	key   string
+	used  bool
+}
+
+// replayResponder returns the recorded result of the call which best matches the replayed call.
+func replayResponder(c *Client, calls []*replayedCall, ignoreParams map[string]bool) Responder {
+	// Handlers may make calls concurrently, so lookups must not race on the used flags.
+	lock := sync.Mutex{}
+	return func(call Call) (interface{}, error) {
+		k := entryKey(callEntry(call), ignoreParams)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		var match *replayedCall
+		for _, rc := range calls {
+			if !rc.used && rc.key == k {
+				match = rc
+				break
+			}
+		}
+		if match == nil {
+			for _, rc := range calls {
+				if !rc.used {
+					match = rc
+					break
+				}
+			}
+		}
+		if match == nil {
+			return c.defaultResponse(call), nil
+		}
+		match.used = true
+
+		if match.entry.Error != nil {
+			return nil, &gotgbot.TelegramError{
+				Code:           match.entry.Error.Code,
+				Description:    match.entry.Error.Description,
+				ResponseParams: match.entry.Error.Parameters,
+			}
+		}
+		return match.entry.Result, nil
+	}
+}
+
+// callEntry converts a replayed call to a redacted entry, as it would have been recorded.
+func callEntry(call Call) RecordEntry {
+	e := RecordEntry{
+		Type:   EntryTypeCall,
+		Method: call.Method,
+		Params: map[string]string{},
+	}
+	for k, v := range call.Params {
+		e.Params[k] = v
+	}
+	if len(call.Files) > 0 {
+		e.Files = map[string]RecordedFile{}
+		for field, f := range call.Files {
+			sum := sha256.Sum256(f.Data)
+			e.Files[field] = RecordedFile{Name: f.Name, SHA256: hex.EncodeToString(sum[:])}
+		}
+	}
+	redactEntry(&e, call.Token)
+	return e
+}
+
+// entryKey returns a key identifying the method, params and files of a call, ignoring the given params.
+func entryKey(e RecordEntry, ignoreParams map[string]bool) string {
+	params := map[string]string{}
+	for k, v := range e.Params {
+		if !ignoreParams[k] {
+			params[k] = v
+		}
+	}
+	// Maps are marshalled with sorted keys, so the key is deterministic.
+	bs, _ := json.Marshal(struct {
+		Method string                  `json:"method"`
+		Params map[string]string       `json:"params"`
+		Files  map[string]RecordedFile `json:"files"`
+	}{e.Method, params, e.Files})
+	return string(bs)
+}
+
+// describeEntry describes a call entry, with its params in order.
+func describeEntry(e RecordEntry) string {
+	var fields []string
+	for k, v := range e.Params {
+		fields = append(fields, k+"="+v)
+	}
+	for k, f := range e.Files {
+		fields = append(fields, k+"=<file "+f.Name+" sha256:"+f.SHA256+">")
+	}
+	sort.Strings(fields)
+	return e.Method + "(" + strings.Join(fields, ", ") + ")"
+}
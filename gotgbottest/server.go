@@ -0,0 +1,339 @@
+// Package gotgbottest provides utilities for testing bots offline: a fake Bot API server, an in-memory BotClient,
+// canned updates to build handler contexts from, and the recording and replaying of real bot traffic.
+package gotgbottest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+)
+
+var ErrNoWebhook = errors.New("no webhook has been set")
+
+// Server is a fake Bot API server, running in-process. Bots created with a BaseBotClient pointing at the server's URL
+// can be tested end-to-end without any network access.
+//
+// Every method call is recorded, and can be inspected with Calls or the assertion helpers. Method results can be
+// configured with Handle and HandleOnce; by default:
+//   - getMe returns the server's BotUser.
+//   - getUpdates returns the updates queued with QueueUpdate, long-polling up to the requested timeout.
+//   - setWebhook stores the webhook, which updates can then be sent to with DeliverWebhookUpdate.
+//   - send*, forwardMessage and copyMessage return a new Message in the requested chat.
+//   - sendMediaGroup returns a new Message for each of the media, and sendChatAction returns true.
+//   - forwardMessages and copyMessages return a new MessageId for each of the messages.
+//   - all other methods return true.
+type Server struct {
+	Recorder
+
+	server *httptest.Server
+
+	// updatesLock allows us to ensure synchronous access to the fields below.
+	updatesLock sync.Mutex
+	// updates are the updates queued for getUpdates, which haven't been acknowledged yet.
+	updates []gotgbot.Update
+	// updateAdded is closed and replaced whenever an update is queued.
+	updateAdded chan struct{}
+	// lastUpdateId is the ID of the latest update queued.
+	lastUpdateId int64
+	// webhookURL and webhookSecret are set by setWebhook.
+	webhookURL    string
+	webhookSecret string
+}
+
+// NewServer starts a new fake Bot API server. It should be closed once the test is done.
+func NewServer() *Server {
+	s := &Server{
+		Recorder:    newRecorder(),
+		updateAdded: make(chan struct{}),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL returns the URL of the server, to be used as the APIURL of the bot's RequestOpts.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Bot returns a bot using the server, with the DefaultToken.
+func (s *Server) Bot() *gotgbot.Bot {
+	return &gotgbot.Bot{
+		Token: DefaultToken,
+		User:  s.BotUser,
+		BotClient: &gotgbot.BaseBotClient{
+			DefaultRequestOpts: &gotgbot.RequestOpts{
+				APIURL: s.URL(),
+			},
+		},
+	}
+}
+
+// QueueUpdate queues an update to be returned by getUpdates. If the update ID is 0, the next available ID is used.
+// The queued update is returned.
+func (s *Server) QueueUpdate(upd gotgbot.Update) gotgbot.Update {
+	s.updatesLock.Lock()
+	defer s.updatesLock.Unlock()
+
+	if upd.UpdateId == 0 {
+		upd.UpdateId = s.lastUpdateId + 1
+	}
+	if upd.UpdateId > s.lastUpdateId {
+		s.lastUpdateId = upd.UpdateId
+	}
+	s.updates = append(s.updates, upd)
+
+	close(s.updateAdded)
+	s.updateAdded = make(chan struct{})
+	return upd
+}
+
+// PendingUpdates returns the number of queued updates which haven't been acknowledged by getUpdates yet.
+func (s *Server) PendingUpdates() int {
+	s.updatesLock.Lock()
+	defer s.updatesLock.Unlock()
+
+	return len(s.updates)
+}
+
+// DeliverWebhookUpdate sends an update to the webhook set with setWebhook, including its secret token, in the same
+// way telegram would. If the update ID is 0, the next available ID is used.
+func (s *Server) DeliverWebhookUpdate(upd gotgbot.Update) error {
+	s.updatesLock.Lock()
+	webhookURL, secret := s.webhookURL, s.webhookSecret
+	if upd.UpdateId == 0 {
+		upd.UpdateId = s.lastUpdateId + 1
+	}
+	if upd.UpdateId > s.lastUpdateId {
+		s.lastUpdateId = upd.UpdateId
+	}
+	s.updatesLock.Unlock()
+
+	if webhookURL == "" {
+		return ErrNoWebhook
+	}
+
+	bs, err := json.Marshal(upd)
+	if err != nil {
+		return fmt.Errorf("failed to marshal update: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(bs))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook update: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	call, err := decodeCall(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responder := s.record(call)
+	var result interface{}
+	if responder != nil {
+		result, err = responder(call)
+	} else {
+		result, err = s.serverResponse(r, call)
+	}
+	writeResponse(w, result, err)
+}
+
+// serverResponse returns the default result for methods which don't have a responder, handling the update methods.
+func (s *Server) serverResponse(r *http.Request, call Call) (interface{}, error) {
+	switch call.Method {
+	case "getUpdates":
+		return s.getUpdates(r, call)
+	case "setWebhook":
+		s.updatesLock.Lock()
+		s.webhookURL = call.Params["url"]
+		s.webhookSecret = call.Params["secret_token"]
+		s.updatesLock.Unlock()
+		return true, nil
+	case "deleteWebhook":
+		s.updatesLock.Lock()
+		s.webhookURL = ""
+		s.webhookSecret = ""
+		s.updatesLock.Unlock()
+		return true, nil
+	}
+	return s.defaultResponse(call), nil
+}
+
+// getUpdates returns the queued updates starting at the requested offset, waiting up to the requested timeout for new
+// updates if there are none.
+func (s *Server) getUpdates(r *http.Request, call Call) ([]gotgbot.Update, error) {
+	offset, _ := strconv.ParseInt(call.Params["offset"], 10, 64)
+	limit, _ := strconv.Atoi(call.Params["limit"])
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	timeout, _ := strconv.Atoi(call.Params["timeout"])
+	deadline := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer deadline.Stop()
+
+	for {
+		s.updatesLock.Lock()
+		// Updates before the offset are acknowledged, and can be forgotten.
+		pending := s.updates[:0]
+		for _, upd := range s.updates {
+			if upd.UpdateId >= offset {
+				pending = append(pending, upd)
+			}
+		}
+		s.updates = pending
+
+		if len(pending) > 0 || timeout <= 0 {
+			if len(pending) > limit {
+				pending = pending[:limit]
+			}
+			updates := append([]gotgbot.Update{}, pending...)
+			s.updatesLock.Unlock()
+			return updates, nil
+		}
+		updateAdded := s.updateAdded
+		s.updatesLock.Unlock()
+
+		select {
+		case <-updateAdded:
+		case <-deadline.C:
+			return []gotgbot.Update{}, nil
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+	}
+}
+
+func writeResponse(w http.ResponseWriter, result interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err == nil {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":     true,
+			"result": result,
+		})
+		return
+	}
+
+	var tgErr *gotgbot.TelegramError
+	if !errors.As(err, &tgErr) {
+		tgErr = &gotgbot.TelegramError{Code: http.StatusInternalServerError, Description: "Internal Server Error: " + err.Error()}
+	}
+	_ = json.NewEncoder(w).Encode(gotgbot.Response{
+		Ok:          false,
+		ErrorCode:   tgErr.Code,
+		Description: tgErr.Description,
+		Parameters:  tgErr.ResponseParams,
+	})
+}
+
+// decodeCall decodes the token, method, params and files of a request, sent as either JSON or multipart data.
+func decodeCall(r *http.Request) (Call, error) {
+	path := strings.TrimPrefix(r.URL.Path, "/bot")
+	token, method, ok := strings.Cut(path, "/")
+	if !ok || path == r.URL.Path {
+		return Call{}, fmt.Errorf("unexpected path %s", r.URL.Path)
+	}
+	// Test environment bots use an extra path segment.
+	method = strings.TrimPrefix(method, "test/")
+
+	call := Call{
+		Token:  token,
+		Method: method,
+		Params: map[string]string{},
+		Files:  map[string]File{},
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil && r.Header.Get("Content-Type") != "" {
+		return Call{}, fmt.Errorf("invalid content type: %w", err)
+	}
+
+	switch mediaType {
+	case "multipart/form-data":
+		err := r.ParseMultipartForm(32 << 20)
+		if err != nil {
+			return Call{}, fmt.Errorf("failed to parse multipart form: %w", err)
+		}
+		for k, vs := range r.MultipartForm.Value {
+			if len(vs) > 0 {
+				call.Params[k] = vs[0]
+			}
+		}
+		for k, fhs := range r.MultipartForm.File {
+			if len(fhs) == 0 {
+				continue
+			}
+			f, err := readFile(fhs[0])
+			if err != nil {
+				return Call{}, fmt.Errorf("failed to read file %s: %w", k, err)
+			}
+			call.Files[k] = f
+		}
+	default:
+		bs, err := io.ReadAll(r.Body)
+		if err != nil {
+			return Call{}, fmt.Errorf("failed to read body: %w", err)
+		}
+		if len(bytes.TrimSpace(bs)) > 0 {
+			err = json.Unmarshal(bs, &call.Params)
+			if err != nil {
+				return Call{}, fmt.Errorf("failed to decode JSON params: %w", err)
+			}
+		}
+	}
+
+	// The JSON client sends empty values for unset optional params; drop them, to keep assertions simple.
+	for k, v := range call.Params {
+		if v == "" {
+			delete(call.Params, k)
+		}
+	}
+	return call, nil
+}
+
+func readFile(fh *multipart.FileHeader) (File, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return File{}, err
+	}
+	return File{Name: fh.Filename, Data: data}, nil
+}
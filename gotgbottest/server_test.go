@@ -0,0 +1,138 @@
+package gotgbottest_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/gotgbottest"
+)
+
+func TestServerPolling(t *testing.T) {
+	s := gotgbottest.NewServer()
+	defer s.Close()
+	b := s.Bot()
+
+	d := ext.NewDispatcher(nil)
+	d.AddHandler(handlers.NewCommand("start", func(b *gotgbot.Bot, ctx *ext.Context) error {
+		_, err := ctx.EffectiveMessage.Reply(b, "Hello, "+ctx.EffectiveUser.FirstName, nil)
+		return err
+	}))
+
+	u := ext.NewUpdater(d, nil)
+	err := u.StartPolling(b, &ext.PollingOpts{
+		GetUpdatesOpts: &gotgbot.GetUpdatesOpts{
+			Timeout: 1,
+			RequestOpts: &gotgbot.RequestOpts{
+				Timeout: 2 * time.Second,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to start polling: %v", err)
+	}
+	defer func() {
+		if err := u.Stop(); err != nil {
+			t.Errorf("failed to stop updater: %v", err)
+		}
+	}()
+
+	s.QueueUpdate(gotgbot.Update{
+		Message: &gotgbot.Message{
+			MessageId: 1,
+			Text:      "/start",
+			Entities:  []gotgbot.MessageEntity{{Type: "bot_command", Offset: 0, Length: 6}},
+			From:      &gotgbot.User{Id: 10, FirstName: "Alice"},
+			Chat:      gotgbot.Chat{Id: 10, Type: "private"},
+		},
+	})
+
+	if _, ok := s.WaitForCall("sendMessage", nil, 5*time.Second); !ok {
+		t.Fatal("expected the bot to reply")
+	}
+	call := s.AssertSentMessage(t, 10, "Hello, Alice")
+	if call.Token != gotgbottest.DefaultToken || !strings.Contains(call.Params["reply_parameters"], `"message_id":1`) {
+		t.Errorf("unexpected call: %+v", call)
+	}
+}
+
+func TestServerResponses(t *testing.T) {
+	s := gotgbottest.NewServer()
+	defer s.Close()
+	b := s.Bot()
+
+	s.HandleOnce("sendMessage", func(gotgbottest.Call) (interface{}, error) {
+		return nil, gotgbottest.ErrTooManyRequests(5)
+	})
+	s.RespondError("banChatMember", gotgbottest.ErrForbidden("bot is not an admin"))
+
+	_, err := b.SendMessage(1, "hello", nil)
+	var tgErr *gotgbot.TelegramError
+	if !errors.As(err, &tgErr) || tgErr.Code != http.StatusTooManyRequests || tgErr.ResponseParams.RetryAfter != 5 {
+		t.Errorf("expected rate limit error, got %v", err)
+	}
+
+	msg, err := b.SendMessage(1, "hello", nil)
+	if err != nil {
+		t.Fatalf("expected once responder to be used up, got %v", err)
+	}
+	if msg.Text != "hello" || msg.Chat.Id != 1 || msg.MessageId == 0 {
+		t.Errorf("unexpected default message: %+v", msg)
+	}
+
+	_, err = b.BanChatMember(-100, 1, nil)
+	if !errors.As(err, &tgErr) || tgErr.Code != http.StatusForbidden {
+		t.Errorf("expected forbidden error, got %v", err)
+	}
+
+	_, err = b.SendDocument(1, gotgbot.NamedFile{FileName: "notes.txt", File: strings.NewReader("some notes")}, &gotgbot.SendDocumentOpts{
+		Caption: "notes",
+	})
+	if err != nil {
+		t.Fatalf("failed to send document: %v", err)
+	}
+	call := s.AssertCalled(t, "sendDocument", map[string]string{"caption": "notes"})
+	var file gotgbottest.File
+	for _, f := range call.Files {
+		file = f
+	}
+	if file.Name != "notes.txt" || string(file.Data) != "some notes" {
+		t.Errorf("unexpected uploaded file: %+v", file)
+	}
+
+	s.AssertCallCount(t, "sendMessage", 2)
+	s.AssertNotCalled(t, "deleteMessage")
+}
+
+func TestServerWebhook(t *testing.T) {
+	s := gotgbottest.NewServer()
+	defer s.Close()
+	b := s.Bot()
+
+	received := make(chan string, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	}))
+	defer webhook.Close()
+
+	if err := s.DeliverWebhookUpdate(gotgbot.Update{}); !errors.Is(err, gotgbottest.ErrNoWebhook) {
+		t.Errorf("expected missing webhook error, got %v", err)
+	}
+
+	_, err := b.SetWebhook(webhook.URL, &gotgbot.SetWebhookOpts{SecretToken: "secret"})
+	if err != nil {
+		t.Fatalf("failed to set webhook: %v", err)
+	}
+	if err := s.DeliverWebhookUpdate(gotgbot.Update{}); err != nil {
+		t.Fatalf("failed to deliver webhook update: %v", err)
+	}
+	if secret := <-received; secret != "secret" {
+		t.Errorf("expected secret token to be sent, got %q", secret)
+	}
+}
@@ -0,0 +1,320 @@
+package gotgbot
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// keyboardLayout lays out keyboard buttons into rows, wrapping rows automatically as buttons are added.
+type keyboardLayout[B any] struct {
+	rows [][]B
+	// columns is the maximum number of buttons per row; 0 means no limit.
+	columns int
+	// maxRowWidth is the maximum combined length of the button texts in a row, in characters; 0 means no limit.
+	maxRowWidth int
+	// rowWidth is the combined length of the button texts in the current row.
+	rowWidth int
+}
+
+// add adds a button to the current row, or to a new row if the current one is full.
+func (l *keyboardLayout[B]) add(b B, text string) {
+	width := utf8.RuneCountInString(text)
+	if len(l.rows) == 0 || l.isRowFull(width) {
+		l.row()
+	}
+	last := len(l.rows) - 1
+	l.rows[last] = append(l.rows[last], b)
+	l.rowWidth += width
+}
+
+func (l *keyboardLayout[B]) isRowFull(width int) bool {
+	row := l.rows[len(l.rows)-1]
+	if len(row) == 0 {
+		return false
+	}
+	return (l.columns > 0 && len(row) >= l.columns) ||
+		(l.maxRowWidth > 0 && l.rowWidth+width > l.maxRowWidth)
+}
+
+// row starts a new row, unless the current row is still empty.
+func (l *keyboardLayout[B]) row() {
+	if len(l.rows) > 0 && len(l.rows[len(l.rows)-1]) == 0 {
+		return
+	}
+	l.rows = append(l.rows, nil)
+	l.rowWidth = 0
+}
+
+// build returns a copy of the non-empty rows.
+func (l *keyboardLayout[B]) build() [][]B {
+	rows := make([][]B, 0, len(l.rows))
+	for _, r := range l.rows {
+		if len(r) == 0 {
+			continue
+		}
+		rows = append(rows, append([]B(nil), r...))
+	}
+	return rows
+}
+
+// InlineKeyboardBuilder helps build InlineKeyboardMarkup, by adding buttons one at a time. Rows are wrapped
+// automatically when Columns or MaxRowWidth are set; Row can be used to start a new row manually.
+//
+// Note: copy_text buttons are not supported, as they are not part of the Bot API version this library was generated
+// for.
+type InlineKeyboardBuilder struct {
+	layout keyboardLayout[InlineKeyboardButton]
+}
+
+// NewInlineKeyboard creates a new InlineKeyboardBuilder.
+func NewInlineKeyboard() *InlineKeyboardBuilder {
+	return &InlineKeyboardBuilder{}
+}
+
+// Columns sets the maximum number of buttons per row; further buttons are wrapped onto a new row.
+// 0 disables wrapping.
+func (kb *InlineKeyboardBuilder) Columns(n int) *InlineKeyboardBuilder {
+	kb.layout.columns = n
+	return kb
+}
+
+// MaxRowWidth sets the maximum combined length of the button texts in a row, in characters; further buttons are
+// wrapped onto a new row. A single button is never wrapped, even if its text is longer than the limit.
+// 0 disables wrapping.
+func (kb *InlineKeyboardBuilder) MaxRowWidth(n int) *InlineKeyboardBuilder {
+	kb.layout.maxRowWidth = n
+	return kb
+}
+
+// Row starts a new row of buttons.
+func (kb *InlineKeyboardBuilder) Row() *InlineKeyboardBuilder {
+	kb.layout.row()
+	return kb
+}
+
+// Button adds a button.
+func (kb *InlineKeyboardBuilder) Button(b InlineKeyboardButton) *InlineKeyboardBuilder {
+	kb.layout.add(b, b.Text)
+	return kb
+}
+
+// Callback adds a button which sends a callback query with the given data when pressed.
+func (kb *InlineKeyboardBuilder) Callback(text string, data string) *InlineKeyboardBuilder {
+	return kb.Button(InlineKeyboardButton{Text: text, CallbackData: data})
+}
+
+// URL adds a button which opens the given URL when pressed.
+func (kb *InlineKeyboardBuilder) URL(text string, url string) *InlineKeyboardBuilder {
+	return kb.Button(InlineKeyboardButton{Text: text, Url: url})
+}
+
+// WebApp adds a button which opens the webapp at the given URL when pressed.
+func (kb *InlineKeyboardBuilder) WebApp(text string, url string) *InlineKeyboardBuilder {
+	return kb.Button(InlineKeyboardButton{Text: text, WebApp: &WebAppInfo{Url: url}})
+}
+
+// LoginURL adds a button which authorizes the user on a website when pressed.
+func (kb *InlineKeyboardBuilder) LoginURL(text string, loginUrl LoginUrl) *InlineKeyboardBuilder {
+	return kb.Button(InlineKeyboardButton{Text: text, LoginUrl: &loginUrl})
+}
+
+// SwitchInlineQuery adds a button which prompts the user to pick a chat, and inserts the bot's username and the given
+// query in the input field.
+func (kb *InlineKeyboardBuilder) SwitchInlineQuery(text string, query string) *InlineKeyboardBuilder {
+	return kb.Button(InlineKeyboardButton{Text: text, SwitchInlineQuery: &query})
+}
+
+// SwitchInlineQueryCurrentChat adds a button which inserts the bot's username and the given query in the input field
+// of the current chat.
+func (kb *InlineKeyboardBuilder) SwitchInlineQueryCurrentChat(text string, query string) *InlineKeyboardBuilder {
+	return kb.Button(InlineKeyboardButton{Text: text, SwitchInlineQueryCurrentChat: &query})
+}
+
+// SwitchInlineQueryChosenChat adds a button which prompts the user to pick a chat of the specified type, and inserts
+// the bot's username and the specified query in the input field.
+func (kb *InlineKeyboardBuilder) SwitchInlineQueryChosenChat(text string, chosenChat SwitchInlineQueryChosenChat) *InlineKeyboardBuilder {
+	return kb.Button(InlineKeyboardButton{Text: text, SwitchInlineQueryChosenChat: &chosenChat})
+}
+
+// CallbackGame adds a button which launches the bot's game. It must be the first button of the first row.
+func (kb *InlineKeyboardBuilder) CallbackGame(text string) *InlineKeyboardBuilder {
+	return kb.Button(InlineKeyboardButton{Text: text, CallbackGame: &CallbackGame{}})
+}
+
+// Pay adds a pay button. It must be the first button of the first row, and can only be used with invoice messages.
+func (kb *InlineKeyboardBuilder) Pay(text string) *InlineKeyboardBuilder {
+	return kb.Button(InlineKeyboardButton{Text: text, Pay: true})
+}
+
+// Build returns the InlineKeyboardMarkup. The builder can still be used afterwards.
+func (kb *InlineKeyboardBuilder) Build() InlineKeyboardMarkup {
+	return InlineKeyboardMarkup{InlineKeyboard: kb.layout.build()}
+}
+
+// PaginateInlineKeyboard adds the buttons for a single page of items to the keyboard, followed by the PageNavigation
+// row. Pages are numbered from 0; out of range pages are clamped to the first or last page.
+func PaginateInlineKeyboard[T any](kb *InlineKeyboardBuilder, items []T, page int, perPage int, button func(item T) InlineKeyboardButton, pageData func(page int) string) *InlineKeyboardBuilder {
+	if perPage <= 0 {
+		perPage = len(items)
+	}
+	pages := 1
+	if perPage > 0 {
+		pages = (len(items) + perPage - 1) / perPage
+	}
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	end := (page + 1) * perPage
+	if end > len(items) {
+		end = len(items)
+	}
+	for _, item := range items[page*perPage : end] {
+		kb.Button(button(item))
+	}
+
+	return kb.PageNavigation(page, pages, pageData)
+}
+
+// PageNavigation adds a navigation row to move between pages, numbered from 0: a button to the previous page, a
+// button showing the current page, and a button to the next page. The buttons send the callback data returned by
+// pageData for the page they lead to. Nothing is added if there is only one page.
+// The navigation row ignores the column and width limits, to keep it on a single row.
+func (kb *InlineKeyboardBuilder) PageNavigation(page int, pages int, pageData func(page int) string) *InlineKeyboardBuilder {
+	if pages <= 1 {
+		return kb
+	}
+
+	kb.layout.row()
+	last := len(kb.layout.rows) - 1
+	if page > 0 {
+		kb.layout.rows[last] = append(kb.layout.rows[last], InlineKeyboardButton{Text: "«", CallbackData: pageData(page - 1)})
+	}
+	kb.layout.rows[last] = append(kb.layout.rows[last], InlineKeyboardButton{
+		Text:         strconv.Itoa(page+1) + "/" + strconv.Itoa(pages),
+		CallbackData: pageData(page),
+	})
+	if page < pages-1 {
+		kb.layout.rows[last] = append(kb.layout.rows[last], InlineKeyboardButton{Text: "»", CallbackData: pageData(page + 1)})
+	}
+	kb.layout.row()
+	return kb
+}
+
+// ReplyKeyboardBuilder helps build ReplyKeyboardMarkup, by adding buttons one at a time. Rows are wrapped
+// automatically when Columns or MaxRowWidth are set; Row can be used to start a new row manually.
+type ReplyKeyboardBuilder struct {
+	layout keyboardLayout[KeyboardButton]
+	markup ReplyKeyboardMarkup
+}
+
+// NewReplyKeyboard creates a new ReplyKeyboardBuilder.
+func NewReplyKeyboard() *ReplyKeyboardBuilder {
+	return &ReplyKeyboardBuilder{}
+}
+
+// Columns sets the maximum number of buttons per row; further buttons are wrapped onto a new row.
+// 0 disables wrapping.
+func (kb *ReplyKeyboardBuilder) Columns(n int) *ReplyKeyboardBuilder {
+	kb.layout.columns = n
+	return kb
+}
+
+// MaxRowWidth sets the maximum combined length of the button texts in a row, in characters; further buttons are
+// wrapped onto a new row. A single button is never wrapped, even if its text is longer than the limit.
+// 0 disables wrapping.
+func (kb *ReplyKeyboardBuilder) MaxRowWidth(n int) *ReplyKeyboardBuilder {
+	kb.layout.maxRowWidth = n
+	return kb
+}
+
+// Row starts a new row of buttons.
+func (kb *ReplyKeyboardBuilder) Row() *ReplyKeyboardBuilder {
+	kb.layout.row()
+	return kb
+}
+
+// Button adds a button.
+func (kb *ReplyKeyboardBuilder) Button(b KeyboardButton) *ReplyKeyboardBuilder {
+	kb.layout.add(b, b.Text)
+	return kb
+}
+
+// Text adds a button which sends its text as a message when pressed.
+func (kb *ReplyKeyboardBuilder) Text(text string) *ReplyKeyboardBuilder {
+	return kb.Button(KeyboardButton{Text: text})
+}
+
+// RequestContact adds a button which sends the user's phone number when pressed. Only available in private chats.
+func (kb *ReplyKeyboardBuilder) RequestContact(text string) *ReplyKeyboardBuilder {
+	return kb.Button(KeyboardButton{Text: text, RequestContact: true})
+}
+
+// RequestLocation adds a button which sends the user's location when pressed. Only available in private chats.
+func (kb *ReplyKeyboardBuilder) RequestLocation(text string) *ReplyKeyboardBuilder {
+	return kb.Button(KeyboardButton{Text: text, RequestLocation: true})
+}
+
+// RequestPoll adds a button which prompts the user to create a poll of the given type; "quiz", "regular", or empty
+// to allow any type. Only available in private chats.
+func (kb *ReplyKeyboardBuilder) RequestPoll(text string, pollType string) *ReplyKeyboardBuilder {
+	return kb.Button(KeyboardButton{Text: text, RequestPoll: &KeyboardButtonPollType{Type: pollType}})
+}
+
+// RequestUsers adds a button which prompts the user to select users to share with the bot.
+// Only available in private chats.
+func (kb *ReplyKeyboardBuilder) RequestUsers(text string, request KeyboardButtonRequestUsers) *ReplyKeyboardBuilder {
+	return kb.Button(KeyboardButton{Text: text, RequestUsers: &request})
+}
+
+// RequestChat adds a button which prompts the user to select a chat to share with the bot.
+// Only available in private chats.
+func (kb *ReplyKeyboardBuilder) RequestChat(text string, request KeyboardButtonRequestChat) *ReplyKeyboardBuilder {
+	return kb.Button(KeyboardButton{Text: text, RequestChat: &request})
+}
+
+// WebApp adds a button which opens the webapp at the given URL when pressed. Only available in private chats.
+func (kb *ReplyKeyboardBuilder) WebApp(text string, url string) *ReplyKeyboardBuilder {
+	return kb.Button(KeyboardButton{Text: text, WebApp: &WebAppInfo{Url: url}})
+}
+
+// Resize requests clients to resize the keyboard vertically to fit its buttons.
+func (kb *ReplyKeyboardBuilder) Resize() *ReplyKeyboardBuilder {
+	kb.markup.ResizeKeyboard = true
+	return kb
+}
+
+// OneTime requests clients to hide the keyboard as soon as it's been used.
+func (kb *ReplyKeyboardBuilder) OneTime() *ReplyKeyboardBuilder {
+	kb.markup.OneTimeKeyboard = true
+	return kb
+}
+
+// Persistent requests clients to always show the keyboard when the regular keyboard is hidden.
+func (kb *ReplyKeyboardBuilder) Persistent() *ReplyKeyboardBuilder {
+	kb.markup.IsPersistent = true
+	return kb
+}
+
+// Placeholder sets the placeholder shown in the input field when the keyboard is active; 1-64 characters.
+func (kb *ReplyKeyboardBuilder) Placeholder(placeholder string) *ReplyKeyboardBuilder {
+	kb.markup.InputFieldPlaceholder = placeholder
+	return kb
+}
+
+// Selective only shows the keyboard to the users mentioned in the message text, and to the sender of the message
+// being replied to.
+func (kb *ReplyKeyboardBuilder) Selective() *ReplyKeyboardBuilder {
+	kb.markup.Selective = true
+	return kb
+}
+
+// Build returns the ReplyKeyboardMarkup. The builder can still be used afterwards.
+func (kb *ReplyKeyboardBuilder) Build() ReplyKeyboardMarkup {
+	markup := kb.markup
+	markup.Keyboard = kb.layout.build()
+	return markup
+}
@@ -0,0 +1,99 @@
+package gotgbot
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestInlineKeyboardBuilder(t *testing.T) {
+	query := "q"
+	markup := NewInlineKeyboard().
+		Columns(2).
+		Callback("a", "1").
+		URL("b", "https://example.com").
+		SwitchInlineQuery("c", query).
+		Row().
+		Row().
+		Pay("d").
+		Build()
+
+	expected := InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{
+		{{Text: "a", CallbackData: "1"}, {Text: "b", Url: "https://example.com"}},
+		{{Text: "c", SwitchInlineQuery: &query}},
+		{{Text: "d", Pay: true}},
+	}}
+	if !reflect.DeepEqual(markup, expected) {
+		t.Errorf("unexpected keyboard:\n%+v\nexpected:\n%+v", markup, expected)
+	}
+}
+
+func TestKeyboardMaxRowWidth(t *testing.T) {
+	markup := NewReplyKeyboard().
+		MaxRowWidth(6).
+		Text("yes").
+		Text("no").
+		Text("maybe").
+		Text("a very long answer").
+		Resize().
+		OneTime().
+		Build()
+
+	expected := ReplyKeyboardMarkup{
+		Keyboard: [][]KeyboardButton{
+			{{Text: "yes"}, {Text: "no"}},
+			{{Text: "maybe"}},
+			{{Text: "a very long answer"}},
+		},
+		ResizeKeyboard:  true,
+		OneTimeKeyboard: true,
+	}
+	if !reflect.DeepEqual(markup, expected) {
+		t.Errorf("unexpected keyboard:\n%+v\nexpected:\n%+v", markup, expected)
+	}
+}
+
+func TestPaginateInlineKeyboard(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	button := func(i int) InlineKeyboardButton {
+		return InlineKeyboardButton{Text: strconv.Itoa(i), CallbackData: "item:" + strconv.Itoa(i)}
+	}
+	pageData := func(page int) string {
+		return "page:" + strconv.Itoa(page)
+	}
+
+	for name, testParams := range map[string]struct {
+		page     int
+		expected [][]InlineKeyboardButton
+	}{
+		"first page": {
+			page: 0,
+			expected: [][]InlineKeyboardButton{
+				{{Text: "1", CallbackData: "item:1"}, {Text: "2", CallbackData: "item:2"}},
+				{{Text: "1/3", CallbackData: "page:0"}, {Text: "»", CallbackData: "page:1"}},
+			},
+		},
+		"middle page": {
+			page: 1,
+			expected: [][]InlineKeyboardButton{
+				{{Text: "3", CallbackData: "item:3"}, {Text: "4", CallbackData: "item:4"}},
+				{{Text: "«", CallbackData: "page:0"}, {Text: "2/3", CallbackData: "page:1"}, {Text: "»", CallbackData: "page:2"}},
+			},
+		},
+		"clamped last page": {
+			page: 10,
+			expected: [][]InlineKeyboardButton{
+				{{Text: "5", CallbackData: "item:5"}},
+				{{Text: "«", CallbackData: "page:1"}, {Text: "3/3", CallbackData: "page:2"}},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			kb := NewInlineKeyboard().Columns(2)
+			markup := PaginateInlineKeyboard(kb, items, testParams.page, 2, button, pageData).Build()
+			if !reflect.DeepEqual(markup.InlineKeyboard, testParams.expected) {
+				t.Errorf("unexpected keyboard:\n%+v\nexpected:\n%+v", markup.InlineKeyboard, testParams.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,553 @@
+package gotgbot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidMarkup is returned when parsing HTML or MarkdownV2 text which would be rejected by telegram.
+var ErrInvalidMarkup = errors.New("invalid markup")
+
+// parsedEntity is an entity which is being parsed from markup, and is waiting for its closing tag or marker.
+type parsedEntity struct {
+	openedEntity
+	// tag is the HTML tag or MarkdownV2 marker which opened the entity.
+	tag string
+	// pos is the byte offset of the opening tag or marker, for error messages.
+	pos int
+}
+
+// ParseHTML parses text formatted with the "HTML" parse mode, and returns the plain text, and the list of entities
+// describing its formatting. Entity offsets are computed in UTF-16 code units, as used by telegram.
+// Invalid markup returns ErrInvalidMarkup.
+// See https://core.telegram.org/bots/api#html-style for more details.
+func ParseHTML(s string) (string, []MessageEntity, error) {
+	tb := &TextBuilder{}
+	var stack []parsedEntity
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '&':
+			text, n := decodeHTMLEntity(s[i:])
+			tb.Text(text)
+			i += n
+
+		case '<':
+			if strings.HasPrefix(s[i:], "</") {
+				end := strings.IndexByte(s[i:], '>')
+				if end < 0 {
+					return "", nil, fmt.Errorf("%w: unclosed end tag at byte offset %d", ErrInvalidMarkup, i)
+				}
+				tag := strings.ToLower(strings.TrimSpace(s[i+2 : i+end]))
+				if len(stack) == 0 {
+					return "", nil, fmt.Errorf("%w: unexpected end tag </%s> at byte offset %d", ErrInvalidMarkup, tag, i)
+				}
+				top := stack[len(stack)-1]
+				if top.tag != tag {
+					return "", nil, fmt.Errorf("%w: unmatched end tag at byte offset %d, expected </%s>, found </%s>", ErrInvalidMarkup, i, top.tag, tag)
+				}
+
+				stack = stack[:len(stack)-1]
+				tb.closeEntity(top.openedEntity)
+				i += end + 1
+				continue
+			}
+
+			tag, attrs, n, err := parseHTMLStartTag(s[i:])
+			if err != nil {
+				return "", nil, fmt.Errorf("%w: %s at byte offset %d", ErrInvalidMarkup, err.Error(), i)
+			}
+
+			ent, err := getHTMLEntity(tb, stack, tag, attrs)
+			if err != nil {
+				return "", nil, fmt.Errorf("%w: %s at byte offset %d", ErrInvalidMarkup, err.Error(), i)
+			}
+
+			stack = append(stack, parsedEntity{openedEntity: tb.openEntity(ent), tag: tag, pos: i})
+			i += n
+
+		default:
+			end := strings.IndexAny(s[i:], "<&")
+			if end < 0 {
+				end = len(s) - i
+			}
+			tb.Text(s[i : i+end])
+			i += end
+		}
+	}
+
+	if len(stack) > 0 {
+		top := stack[len(stack)-1]
+		return "", nil, fmt.Errorf("%w: can't find end tag corresponding to start tag <%s> at byte offset %d", ErrInvalidMarkup, top.tag, top.pos)
+	}
+	return tb.String(), tb.Entities(), nil
+}
+
+// decodeHTMLEntity decodes the HTML entity at the start of the input, and returns the decoded text and the number of
+// bytes consumed. Telegram only supports the &lt; &gt; &amp; and &quot; named entities, as well as numeric entities;
+// anything else is returned as-is.
+func decodeHTMLEntity(s string) (string, int) {
+	end := strings.IndexByte(s, ';')
+	if end < 0 || end > 10 {
+		return "&", 1
+	}
+
+	name := s[1:end]
+	switch name {
+	case "lt":
+		return "<", end + 1
+	case "gt":
+		return ">", end + 1
+	case "amp":
+		return "&", end + 1
+	case "quot":
+		return "\"", end + 1
+	}
+
+	if strings.HasPrefix(name, "#") {
+		var code uint64
+		var err error
+		if strings.HasPrefix(name, "#x") || strings.HasPrefix(name, "#X") {
+			code, err = strconv.ParseUint(name[2:], 16, 32)
+		} else {
+			code, err = strconv.ParseUint(name[1:], 10, 32)
+		}
+		if err == nil && code > 0 && utf8.ValidRune(rune(code)) {
+			return string(rune(code)), end + 1
+		}
+	}
+	return "&", 1
+}
+
+// decodeHTMLEntities decodes all the HTML entities in the input.
+func decodeHTMLEntities(s string) string {
+	bd := strings.Builder{}
+	for i := 0; i < len(s); {
+		if s[i] != '&' {
+			bd.WriteByte(s[i])
+			i++
+			continue
+		}
+		text, n := decodeHTMLEntity(s[i:])
+		bd.WriteString(text)
+		i += n
+	}
+	return bd.String()
+}
+
+// parseHTMLStartTag parses the HTML start tag at the start of the input, and returns the lowercased tag name, its
+// attributes, and the number of bytes consumed.
+func parseHTMLStartTag(s string) (string, map[string]string, int, error) {
+	i := 1
+	for i < len(s) && isHTMLNameChar(s[i]) {
+		i++
+	}
+	if i == 1 {
+		return "", nil, 0, errors.New("unexpected '<', which must be escaped as &lt;")
+	}
+	tag := strings.ToLower(s[1:i])
+
+	attrs := map[string]string{}
+	for {
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return "", nil, 0, fmt.Errorf("unclosed start tag <%s>", tag)
+		}
+		if s[i] == '>' {
+			return tag, attrs, i + 1, nil
+		}
+
+		start := i
+		for i < len(s) && isHTMLNameChar(s[i]) {
+			i++
+		}
+		if i == start {
+			return "", nil, 0, fmt.Errorf("unexpected character %q in start tag <%s>", s[i], tag)
+		}
+		name := strings.ToLower(s[start:i])
+
+		if i >= len(s) || s[i] != '=' {
+			// Attributes without values, such as "expandable".
+			attrs[name] = ""
+			continue
+		}
+		i++
+
+		if i >= len(s) {
+			return "", nil, 0, fmt.Errorf("unclosed start tag <%s>", tag)
+		}
+
+		var value string
+		if quote := s[i]; quote == '"' || quote == '\'' {
+			end := strings.IndexByte(s[i+1:], quote)
+			if end < 0 {
+				return "", nil, 0, fmt.Errorf("unclosed attribute value in start tag <%s>", tag)
+			}
+			value = s[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			start := i
+			for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+				i++
+			}
+			value = s[start:i]
+		}
+		attrs[name] = decodeHTMLEntities(value)
+	}
+}
+
+func isHTMLNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// getHTMLEntity returns the entity which should be opened for a given HTML tag. Tags which do not create an entity
+// of their own return an entity with an empty type.
+func getHTMLEntity(tb *TextBuilder, stack []parsedEntity, tag string, attrs map[string]string) (MessageEntity, error) {
+	switch tag {
+	case "b", "strong":
+		return MessageEntity{Type: "bold"}, nil
+	case "i", "em":
+		return MessageEntity{Type: "italic"}, nil
+	case "u", "ins":
+		return MessageEntity{Type: "underline"}, nil
+	case "s", "strike", "del":
+		return MessageEntity{Type: "strikethrough"}, nil
+	case "tg-spoiler":
+		return MessageEntity{Type: "spoiler"}, nil
+	case "span":
+		if attrs["class"] != "tg-spoiler" {
+			return MessageEntity{}, errors.New("tag <span> must have class \"tg-spoiler\"")
+		}
+		return MessageEntity{Type: "spoiler"}, nil
+	case "a":
+		href := attrs["href"]
+		if href == "" {
+			return MessageEntity{}, nil
+		}
+		if userId, ok := getMentionUserId(href); ok {
+			return MessageEntity{Type: "text_mention", User: &User{Id: userId}}, nil
+		}
+		return MessageEntity{Type: "text_link", Url: href}, nil
+	case "tg-emoji":
+		if attrs["emoji-id"] == "" {
+			return MessageEntity{}, errors.New("tag <tg-emoji> must have an emoji-id")
+		}
+		return MessageEntity{Type: "custom_emoji", CustomEmojiId: attrs["emoji-id"]}, nil
+	case "code":
+		if len(stack) > 0 {
+			if top := &stack[len(stack)-1]; top.ent.Type == "pre" && top.ent.Offset == tb.length {
+				// A <code> tag directly within a <pre> tag is used to set the pre block's language.
+				top.ent.Language = strings.TrimPrefix(attrs["class"], "language-")
+				return MessageEntity{}, nil
+			}
+		}
+		return MessageEntity{Type: "code"}, nil
+	case "pre":
+		return MessageEntity{Type: "pre"}, nil
+	case "blockquote":
+		if _, ok := attrs["expandable"]; ok {
+			return MessageEntity{Type: "expandable_blockquote"}, nil
+		}
+		return MessageEntity{Type: "blockquote"}, nil
+	default:
+		return MessageEntity{}, fmt.Errorf("unsupported start tag <%s>", tag)
+	}
+}
+
+// getMentionUserId returns the user ID contained in a "tg://user?id=<id>" URL.
+func getMentionUserId(url string) (int64, bool) {
+	if !strings.HasPrefix(url, "tg://user?id=") {
+		return 0, false
+	}
+	userId, err := strconv.ParseInt(strings.TrimPrefix(url, "tg://user?id="), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return userId, true
+}
+
+// ParseMarkdownV2 parses text formatted with the "MarkdownV2" parse mode, and returns the plain text, and the list of
+// entities describing its formatting. Entity offsets are computed in UTF-16 code units, as used by telegram.
+// Invalid markup, such as unescaped reserved characters, returns ErrInvalidMarkup.
+// See https://core.telegram.org/bots/api#markdownv2-style for more details.
+func ParseMarkdownV2(s string) (string, []MessageEntity, error) {
+	p := mdV2Parser{s: s, tb: &TextBuilder{}}
+	if err := p.parse(); err != nil {
+		return "", nil, err
+	}
+	return p.tb.String(), p.tb.Entities(), nil
+}
+
+// mdV2Parser holds the state of the MarkdownV2 parser.
+type mdV2Parser struct {
+	s     string
+	tb    *TextBuilder
+	stack []parsedEntity
+	// quote is the index of the currently open blockquote on the stack, or -1 if there is none.
+	quote int
+	// quoteEnded is set when the "||" marker ending an expandable blockquote is found.
+	quoteEnded bool
+}
+
+func (p *mdV2Parser) parse() error {
+	p.quote = -1
+	s := p.s
+	for i := 0; i < len(s); {
+		c := s[i]
+		lineStart := i == 0 || s[i-1] == '\n'
+
+		if top := p.top(); top != nil && (top.ent.Type == "code" || top.ent.Type == "pre") {
+			n, err := p.parseCode(i, top)
+			if err != nil {
+				return err
+			}
+			i += n
+			continue
+		}
+
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] > 0 && s[i+1] <= 126:
+			p.tb.Text(s[i+1 : i+2])
+			i += 2
+
+		case c == '_' && strings.HasPrefix(s[i:], "__"):
+			p.toggle("underline", "__", i)
+			i += 2
+		case c == '_':
+			p.toggle("italic", "_", i)
+			i++
+
+		case c == '*' && lineStart && p.quote < 0 && strings.HasPrefix(s[i:], "**>"):
+			p.push(MessageEntity{Type: "expandable_blockquote"}, "**>", i)
+			p.quote = len(p.stack) - 1
+			i += 3
+		case c == '*':
+			p.toggle("bold", "*", i)
+			i++
+
+		case c == '~':
+			p.toggle("strikethrough", "~", i)
+			i++
+
+		case c == '|' && strings.HasPrefix(s[i:], "||"):
+			if p.quote >= 0 && p.stack[p.quote].ent.Type == "expandable_blockquote" && p.top().ent.Type != "spoiler" &&
+				(i+2 == len(s) || s[i+2] == '\n') {
+				// The expandability mark at the end of the last line of an expandable blockquote.
+				p.quoteEnded = true
+			} else {
+				p.toggle("spoiler", "||", i)
+			}
+			i += 2
+
+		case c == '`' && strings.HasPrefix(s[i:], "```"):
+			i += p.openPre(i)
+		case c == '`':
+			p.push(MessageEntity{Type: "code"}, "`", i)
+			i++
+
+		case c == '>' && lineStart:
+			if p.quote < 0 {
+				p.push(MessageEntity{Type: "blockquote"}, ">", i)
+				p.quote = len(p.stack) - 1
+			}
+			// Otherwise, this is the continuation of the current blockquote.
+			i++
+
+		case c == '!' && strings.HasPrefix(s[i:], "!["):
+			p.push(MessageEntity{Type: "custom_emoji"}, "![", i)
+			i += 2
+		case c == '[':
+			p.push(MessageEntity{Type: "text_link"}, "[", i)
+			i++
+		case c == ']' && p.top() != nil && (p.top().tag == "[" || p.top().tag == "!["):
+			n, err := p.closeLink(i)
+			if err != nil {
+				return err
+			}
+			i += n
+
+		case c == '\n':
+			if err := p.closeQuote(i); err != nil {
+				return err
+			}
+			p.tb.Text("\n")
+			i++
+
+		case strings.IndexByte("_*[]()~`>#+-=|{}.!", c) >= 0:
+			return fmt.Errorf("%w: character %q is reserved and must be escaped with the preceding '\\' at byte offset %d", ErrInvalidMarkup, c, i)
+
+		default:
+			end := strings.IndexAny(s[i:], "\\_*[]()~`>#+-=|{}.!\n")
+			if end < 0 {
+				end = len(s) - i
+			}
+			if end == 0 {
+				// Unmatched backslash at the end of the text, or followed by a non-ASCII character.
+				end = 1
+			}
+			p.tb.Text(s[i : i+end])
+			i += end
+		}
+	}
+
+	if err := p.closeQuote(len(s)); err != nil {
+		return err
+	}
+	if top := p.top(); top != nil {
+		return fmt.Errorf("%w: can't find end of %s entity at byte offset %d", ErrInvalidMarkup, top.ent.Type, top.pos)
+	}
+	return nil
+}
+
+// top returns the innermost open entity.
+func (p *mdV2Parser) top() *parsedEntity {
+	if len(p.stack) == 0 {
+		return nil
+	}
+	return &p.stack[len(p.stack)-1]
+}
+
+func (p *mdV2Parser) push(ent MessageEntity, tag string, pos int) {
+	p.stack = append(p.stack, parsedEntity{openedEntity: p.tb.openEntity(ent), tag: tag, pos: pos})
+}
+
+func (p *mdV2Parser) pop() {
+	p.tb.closeEntity(p.top().openedEntity)
+	p.stack = p.stack[:len(p.stack)-1]
+}
+
+// toggle closes the innermost entity if it has the given type, or opens a new one otherwise.
+func (p *mdV2Parser) toggle(entType string, tag string, pos int) {
+	if top := p.top(); top != nil && top.ent.Type == entType {
+		p.pop()
+		return
+	}
+	p.push(MessageEntity{Type: entType}, tag, pos)
+}
+
+// parseCode parses the contents of code and pre entities, in which only backslashes and backticks are special.
+func (p *mdV2Parser) parseCode(i int, top *parsedEntity) (int, error) {
+	s := p.s
+	switch {
+	case s[i] == '\\' && i+1 < len(s) && s[i+1] > 0 && s[i+1] <= 126:
+		p.tb.Text(s[i+1 : i+2])
+		return 2, nil
+	case s[i] == '`' && top.ent.Type == "code":
+		p.pop()
+		return 1, nil
+	case s[i] == '`' && strings.HasPrefix(s[i:], "```"):
+		p.pop()
+		return 3, nil
+	case s[i] == '`':
+		return 0, fmt.Errorf("%w: character '`' is reserved and must be escaped with the preceding '\\' at byte offset %d", ErrInvalidMarkup, i)
+	}
+
+	end := strings.IndexAny(s[i+1:], "\\`")
+	if end < 0 {
+		end = len(s) - i - 1
+	}
+	p.tb.Text(s[i : i+1+end])
+	return end + 1, nil
+}
+
+// openPre opens a pre entity, reading the optional language from the first line.
+func (p *mdV2Parser) openPre(pos int) int {
+	s := p.s
+	i := pos + 3
+	ent := MessageEntity{Type: "pre"}
+
+	langEnd := i
+	for langEnd < len(s) && !isHTMLSpace(s[langEnd]) && s[langEnd] != '`' {
+		langEnd++
+	}
+	if langEnd != i && langEnd < len(s) && s[langEnd] != '`' {
+		ent.Language = s[i:langEnd]
+		i = langEnd
+	}
+
+	// Skip the newline at the start of the pre block.
+	if strings.HasPrefix(s[i:], "\r\n") {
+		i += 2
+	} else if i < len(s) && (s[i] == '\n' || s[i] == '\r') {
+		i++
+	}
+
+	p.push(ent, "```", pos)
+	return i - pos
+}
+
+// closeLink closes a link or custom emoji, reading the URL contained in the following brackets.
+func (p *mdV2Parser) closeLink(pos int) (int, error) {
+	s := p.s
+	top := p.top()
+	i := pos + 1
+
+	if i >= len(s) || s[i] != '(' {
+		if top.ent.Type == "custom_emoji" {
+			return 0, fmt.Errorf("%w: custom emoji at byte offset %d must have a URL", ErrInvalidMarkup, top.pos)
+		}
+		// Links without a URL are kept as text.
+		top.ent.Type = ""
+		p.pop()
+		return 1, nil
+	}
+
+	url := strings.Builder{}
+	for i++; ; i++ {
+		if i >= len(s) {
+			return 0, fmt.Errorf("%w: can't find end of URL at byte offset %d", ErrInvalidMarkup, pos+1)
+		}
+		if s[i] == ')' {
+			break
+		}
+		if s[i] == '\\' && i+1 < len(s) && s[i+1] > 0 && s[i+1] <= 126 {
+			i++
+		}
+		url.WriteByte(s[i])
+	}
+
+	if top.ent.Type == "custom_emoji" {
+		id := strings.TrimPrefix(url.String(), "tg://emoji?id=")
+		if _, err := strconv.ParseInt(id, 10, 64); !strings.HasPrefix(url.String(), "tg://emoji?id=") || err != nil {
+			return 0, fmt.Errorf("%w: invalid custom emoji URL %q at byte offset %d", ErrInvalidMarkup, url.String(), pos+1)
+		}
+		top.ent.CustomEmojiId = id
+	} else if userId, ok := getMentionUserId(url.String()); ok {
+		top.ent = MessageEntity{Type: "text_mention", Offset: top.ent.Offset, User: &User{Id: userId}}
+	} else {
+		top.ent.Url = url.String()
+		if top.ent.Url == "" {
+			top.ent.Type = ""
+		}
+	}
+
+	p.pop()
+	return i + 1 - pos, nil
+}
+
+// closeQuote closes the current blockquote when reaching the end of a line, unless the next line continues it.
+func (p *mdV2Parser) closeQuote(pos int) error {
+	if p.quote < 0 {
+		return nil
+	}
+	if !p.quoteEnded && pos+1 < len(p.s) && p.s[pos+1] == '>' {
+		return nil
+	}
+
+	if p.quote != len(p.stack)-1 {
+		top := p.top()
+		return fmt.Errorf("%w: can't find end of %s entity at byte offset %d", ErrInvalidMarkup, top.ent.Type, top.pos)
+	}
+	p.pop()
+	p.quote = -1
+	p.quoteEnded = false
+	return nil
+}
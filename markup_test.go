@@ -0,0 +1,213 @@
+package gotgbot
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParseHTML(t *testing.T) {
+	text, entities, err := ParseHTML(`<b>bold <i>both</i></b> &lt;&#128077;&gt; <a href="tg://user?id=123">user</a> ` +
+		`<a href='https://example.com/?a=1&amp;b=2'>link</a> <span class="tg-spoiler">s</span>` + "\n" +
+		`<pre><code class="language-go">x := 1</code></pre><blockquote expandable>quote</blockquote>`)
+	if err != nil {
+		t.Fatalf("failed to parse HTML: %v", err)
+	}
+
+	expectedText := "bold both <👍> user link s\nx := 1quote"
+	if text != expectedText {
+		t.Errorf("unexpected text: %q", text)
+	}
+
+	expectedEntities := []MessageEntity{
+		{Type: "bold", Offset: 0, Length: 9},
+		{Type: "italic", Offset: 5, Length: 4},
+		{Type: "text_mention", Offset: 15, Length: 4, User: &User{Id: 123}},
+		{Type: "text_link", Offset: 20, Length: 4, Url: "https://example.com/?a=1&b=2"},
+		{Type: "spoiler", Offset: 25, Length: 1},
+		{Type: "pre", Offset: 27, Length: 6, Language: "go"},
+		{Type: "expandable_blockquote", Offset: 33, Length: 5},
+	}
+	if !reflect.DeepEqual(entities, expectedEntities) {
+		t.Errorf("unexpected entities:\n%+v\nexpected:\n%+v", entities, expectedEntities)
+	}
+}
+
+func TestParseMarkdownV2(t *testing.T) {
+	text, entities, err := ParseMarkdownV2("*bold _both_* \\<👍\\> [user](tg://user?id=123) [link](https://example.com/\\(a\\)) " +
+		"||s|| ![👍](tg://emoji?id=5368324170671202286)\n```go\nx := \\`1\\`\\\\``` ___a_**__\n>quote\n>line\ntext\n**>expandable||")
+	if err != nil {
+		t.Fatalf("failed to parse markdownV2: %v", err)
+	}
+
+	expectedText := "bold both <👍> user link s 👍\nx := `1`\\ a\nquote\nline\ntext\nexpandable"
+	if text != expectedText {
+		t.Errorf("unexpected text: %q", text)
+	}
+
+	expectedEntities := []MessageEntity{
+		{Type: "bold", Offset: 0, Length: 9},
+		{Type: "italic", Offset: 5, Length: 4},
+		{Type: "text_mention", Offset: 15, Length: 4, User: &User{Id: 123}},
+		{Type: "text_link", Offset: 20, Length: 4, Url: "https://example.com/(a)"},
+		{Type: "spoiler", Offset: 25, Length: 1},
+		{Type: "custom_emoji", Offset: 27, Length: 2, CustomEmojiId: "5368324170671202286"},
+		{Type: "pre", Offset: 30, Length: 9, Language: "go"},
+		{Type: "underline", Offset: 40, Length: 1},
+		{Type: "italic", Offset: 40, Length: 1},
+		{Type: "blockquote", Offset: 42, Length: 10},
+		{Type: "expandable_blockquote", Offset: 58, Length: 10},
+	}
+	if !reflect.DeepEqual(entities, expectedEntities) {
+		t.Errorf("unexpected entities:\n%+v\nexpected:\n%+v", entities, expectedEntities)
+	}
+}
+
+func TestParseInvalidMarkup(t *testing.T) {
+	for name, parse := range map[string]func() error{
+		"html unsupported tag": func() error {
+			_, _, err := ParseHTML("<div>text</div>")
+			return err
+		},
+		"html unescaped bracket": func() error {
+			_, _, err := ParseHTML("1 < 2")
+			return err
+		},
+		"html unclosed tag": func() error {
+			_, _, err := ParseHTML("<b>text")
+			return err
+		},
+		"html unmatched end tag": func() error {
+			_, _, err := ParseHTML("<b><i>text</b></i>")
+			return err
+		},
+		"markdownV2 reserved character": func() error {
+			_, _, err := ParseMarkdownV2("end.")
+			return err
+		},
+		"markdownV2 unclosed entity": func() error {
+			_, _, err := ParseMarkdownV2("*bold")
+			return err
+		},
+		"markdownV2 unescaped backtick in pre": func() error {
+			_, _, err := ParseMarkdownV2("```\na`b```")
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := parse(); !errors.Is(err, ErrInvalidMarkup) {
+				t.Errorf("expected ErrInvalidMarkup, got %v", err)
+			}
+		})
+	}
+}
+
+// TestMarkupRoundTrip checks that converting random entities to markup, and parsing that markup, returns the original
+// entities.
+func TestMarkupRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1)) //nolint:gosec // Deterministic randomness is fine for tests.
+	for i := 0; i < 1000; i++ {
+		tb := NewTextBuilder()
+		writeRandomLines(r, tb)
+		m := Message{Text: tb.String(), Entities: tb.Entities()}
+
+		html := m.OriginalHTML()
+		text, entities, err := ParseHTML(html)
+		if err != nil {
+			t.Fatalf("failed to parse HTML %q: %v", html, err)
+		}
+		if text != m.Text || !reflect.DeepEqual(entities, m.Entities) {
+			t.Fatalf("HTML %q did not round trip:\n%q %+v\nexpected:\n%q %+v", html, text, entities, m.Text, m.Entities)
+		}
+
+		mdV2 := m.OriginalMDV2()
+		text, entities, err = ParseMarkdownV2(mdV2)
+		if err != nil {
+			t.Fatalf("failed to parse markdownV2 %q: %v", mdV2, err)
+		}
+		if text != m.Text || !reflect.DeepEqual(entities, m.Entities) {
+			t.Fatalf("markdownV2 %q did not round trip:\n%q %+v\nexpected:\n%q %+v", mdV2, text, entities, m.Text, m.Entities)
+		}
+	}
+}
+
+var randomWords = []string{"hello", "snake_case", "1.5", "<b>", "a&b", "👍", "[x](y)", "back\\slash", "`tick`", "#tag", "ü"}
+
+func writeRandomLines(r *rand.Rand, tb *TextBuilder) {
+	lines := 1 + r.Intn(3)
+	prevQuote := false
+	for i := 0; i < lines; i++ {
+		if i > 0 {
+			tb.Text("\n")
+		}
+
+		lineType := r.Intn(6)
+		if lineType == 0 && prevQuote {
+			// Consecutive blockquotes are merged in MarkdownV2.
+			lineType = 1
+		}
+		prevQuote = lineType == 0
+
+		switch lineType {
+		case 0:
+			tb.Nest(MessageEntity{Type: "blockquote"}, func(tb *TextBuilder) {
+				writeRandomInline(r, tb, 2, nil)
+				tb.Text("\n")
+				writeRandomInline(r, tb, 2, nil)
+			})
+		case 1:
+			tb.Nest(MessageEntity{Type: "expandable_blockquote"}, func(tb *TextBuilder) {
+				writeRandomInline(r, tb, 2, nil)
+			})
+		case 2:
+			tb.Pre(randomWords[r.Intn(len(randomWords))], []string{"", "go"}[r.Intn(2)])
+		default:
+			writeRandomInline(r, tb, 3, nil)
+		}
+	}
+}
+
+// writeRandomInline writes random words with inline entities. Entities are not nested within entities of the same
+// type, since telegram merges those.
+func writeRandomInline(r *rand.Rand, tb *TextBuilder, depth int, parents []string) {
+	words := 1 + r.Intn(3)
+	for i := 0; i < words; i++ {
+		if i > 0 {
+			tb.Text(" ")
+		}
+
+		word := randomWords[r.Intn(len(randomWords))]
+		switch r.Intn(8) {
+		case 0:
+			tb.Code(word)
+		case 1:
+			tb.Link(word, "https://example.com/?q=("+strconv.Itoa(r.Intn(10))+")")
+		case 2:
+			tb.Mention(word, User{Id: r.Int63n(1000) + 1})
+		case 3:
+			tb.CustomEmoji("👍", strconv.Itoa(r.Intn(1000)+1))
+		case 4, 5:
+			entType := []string{"bold", "italic", "underline", "strikethrough", "spoiler"}[r.Intn(5)]
+			if depth > 0 && !containsString(entType, parents) {
+				tb.Nest(MessageEntity{Type: entType}, func(tb *TextBuilder) {
+					writeRandomInline(r, tb, depth-1, append(parents[:len(parents):len(parents)], entType))
+				})
+				continue
+			}
+			tb.Text(word)
+		default:
+			tb.Text(word)
+		}
+	}
+}
+
+func containsString(s string, ss []string) bool {
+	for _, x := range ss {
+		if s == x {
+			return true
+		}
+	}
+	return false
+}
@@ -39,9 +39,15 @@ func main() {
 	updater := ext.NewUpdater(dispatcher, nil)
 
 	// /start command to introduce the bot
-	dispatcher.AddHandler(handlers.NewCommand("start", start))
+	dispatcher.AddHandler(handlers.NewCommand("start", start).SetDescription("Introduce the bot"))
 	// /source command to send the bot source code
-	dispatcher.AddHandler(handlers.NewCommand("source", source))
+	dispatcher.AddHandler(handlers.NewCommand("source", source).SetDescription("Get the bot's source code"))
+
+	// Register the command descriptions with telegram, so they show up in the command menu.
+	err = dispatcher.SyncBotCommands(b, nil)
+	if err != nil {
+		panic("failed to sync bot commands: " + err.Error())
+	}
 
 	// Start receiving updates.
 	err = updater.StartPolling(b, &ext.PollingOpts{
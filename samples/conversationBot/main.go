@@ -12,6 +12,7 @@ import (
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/conversation"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
 )
 
@@ -42,6 +43,9 @@ func main() {
 	})
 	updater := ext.NewUpdater(dispatcher, nil)
 
+	// Create a matcher which only matches text which is not a command.
+	noCommands := filters.And(message.Text, filters.Not(message.Command))
+
 	dispatcher.AddHandler(handlers.NewConversation(
 		[]ext.Handler{handlers.NewCommand("start", start)},
 		map[string][]ext.Handler{
@@ -80,11 +84,6 @@ const (
 	AGE  = "age"
 )
 
-// Create a matcher which only matches text which is not a command.
-func noCommands(msg *gotgbot.Message) bool {
-	return message.Text(msg) && !message.Command(msg)
-}
-
 // start introduces the bot and starts the conversation.
 func start(b *gotgbot.Bot, ctx *ext.Context) error {
 	_, err := ctx.EffectiveMessage.Reply(b, fmt.Sprintf("Hello, I'm @%s.\nWhat is your name?.", b.User.Username), &gotgbot.SendMessageOpts{
@@ -0,0 +1,7 @@
+module github.com/PaulSonOfLars/gotgbot/samples/sessionBot
+
+go 1.19
+
+require github.com/PaulSonOfLars/gotgbot/v2 v2.99.99
+
+replace github.com/PaulSonOfLars/gotgbot/v2 => ../../
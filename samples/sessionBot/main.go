@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/session"
+)
+
+// This bot demonstrates how to store per-user and per-chat data using sessions.
+// Sessions are loaded when a handler first accesses them, and saved automatically once the update has been handled.
+// This example stores sessions in files, so that they persist across restarts.
+func main() {
+	// Get token from the environment variable
+	token := os.Getenv("TOKEN")
+	if token == "" {
+		panic("TOKEN environment variable is empty")
+	}
+
+	// Create bot from environment value.
+	b, err := gotgbot.NewBot(token, nil)
+	if err != nil {
+		panic("failed to create new bot: " + err.Error())
+	}
+
+	// Create updater and dispatcher.
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{
+		// If an error is returned by a handler, log it and continue going.
+		Error: func(b *gotgbot.Bot, ctx *ext.Context, err error) ext.DispatcherAction {
+			log.Println("an error occurred while handling update:", err.Error())
+			return ext.DispatcherActionNoop
+		},
+		// Errors saving sessions (eg, a session.ErrConflict) are reported here.
+		UnhandledErrFunc: func(err error) {
+			log.Println("failed to process update:", err.Error())
+		},
+		// The session processor makes sessions available to all handlers.
+		Processor:   session.NewProcessor(session.NewFileStore("sessions"), nil),
+		MaxRoutines: ext.DefaultMaxRoutines,
+	})
+	updater := ext.NewUpdater(dispatcher, nil)
+
+	dispatcher.AddHandler(handlers.NewCommand("start", start))
+	dispatcher.AddHandler(handlers.NewCommand("reset", reset))
+
+	// Start receiving updates.
+	err = updater.StartPolling(b, &ext.PollingOpts{
+		DropPendingUpdates: true,
+		GetUpdatesOpts: &gotgbot.GetUpdatesOpts{
+			Timeout: 9,
+			RequestOpts: &gotgbot.RequestOpts{
+				Timeout: time.Second * 10,
+			},
+		},
+	})
+	if err != nil {
+		panic("failed to start polling: " + err.Error())
+	}
+	log.Printf("%s has been started...\n", b.User.Username)
+
+	// Idle, to keep updates coming in, and avoid bot stopping.
+	updater.Idle()
+}
+
+// userData is the data stored for each user. Only exported fields are stored.
+type userData struct {
+	Starts int
+}
+
+// chatData is the data stored for each chat.
+type chatData struct {
+	Starts   int
+	LastUser string
+}
+
+// start counts how many times the start command has been used, by the current user and in the current chat.
+func start(b *gotgbot.Bot, ctx *ext.Context) error {
+	user, err := session.User[userData](ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get user session: %w", err)
+	}
+	chat, err := session.Chat[chatData](ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chat session: %w", err)
+	}
+
+	// Changes are saved once the handler returns.
+	user.Starts++
+	chat.Starts++
+	previous := chat.LastUser
+	chat.LastUser = ctx.EffectiveUser.FirstName
+
+	text := fmt.Sprintf("You have pressed start %d times; this chat has pressed start %d times.", user.Starts, chat.Starts)
+	if previous != "" {
+		text += fmt.Sprintf("\nThe last person to press start here was %s.", previous)
+	}
+
+	_, err = ctx.EffectiveMessage.Reply(b, text, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send start message: %w", err)
+	}
+	return nil
+}
+
+// reset deletes the current user's session.
+func reset(b *gotgbot.Bot, ctx *ext.Context) error {
+	err := session.Delete(ctx, session.ScopeUser)
+	if err != nil {
+		return fmt.Errorf("failed to delete user session: %w", err)
+	}
+
+	_, err = ctx.EffectiveMessage.Reply(b, "Your start count has been reset.", nil)
+	if err != nil {
+		return fmt.Errorf("failed to send reset message: %w", err)
+	}
+	return nil
+}
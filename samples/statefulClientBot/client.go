@@ -20,6 +20,7 @@ type client struct {
 	// The second map has values of type "any" so anything can be stored in them, for the purpose of this example.
 	// This could be improved by using a struct with typed fields, though this would need some additional handling to
 	// ensure concurrent safety.
+	// For typed and persistent per-user or per-chat data, see the ext/session package, as used in samples/sessionBot.
 	userData map[int64]map[string]any
 
 	// This struct could also contain:
@@ -82,12 +82,19 @@ func main() {
 		panic("Failed to set bot webhooks: " + err.Error())
 	}
 
+	// Create the middleware to authenticate requests from our webapp.
+	webappAuth, err := ext.NewWebAppAuth([]string{token}, nil)
+	if err != nil {
+		panic("failed to create webapp auth middleware: " + err.Error())
+	}
+
 	// Setup new HTTP server mux to handle different paths.
 	mux := http.NewServeMux()
 	// This serves the home page.
 	mux.HandleFunc("/", index(webappURL))
 	// This serves our "validation" API, which checks if the input data is valid.
-	mux.HandleFunc("/validate", validate(token))
+	// The WebAppAuth middleware rejects any requests which do not contain valid initData for our bot.
+	mux.Handle("/validate", webappAuth.Middleware(http.HandlerFunc(validate)))
 	// This serves the updater's webhook handler.
 	mux.HandleFunc(updaterSubpath, updater.GetHandlerFunc(updaterSubpath))
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"text/template"
 
@@ -23,18 +24,13 @@ func index(webappURL string) func(writer http.ResponseWriter, request *http.Requ
 	}
 }
 
-func validate(token string) func(writer http.ResponseWriter, request *http.Request) {
-	return func(writer http.ResponseWriter, request *http.Request) {
-		ok, err := ext.ValidateWebAppQuery(request.URL.Query(), token)
-		if err != nil {
-			writer.WriteHeader(http.StatusBadRequest)
-			writer.Write([]byte("validation failed; error: " + err.Error()))
-			return
-		}
-		if ok {
-			writer.Write([]byte("validation success; user is authenticated."))
-		} else {
-			writer.Write([]byte("validation failed; data cannot be trusted."))
-		}
+// validate is only called for requests which have been authenticated by the ext.WebAppAuth middleware, so the
+// webapp user can be read from the request context.
+func validate(writer http.ResponseWriter, request *http.Request) {
+	user, ok := ext.WebAppUserFromContext(request.Context())
+	if !ok {
+		writer.Write([]byte("validation success; but no user was provided."))
+		return
 	}
+	writer.Write([]byte(fmt.Sprintf("validation success; user %d is authenticated.", user.Id)))
 }
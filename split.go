@@ -0,0 +1,173 @@
+package gotgbot
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf16"
+)
+
+const (
+	// MaxMessageLength is the maximum length of a message text, in UTF-16 code units.
+	MaxMessageLength = 4096
+	// MaxCaptionLength is the maximum length of a media caption, in UTF-16 code units.
+	MaxCaptionLength = 1024
+)
+
+// TextChunk is a part of a formatted text, as returned by SplitText.
+type TextChunk struct {
+	Text     string
+	Entities []MessageEntity
+}
+
+// Split splits the message text (or caption, for media messages) into chunks of at most maxLen UTF-16 code units.
+// See SplitText for more details.
+func (m Message) Split(maxLen int64) []TextChunk {
+	return SplitText(m.GetText(), m.GetEntities(), maxLen)
+}
+
+// SplitText splits a formatted text into chunks of at most maxLen UTF-16 code units, as counted by telegram.
+// If maxLen is 0 or less, MaxMessageLength is used.
+//
+// Texts are split at paragraph boundaries if possible, then at line boundaries, then at word boundaries, and only cut
+// mid-word as a last resort; surrogate pairs are never split. The whitespace at the split point is dropped.
+// Entities crossing a split are cut, and reopened in the next chunk, with their offsets rebased to each chunk.
+func SplitText(text string, entities []MessageEntity, maxLen int64) []TextChunk {
+	if maxLen <= 0 {
+		maxLen = MaxMessageLength
+	}
+
+	utf16Text := utf16.Encode([]rune(text))
+	if int64(len(utf16Text)) <= maxLen {
+		return []TextChunk{{Text: text, Entities: entities}}
+	}
+
+	var chunks []TextChunk
+	start := int64(0)
+	for start < int64(len(utf16Text)) {
+		end, next := getSplitPoint(utf16Text, start, maxLen)
+		chunks = append(chunks, TextChunk{
+			Text:     string(utf16.Decode(utf16Text[start:end])),
+			Entities: getChunkEntities(entities, start, end),
+		})
+		start = next
+	}
+	return chunks
+}
+
+// getSplitPoint finds where to end the chunk starting at the given offset, and where the next chunk should start.
+func getSplitPoint(utf16Text []uint16, start int64, maxLen int64) (int64, int64) {
+	limit := start + maxLen
+	if limit >= int64(len(utf16Text)) {
+		return int64(len(utf16Text)), int64(len(utf16Text))
+	}
+
+	// Find the best boundary, splitting at the last paragraph, line or word break within the limit.
+	for _, isBreak := range []func(i int64) bool{
+		func(i int64) bool { return utf16Text[i] == '\n' && i > 0 && utf16Text[i-1] == '\n' },
+		func(i int64) bool { return utf16Text[i] == '\n' },
+		func(i int64) bool { return unicode.IsSpace(rune(utf16Text[i])) },
+	} {
+		for i := limit; i > start; i-- {
+			if !isBreak(i) {
+				continue
+			}
+
+			end, next := i, i
+			for end > start && unicode.IsSpace(rune(utf16Text[end-1])) {
+				end--
+			}
+			for next < int64(len(utf16Text)) && unicode.IsSpace(rune(utf16Text[next])) {
+				next++
+			}
+			if end > start {
+				return end, next
+			}
+		}
+	}
+
+	// No boundary found; cut mid-word, making sure not to split a surrogate pair.
+	end := limit
+	if utf16.IsSurrogate(rune(utf16Text[end-1])) && utf16Text[end-1] < 0xdc00 {
+		end--
+	}
+	return end, end
+}
+
+// getChunkEntities returns the entities contained within the [start, end) range, cut to fit the range and rebased to
+// its start.
+func getChunkEntities(entities []MessageEntity, start int64, end int64) []MessageEntity {
+	var out []MessageEntity
+	for _, ent := range entities {
+		entStart := ent.Offset
+		entEnd := ent.Offset + ent.Length
+		if entStart < start {
+			entStart = start
+		}
+		if entEnd > end {
+			entEnd = end
+		}
+		if entEnd <= entStart {
+			continue
+		}
+
+		ent.Offset = entStart - start
+		ent.Length = entEnd - entStart
+		out = append(out, ent)
+	}
+	return out
+}
+
+// SendLongMessage sends a message which may be longer than telegram's MaxMessageLength limit, by splitting it into
+// multiple messages with SplitText. Each message is sent as a reply to the previous one.
+//
+// If opts.ParseMode is set to "HTML" or "MarkdownV2", the text is parsed with ParseHTML or ParseMarkdownV2 to split
+// it without breaking the formatting; the legacy "Markdown" parse mode is not supported. Any ReplyMarkup is only
+// attached to the last message.
+// The messages which were sent successfully are returned, even if an error occurs.
+func (bot *Bot) SendLongMessage(chatId int64, text string, opts *SendMessageOpts) ([]*Message, error) {
+	var sendOpts SendMessageOpts
+	if opts != nil {
+		sendOpts = *opts
+	}
+
+	entities := sendOpts.Entities
+	switch sendOpts.ParseMode {
+	case ParseModeNone:
+	case ParseModeHTML:
+		var err error
+		text, entities, err = ParseHTML(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML text: %w", err)
+		}
+	case ParseModeMarkdownV2:
+		var err error
+		text, entities, err = ParseMarkdownV2(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MarkdownV2 text: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported parse mode %q for long messages", sendOpts.ParseMode)
+	}
+	sendOpts.ParseMode = ParseModeNone
+
+	chunks := SplitText(text, entities, MaxMessageLength)
+	msgs := make([]*Message, 0, len(chunks))
+	replyMarkup := sendOpts.ReplyMarkup
+	for i, chunk := range chunks {
+		sendOpts.Entities = chunk.Entities
+		sendOpts.ReplyMarkup = nil
+		if i == len(chunks)-1 {
+			sendOpts.ReplyMarkup = replyMarkup
+		}
+		if i > 0 {
+			sendOpts.ReplyParameters = &ReplyParameters{MessageId: msgs[i-1].MessageId}
+		}
+
+		msg, err := bot.SendMessage(chatId, chunk.Text, &sendOpts)
+		if err != nil {
+			return msgs, fmt.Errorf("failed to send message %d of %d: %w", i+1, len(chunks), err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
@@ -0,0 +1,143 @@
+package gotgbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSplitText(t *testing.T) {
+	for name, testParams := range map[string]struct {
+		text     string
+		entities []MessageEntity
+		maxLen   int64
+		expected []TextChunk
+	}{
+		"short text": {
+			text:     "hello",
+			maxLen:   10,
+			expected: []TextChunk{{Text: "hello"}},
+		},
+		"paragraphs before lines": {
+			text:   "aaaa\n\nbb\ncc dd",
+			maxLen: 12,
+			expected: []TextChunk{
+				{Text: "aaaa"},
+				{Text: "bb\ncc dd"},
+			},
+		},
+		"lines before words": {
+			text:   "aa bb\ncc dd",
+			maxLen: 9,
+			expected: []TextChunk{
+				{Text: "aa bb"},
+				{Text: "cc dd"},
+			},
+		},
+		"words": {
+			text:   "aa bb cc",
+			maxLen: 6,
+			expected: []TextChunk{
+				{Text: "aa bb"},
+				{Text: "cc"},
+			},
+		},
+		"cut mid-word without splitting surrogate pairs": {
+			text:   "a👍👍",
+			maxLen: 4,
+			expected: []TextChunk{
+				{Text: "a👍"},
+				{Text: "👍"},
+			},
+		},
+		"entities are cut and reopened": {
+			text: "hello world 👍 foo bar",
+			entities: []MessageEntity{
+				{Type: "bold", Offset: 0, Length: 5},
+				{Type: "italic", Offset: 6, Length: 12},
+				{Type: "code", Offset: 19, Length: 3},
+			},
+			maxLen: 14,
+			expected: []TextChunk{
+				{Text: "hello world 👍", Entities: []MessageEntity{
+					{Type: "bold", Offset: 0, Length: 5},
+					{Type: "italic", Offset: 6, Length: 8},
+				}},
+				{Text: "foo bar", Entities: []MessageEntity{
+					{Type: "italic", Offset: 0, Length: 3},
+					{Type: "code", Offset: 4, Length: 3},
+				}},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			chunks := SplitText(testParams.text, testParams.entities, testParams.maxLen)
+			if !reflect.DeepEqual(chunks, testParams.expected) {
+				t.Errorf("unexpected chunks:\n%+v\nexpected:\n%+v", chunks, testParams.expected)
+			}
+		})
+	}
+}
+
+func TestSendLongMessage(t *testing.T) {
+	var mux sync.Mutex
+	var sent []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("failed to decode params: %v", err)
+			return
+		}
+
+		mux.Lock()
+		sent = append(sent, params)
+		msgId := len(sent)
+		mux.Unlock()
+
+		fmt.Fprintf(w, `{"ok": true, "result": {"message_id": %d, "chat": {"id": 1}}}`, msgId)
+	}))
+	defer server.Close()
+
+	b := &Bot{
+		Token: "123:SOME_TOKEN",
+		BotClient: &BaseBotClient{
+			DefaultRequestOpts: &RequestOpts{APIURL: server.URL},
+		},
+	}
+
+	text := "<b>" + strings.Repeat("a", MaxMessageLength-1) + " bold</b> text"
+	msgs, err := b.SendLongMessage(1, text, &SendMessageOpts{
+		ParseMode: ParseModeHTML,
+		ReplyMarkup: InlineKeyboardMarkup{
+			InlineKeyboard: [][]InlineKeyboardButton{{{Text: "button", CallbackData: "data"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to send long message: %v", err)
+	}
+	if len(msgs) != 2 || len(sent) != 2 {
+		t.Fatalf("expected 2 messages to be sent, got %d", len(sent))
+	}
+
+	if sent[0]["parse_mode"] != "" || sent[0]["entities"] != fmt.Sprintf(`[{"type":"bold","offset":0,"length":%d}]`, MaxMessageLength-1) {
+		t.Errorf("unexpected first message formatting: %q %q", sent[0]["parse_mode"], sent[0]["entities"])
+	}
+	if sent[0]["reply_markup"] != "" {
+		t.Errorf("expected reply markup to only be set on the last message")
+	}
+
+	if sent[1]["text"] != "bold text" || sent[1]["entities"] != `[{"type":"bold","offset":0,"length":4}]` {
+		t.Errorf("unexpected second message: %q %q", sent[1]["text"], sent[1]["entities"])
+	}
+	if sent[1]["reply_parameters"] != `{"message_id":1}` {
+		t.Errorf("expected second message to reply to the first, got %q", sent[1]["reply_parameters"])
+	}
+	if sent[1]["reply_markup"] == "" {
+		t.Errorf("expected reply markup to be set on the last message")
+	}
+}
@@ -0,0 +1,170 @@
+package gotgbot
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// TextBuilder composes formatted message text, without having to escape any of the contents by hand.
+// The result can be used as plain text with a list of MessageEntity, or as HTML or MarkdownV2:
+//
+//	tb := gotgbot.NewTextBuilder().Text("Hello, ").Mention(user.FirstName, *user).Text("!")
+//	_, err := b.SendMessage(chatId, tb.String(), &gotgbot.SendMessageOpts{Entities: tb.Entities()})
+//
+// The zero value is ready to use.
+type TextBuilder struct {
+	// text contains the plain text contents.
+	text strings.Builder
+	// length is the length of the text, in UTF-16 code units, as used by telegram's entity offsets.
+	length int64
+	// entities contains the entities of the text, sorted by offset.
+	entities []MessageEntity
+}
+
+// NewTextBuilder creates a new, empty, TextBuilder.
+func NewTextBuilder() *TextBuilder {
+	return &TextBuilder{}
+}
+
+// Text adds unformatted text.
+func (tb *TextBuilder) Text(s string) *TextBuilder {
+	tb.text.WriteString(s)
+	tb.length += utf16Len(s)
+	return tb
+}
+
+// Bold adds bold text.
+func (tb *TextBuilder) Bold(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "bold"}, s)
+}
+
+// Italic adds italic text.
+func (tb *TextBuilder) Italic(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "italic"}, s)
+}
+
+// Underline adds underlined text.
+func (tb *TextBuilder) Underline(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "underline"}, s)
+}
+
+// Strikethrough adds strikethrough text.
+func (tb *TextBuilder) Strikethrough(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "strikethrough"}, s)
+}
+
+// Spoiler adds text hidden behind a spoiler.
+func (tb *TextBuilder) Spoiler(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "spoiler"}, s)
+}
+
+// Code adds inline monospace text.
+func (tb *TextBuilder) Code(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "code"}, s)
+}
+
+// Pre adds a pre-formatted code block. The language is optional, and can be left empty.
+func (tb *TextBuilder) Pre(s string, language string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "pre", Language: language}, s)
+}
+
+// Link adds text which links to the given URL.
+func (tb *TextBuilder) Link(s string, url string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "text_link", Url: url}, s)
+}
+
+// Mention adds text which mentions the given user, even if they do not have a username.
+func (tb *TextBuilder) Mention(s string, user User) *TextBuilder {
+	return tb.add(MessageEntity{Type: "text_mention", User: &user}, s)
+}
+
+// CustomEmoji adds a custom emoji. The emoji parameter is the standard emoji to display to clients which cannot show
+// custom emoji.
+func (tb *TextBuilder) CustomEmoji(emoji string, customEmojiId string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "custom_emoji", CustomEmojiId: customEmojiId}, emoji)
+}
+
+// Blockquote adds a block quotation.
+func (tb *TextBuilder) Blockquote(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "blockquote"}, s)
+}
+
+// Nest wraps all the contents added by the fn callback in the given entity, allowing for nested formatting.
+// The entity's offset and length are set automatically.
+//
+//	tb.Nest(gotgbot.MessageEntity{Type: "blockquote"}, func(tb *gotgbot.TextBuilder) {
+//		tb.Bold(user.FirstName).Text(" said: " + text)
+//	})
+func (tb *TextBuilder) Nest(ent MessageEntity, fn func(tb *TextBuilder)) *TextBuilder {
+	o := tb.openEntity(ent)
+	fn(tb)
+	tb.closeEntity(o)
+	return tb
+}
+
+// String returns the plain text contents, to be used alongside Entities.
+func (tb *TextBuilder) String() string {
+	return tb.text.String()
+}
+
+// Entities returns the entities describing the text formatting, with UTF-16 offsets and lengths.
+func (tb *TextBuilder) Entities() []MessageEntity {
+	return append([]MessageEntity(nil), tb.entities...)
+}
+
+// HTML returns the formatted text as HTML, to be used with the "HTML" parse mode.
+func (tb *TextBuilder) HTML() string {
+	return getOrigMsgHTML(utf16.Encode([]rune(tb.text.String())), tb.entities)
+}
+
+// MarkdownV2 returns the formatted text as MarkdownV2, to be used with the "MarkdownV2" parse mode.
+func (tb *TextBuilder) MarkdownV2() string {
+	return getOrigMsgMDV2(utf16.Encode([]rune(tb.text.String())), tb.entities)
+}
+
+// add adds text wrapped in a single entity.
+func (tb *TextBuilder) add(ent MessageEntity, s string) *TextBuilder {
+	return tb.Nest(ent, func(tb *TextBuilder) {
+		tb.Text(s)
+	})
+}
+
+// openedEntity is an entity which has been started, but not yet completed.
+type openedEntity struct {
+	ent MessageEntity
+	// idx is the index at which the entity should be inserted, before any of its children, to keep the entities
+	// sorted by offset.
+	idx int
+}
+
+// openEntity starts a new entity at the current end of the text.
+func (tb *TextBuilder) openEntity(ent MessageEntity) openedEntity {
+	ent.Offset = tb.length
+	return openedEntity{ent: ent, idx: len(tb.entities)}
+}
+
+// closeEntity completes an entity at the current end of the text. Empty entities are not allowed, so are skipped.
+func (tb *TextBuilder) closeEntity(o openedEntity) {
+	if o.ent.Type == "" || tb.length == o.ent.Offset {
+		return
+	}
+
+	o.ent.Length = tb.length - o.ent.Offset
+	tb.entities = append(tb.entities, MessageEntity{})
+	copy(tb.entities[o.idx+1:], tb.entities[o.idx:])
+	tb.entities[o.idx] = o.ent
+}
+
+// utf16Len returns the length of a string in UTF-16 code units.
+func utf16Len(s string) int64 {
+	l := int64(0)
+	for _, r := range s {
+		if r >= 0x10000 {
+			// Runes outside the BMP are encoded as surrogate pairs.
+			l += 2
+			continue
+		}
+		l++
+	}
+	return l
+}
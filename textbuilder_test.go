@@ -0,0 +1,76 @@
+package gotgbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTextBuilder(t *testing.T) {
+	user := User{Id: 123, FirstName: "<John> *Smith*"}
+
+	tb := NewTextBuilder().
+		Text("👋 Hi ").
+		Mention(user.FirstName, user).
+		Text("! ").
+		Bold("1 < 2").
+		Text(" ").
+		Link("docs", "https://example.com/?a=1&b=(2)").
+		Text("\n").
+		Pre("fmt.Println(`hi`)", "go")
+
+	expectedText := "👋 Hi <John> *Smith*! 1 < 2 docs\nfmt.Println(`hi`)"
+	if tb.String() != expectedText {
+		t.Errorf("unexpected text: %q", tb.String())
+	}
+
+	expectedEntities := []MessageEntity{
+		// The waving hand emoji is a surrogate pair, so counts as 2 UTF-16 code units.
+		{Type: "text_mention", Offset: 6, Length: 14, User: &user},
+		{Type: "bold", Offset: 22, Length: 5},
+		{Type: "text_link", Offset: 28, Length: 4, Url: "https://example.com/?a=1&b=(2)"},
+		{Type: "pre", Offset: 33, Length: 17, Language: "go"},
+	}
+	if !reflect.DeepEqual(tb.Entities(), expectedEntities) {
+		t.Errorf("unexpected entities:\n%+v\nexpected:\n%+v", tb.Entities(), expectedEntities)
+	}
+
+	expectedHTML := `👋 Hi <a href="tg://user?id=123">&lt;John&gt; *Smith*</a>! <b>1 &lt; 2</b> ` +
+		`<a href="https://example.com/?a=1&amp;b=(2)">docs</a>` + "\n" +
+		`<pre><code class="language-go">fmt.Println(` + "`hi`" + `)</code></pre>`
+	if tb.HTML() != expectedHTML {
+		t.Errorf("unexpected HTML:\n%s\nexpected:\n%s", tb.HTML(), expectedHTML)
+	}
+
+	expectedMDV2 := `👋 Hi [<John\> \*Smith\*](tg://user?id=123)\! *1 < 2* [docs](https://example.com/?a=1&b=(2\))` + "\n" +
+		"```go\nfmt.Println(\\`hi\\`)```"
+	if tb.MarkdownV2() != expectedMDV2 {
+		t.Errorf("unexpected MarkdownV2:\n%s\nexpected:\n%s", tb.MarkdownV2(), expectedMDV2)
+	}
+}
+
+func TestTextBuilderNest(t *testing.T) {
+	tb := NewTextBuilder().Nest(MessageEntity{Type: "underline"}, func(tb *TextBuilder) {
+		tb.Text("a ").Italic("b")
+	}).Italic("c").Underline("d")
+
+	expectedEntities := []MessageEntity{
+		{Type: "underline", Offset: 0, Length: 3},
+		{Type: "italic", Offset: 2, Length: 1},
+		{Type: "italic", Offset: 3, Length: 1},
+		{Type: "underline", Offset: 4, Length: 1},
+	}
+	if !reflect.DeepEqual(tb.Entities(), expectedEntities) {
+		t.Errorf("unexpected entities:\n%+v\nexpected:\n%+v", tb.Entities(), expectedEntities)
+	}
+
+	// Italic and underline markers must be separated by an empty bold entity to avoid ambiguity.
+	expectedMDV2 := "__a _b_**__**_c_**__d__"
+	if tb.MarkdownV2() != expectedMDV2 {
+		t.Errorf("unexpected MarkdownV2:\n%s\nexpected:\n%s", tb.MarkdownV2(), expectedMDV2)
+	}
+
+	expectedHTML := "<u>a <i>b</i></u><i>c</i><u>d</u>"
+	if tb.HTML() != expectedHTML {
+		t.Errorf("unexpected HTML:\n%s\nexpected:\n%s", tb.HTML(), expectedHTML)
+	}
+}